@@ -7,9 +7,15 @@ import (
 	"os"
 	"os/signal"
 	"rbac-service/internal/app"
+	"rbac-service/internal/auth"
 	"rbac-service/internal/controller"
+	"rbac-service/internal/decision"
 	"rbac-service/internal/events"
 	"rbac-service/internal/events/handlers"
+	"rbac-service/internal/events/kafka"
+	"rbac-service/internal/events/memory"
+	"rbac-service/internal/events/nats"
+	"rbac-service/internal/events/postgres"
 	"rbac-service/internal/events/rabbitmq"
 	"rbac-service/internal/logger"
 	"rbac-service/internal/middleware"
@@ -17,6 +23,7 @@ import (
 	"rbac-service/internal/repository"
 	"rbac-service/internal/service"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -48,12 +55,18 @@ func main() {
 	resRepo := repository.NewResourceRepository()
 	permRepo := repository.NewPermissionRepository()
 	eventAuditRepo := repository.NewEventAuditRepository()
+	apiKeyRepo := repository.NewAPIKeyRepository()
+	auditLogRepo := repository.NewAuditLogRepository()
 
 	// 3. Init Domain Services
 	tenantService := service.NewTenantService(tenantRepo)
 	roleService := service.NewRoleService(roleRepo)
 	groupService := service.NewGroupService(groupRepo)
 	permService := service.NewPermissionService(permRepo, resRepo)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo)
+	auditService := service.NewAuditService(auditLogRepo)
+	deadLetterService := service.NewDeadLetterService(eventAuditRepo)
+	eventAuditService := service.NewEventAuditService(eventAuditRepo)
 
 	// 4. Init Event System
 	queueProvider, err := createQueueProvider()
@@ -67,7 +80,31 @@ func main() {
 		logger.Info(ctx, "External queue manager enabled - will skip infrastructure setup", nil)
 	}
 
-	eventManager, err := events.NewEventManager(queueProvider, eventAuditRepo, hasExternalQueueManager)
+	outboxPollInterval := time.Second
+	if intervalStr := os.Getenv("OUTBOX_POLL_INTERVAL"); intervalStr != "" {
+		outboxPollInterval, err = time.ParseDuration(intervalStr)
+		if err != nil {
+			logger.Fatal(ctx, "Invalid OUTBOX_POLL_INTERVAL", err)
+		}
+	}
+
+	topology, err := createTopology()
+	if err != nil {
+		logger.Fatal(ctx, "Failed to load event topology", err)
+	}
+
+	envelope := events.EnvelopeConfig{
+		Format:            os.Getenv("EVENT_FORMAT"),
+		Source:            os.Getenv("EVENT_SOURCE"),
+		DataSchemaBaseURL: os.Getenv("EVENT_DATASCHEMA_BASE_URL"),
+	}
+
+	retention, err := createRetentionConfig()
+	if err != nil {
+		logger.Fatal(ctx, "Failed to load event retention", err)
+	}
+
+	eventManager, err := events.NewEventManager(queueProvider, eventAuditRepo, topology, envelope, hasExternalQueueManager, outboxPollInterval, retention)
 	if err != nil {
 		logger.Fatal(ctx, "Failed to create event manager", err)
 	}
@@ -77,46 +114,85 @@ func main() {
 		publisher = eventManager.GetPublisher()
 	}
 
+	decisionLogger, err := createDecisionLogger(ctx, eventManager.GetPublisher())
+	if err != nil {
+		logger.Fatal(ctx, "Failed to create decision logger", err)
+	}
+	decisionLogger.Start(ctx)
+
 	// 5. Init App Services
-	tenantApp := app.NewTenantAppService(tenantService)
+	tenantApp := app.NewTenantAppService(tenantService, publisher)
 	roleApp := app.NewRoleAppService(roleService, publisher)
 	groupApp := app.NewGroupAppService(groupService, publisher)
-	validationApp := app.NewValidationAppService(permService)
+	validationApp := app.NewValidationAppService(permService, decisionLogger)
+	apiKeyApp := app.NewAPIKeyAppService(apiKeyService, publisher)
+	auditApp := app.NewAuditAppService(auditService)
+	eventAuditApp := app.NewEventAuditAppService(eventAuditService, eventManager)
+	deadLetterApp := app.NewDeadLetterAppService(deadLetterService, eventAuditApp, eventManager)
 
 	// 6. Register Event Handlers
 	if eventManager != nil {
 		router := eventManager.GetRouter()
-		publisher := eventManager.GetPublisher()
 
 		// Create handler instances
-		userRoleHandlers := handlers.NewUserRoleHandlers(roleApp, publisher)
-		userGroupHandlers := handlers.NewUserGroupHandlers(groupApp, publisher)
+		userRoleHandlers := handlers.NewUserRoleHandlers(roleApp)
+		userGroupHandlers := handlers.NewUserGroupHandlers(groupApp)
+		roleHandlers := handlers.NewRoleHandlers(roleApp)
+		groupHandlers := handlers.NewGroupHandlers(groupApp)
+		tenantHandlers := handlers.NewTenantHandlers(tenantApp)
 
 		// Register user-role handlers
-		router.Register(model.EventUserRoleAssignRequest, userRoleHandlers.HandleAssignRequest)
-		router.Register(model.EventUserRoleRemoveRequest, userRoleHandlers.HandleRemoveRequest)
+		events.Register(router, model.EventUserRoleAssignRequest, userRoleHandlers.HandleAssignRequest)
+		events.Register(router, model.EventUserRoleRemoveRequest, userRoleHandlers.HandleRemoveRequest)
 
 		// Register user-group handlers
-		router.Register(model.EventUserGroupAssignRequest, userGroupHandlers.HandleAssignRequest)
-		router.Register(model.EventUserGroupRemoveRequest, userGroupHandlers.HandleRemoveRequest)
+		events.Register(router, model.EventUserGroupAssignRequest, userGroupHandlers.HandleAssignRequest)
+		events.Register(router, model.EventUserGroupRemoveRequest, userGroupHandlers.HandleRemoveRequest)
+
+		// Register role and role permission handlers
+		events.Register(router, model.EventRoleCreateRequest, roleHandlers.HandleCreateRequest)
+		events.Register(router, model.EventRolePermissionAssignRequest, roleHandlers.HandleAssignPermissionsRequest)
+		events.Register(router, model.EventRolePermissionRemoveRequest, roleHandlers.HandleRemovePermissionsRequest)
+		events.Register(router, model.EventRolePermissionSyncRequest, roleHandlers.HandleSyncPermissionsRequest)
+
+		// Register group and group permission handlers
+		events.Register(router, model.EventGroupCreateRequest, groupHandlers.HandleCreateRequest)
+		events.Register(router, model.EventGroupPermissionAssignRequest, groupHandlers.HandleAssignPermissionsRequest)
+		events.Register(router, model.EventGroupPermissionRemoveRequest, groupHandlers.HandleRemovePermissionsRequest)
+		events.Register(router, model.EventGroupPermissionSyncRequest, groupHandlers.HandleSyncPermissionsRequest)
+
+		// Register tenant entitlement handlers
+		events.Register(router, model.EventTenantPermissionAssignRequest, tenantHandlers.HandleAssignPermissionsRequest)
+		events.Register(router, model.EventTenantPermissionRemoveRequest, tenantHandlers.HandleRemovePermissionsRequest)
+		events.Register(router, model.EventTenantPermissionSyncRequest, tenantHandlers.HandleSyncPermissionsRequest)
 
 		if err := eventManager.Start(ctx); err != nil {
 			logger.Fatal(ctx, "Failed to start event system", err)
 		}
-		defer eventManager.Stop()
 	}
 
 	// 7. Init Middleware
-	permMiddleware := middleware.NewPermissionMiddleware(permService)
+	authenticator, err := createAuthenticator(ctx)
+	if err != nil {
+		logger.Fatal(ctx, "Failed to create authenticator", err)
+	}
+	// API keys are accepted alongside the configured user authentication
+	authenticator = auth.NewChainAuthenticator(auth.NewAPIKeyAuthenticator(apiKeyService), authenticator)
+	authMiddleware := middleware.NewAuthMiddleware(authenticator)
+	permMiddleware := middleware.NewPermissionMiddleware(permService, decisionLogger)
 
 	// 8. Init Handlers
 	tenantHandler := controller.NewTenantHandler(tenantApp)
 	roleHandler := controller.NewRoleHandler(roleApp)
 	groupHandler := controller.NewGroupHandler(groupApp)
 	validationHandler := controller.NewValidationHandler(validationApp)
+	apiKeyHandler := controller.NewAPIKeyHandler(apiKeyApp)
+	auditHandler := controller.NewAuditHandler(auditApp)
+	deadLetterHandler := controller.NewDeadLetterHandler(deadLetterApp)
+	eventAuditHandler := controller.NewEventAuditHandler(eventAuditApp)
 
 	// 9. Setup Router
-	r := controller.SetupRouter(tenantHandler, roleHandler, groupHandler, validationHandler, permMiddleware)
+	r := controller.SetupRouter(tenantHandler, roleHandler, groupHandler, validationHandler, apiKeyHandler, auditHandler, deadLetterHandler, eventAuditHandler, authMiddleware, permMiddleware)
 
 	// 8. Start Server with graceful shutdown
 	port := os.Getenv("PORT")
@@ -148,10 +224,13 @@ func main() {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 
+	// Flush decision logs before the event system goes away
+	decisionLogger.Stop()
+
 	// Stop event system
 	if eventManager != nil {
 		logger.Info(shutdownCtx, "Stopping event system...", nil)
-		if err := eventManager.Stop(); err != nil {
+		if err := eventManager.Stop(shutdownCtx); err != nil {
 			logger.Error(shutdownCtx, "Error stopping event system", err)
 		}
 	}
@@ -188,8 +267,319 @@ func createQueueProvider() (events.QueueProvider, error) {
 			}
 		}
 
-		return rabbitmq.NewRabbitMQProvider(url, maxConns, maxChannels)
+		confirmTimeout := 5 * time.Second
+		if timeoutStr := os.Getenv("RABBITMQ_CONFIRM_TIMEOUT"); timeoutStr != "" {
+			var err error
+			confirmTimeout, err = time.ParseDuration(timeoutStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RABBITMQ_CONFIRM_TIMEOUT: %w", err)
+			}
+		}
+
+		return rabbitmq.NewRabbitMQProvider(url, maxConns, maxChannels, confirmTimeout)
+	}
+
+	if providerType == "MEMORY" {
+		return memory.NewMemoryProvider(), nil
+	}
+
+	if providerType == "KAFKA" {
+		var brokers []string
+		for _, broker := range strings.Split(os.Getenv("KAFKA_BROKERS"), ",") {
+			if broker = strings.TrimSpace(broker); broker != "" {
+				brokers = append(brokers, broker)
+			}
+		}
+
+		partitions := 3
+		if partitionsStr := os.Getenv("KAFKA_TOPIC_PARTITIONS"); partitionsStr != "" {
+			var err error
+			partitions, err = strconv.Atoi(partitionsStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid KAFKA_TOPIC_PARTITIONS: %w", err)
+			}
+		}
+
+		replicationFactor := 1
+		if replicationStr := os.Getenv("KAFKA_REPLICATION_FACTOR"); replicationStr != "" {
+			var err error
+			replicationFactor, err = strconv.Atoi(replicationStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid KAFKA_REPLICATION_FACTOR: %w", err)
+			}
+		}
+
+		maxDeliveries := 5
+		if maxDeliveriesStr := os.Getenv("KAFKA_MAX_DELIVERIES"); maxDeliveriesStr != "" {
+			var err error
+			maxDeliveries, err = strconv.Atoi(maxDeliveriesStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid KAFKA_MAX_DELIVERIES: %w", err)
+			}
+		}
+
+		return kafka.NewKafkaProvider(brokers, partitions, replicationFactor, maxDeliveries)
+	}
+
+	if providerType == "NATS" {
+		url := os.Getenv("NATS_URL")
+
+		maxDeliver := 5
+		if maxDeliverStr := os.Getenv("NATS_MAX_DELIVER"); maxDeliverStr != "" {
+			var err error
+			maxDeliver, err = strconv.Atoi(maxDeliverStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid NATS_MAX_DELIVER: %w", err)
+			}
+		}
+
+		return nats.NewNATSProvider(url, maxDeliver)
+	}
+
+	if providerType == "POSTGRES" {
+		visibilityTimeout := 30 * time.Second
+		if timeoutStr := os.Getenv("POSTGRES_QUEUE_VISIBILITY_TIMEOUT"); timeoutStr != "" {
+			var err error
+			visibilityTimeout, err = time.ParseDuration(timeoutStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid POSTGRES_QUEUE_VISIBILITY_TIMEOUT: %w", err)
+			}
+		}
+
+		maxDeliveries := 5
+		if maxDeliveriesStr := os.Getenv("POSTGRES_QUEUE_MAX_DELIVERIES"); maxDeliveriesStr != "" {
+			var err error
+			maxDeliveries, err = strconv.Atoi(maxDeliveriesStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid POSTGRES_QUEUE_MAX_DELIVERIES: %w", err)
+			}
+		}
+
+		return postgres.NewPostgresProvider(repository.NewQueueRepository(), visibilityTimeout, maxDeliveries), nil
 	}
 
 	return nil, fmt.Errorf("unsupported queue provider: %s", providerType)
 }
+
+// createTopology loads the event topology from EVENT_TOPOLOGY_FILE, if set,
+// and applies the EVENT_* environment variables over it
+func createTopology() (events.Topology, error) {
+	topology := events.DefaultTopology()
+	if path := os.Getenv("EVENT_TOPOLOGY_FILE"); path != "" {
+		var err error
+		topology, err = events.LoadTopologyFile(path)
+		if err != nil {
+			return events.Topology{}, err
+		}
+	}
+
+	if prefix := os.Getenv("EVENT_PREFIX"); prefix != "" {
+		topology.Prefix = prefix
+	}
+	if exchange := os.Getenv("EVENT_EXCHANGE"); exchange != "" {
+		topology.Exchange = exchange
+	}
+	if exchange := os.Getenv("EVENT_DEAD_LETTER_EXCHANGE"); exchange != "" {
+		topology.DeadLetterExchange = exchange
+	}
+	if queue := os.Getenv("EVENT_DEAD_LETTER_QUEUE"); queue != "" {
+		topology.DeadLetterQueue = queue
+	}
+
+	// The single-queue variables can't say which of several queues they mean
+	queue := os.Getenv("EVENT_QUEUE")
+	bindings := os.Getenv("EVENT_QUEUE_BINDINGS")
+	if queue != "" || bindings != "" {
+		if len(topology.Queues) != 1 {
+			return events.Topology{}, fmt.Errorf("EVENT_QUEUE and EVENT_QUEUE_BINDINGS require a topology with exactly one queue")
+		}
+		if queue != "" {
+			topology.Queues[0].Name = queue
+		}
+		if bindings != "" {
+			topology.Queues[0].Bindings = nil
+			for _, binding := range strings.Split(bindings, ",") {
+				if binding = strings.TrimSpace(binding); binding != "" {
+					topology.Queues[0].Bindings = append(topology.Queues[0].Bindings, binding)
+				}
+			}
+		}
+	}
+
+	if retriesStr := os.Getenv("EVENT_MAX_RETRIES"); retriesStr != "" {
+		retries, err := strconv.Atoi(retriesStr)
+		if err != nil {
+			return events.Topology{}, fmt.Errorf("invalid EVENT_MAX_RETRIES: %w", err)
+		}
+		topology.MaxRetries = retries
+	}
+
+	if workersStr := os.Getenv("CONSUMER_WORKERS"); workersStr != "" {
+		workers, err := strconv.Atoi(workersStr)
+		if err != nil {
+			return events.Topology{}, fmt.Errorf("invalid CONSUMER_WORKERS: %w", err)
+		}
+		topology.Workers = workers
+	}
+
+	if intervalStr := os.Getenv("EVENT_HEALTH_CHECK_INTERVAL"); intervalStr != "" {
+		interval, err := time.ParseDuration(intervalStr)
+		if err != nil {
+			return events.Topology{}, fmt.Errorf("invalid EVENT_HEALTH_CHECK_INTERVAL: %w", err)
+		}
+		topology.HealthCheckInterval = interval
+	}
+
+	return topology, nil
+}
+
+// createRetentionConfig applies the EVENT_RETENTION_* and EVENT_ARCHIVE_DIR
+// environment variables over the default retention
+func createRetentionConfig() (events.RetentionConfig, error) {
+	config := events.DefaultRetentionConfig()
+	config.ArchiveDir = os.Getenv("EVENT_ARCHIVE_DIR")
+
+	if intervalStr := os.Getenv("EVENT_RETENTION_INTERVAL"); intervalStr != "" {
+		interval, err := time.ParseDuration(intervalStr)
+		if err != nil {
+			return events.RetentionConfig{}, fmt.Errorf("invalid EVENT_RETENTION_INTERVAL: %w", err)
+		}
+		config.Interval = interval
+	}
+
+	if ageStr := os.Getenv("EVENT_RETENTION_COMPLETED"); ageStr != "" {
+		age, err := time.ParseDuration(ageStr)
+		if err != nil {
+			return events.RetentionConfig{}, fmt.Errorf("invalid EVENT_RETENTION_COMPLETED: %w", err)
+		}
+		config.CompletedAge = age
+	}
+
+	if ageStr := os.Getenv("EVENT_RETENTION_FAILED"); ageStr != "" {
+		age, err := time.ParseDuration(ageStr)
+		if err != nil {
+			return events.RetentionConfig{}, fmt.Errorf("invalid EVENT_RETENTION_FAILED: %w", err)
+		}
+		config.FailedAge = age
+	}
+
+	return config, nil
+}
+
+func createAuthenticator(ctx context.Context) (auth.Authenticator, error) {
+	mode := os.Getenv("AUTH_MODE")
+	if mode == "" {
+		mode = "JWT"
+	}
+
+	switch mode {
+	case "TRUSTED_HEADER":
+		var trustedProxies []string
+		if proxies := os.Getenv("AUTH_TRUSTED_PROXIES"); proxies != "" {
+			trustedProxies = strings.Split(proxies, ",")
+		} else {
+			logger.Warn(ctx, "Trusted header authentication enabled without AUTH_TRUSTED_PROXIES - identity headers are accepted from any peer", nil)
+		}
+
+		authenticator, err := auth.NewTrustedHeaderAuthenticator(os.Getenv("AUTH_USER_HEADER"), os.Getenv("AUTH_TENANT_HEADER"), trustedProxies)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AUTH_TRUSTED_PROXIES: %w", err)
+		}
+		return authenticator, nil
+
+	case "JWT":
+		config := auth.JWTConfig{
+			Issuer:      os.Getenv("JWT_ISSUER"),
+			Audience:    os.Getenv("JWT_AUDIENCE"),
+			UserClaim:   os.Getenv("JWT_USER_CLAIM"),
+			TenantClaim: os.Getenv("JWT_TENANT_CLAIM"),
+		}
+
+		if secret := os.Getenv("JWT_HMAC_SECRET"); secret != "" {
+			config.HMACSecret = []byte(secret)
+		}
+
+		if leewayStr := os.Getenv("JWT_LEEWAY"); leewayStr != "" {
+			leeway, err := time.ParseDuration(leewayStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JWT_LEEWAY: %w", err)
+			}
+			config.Leeway = leeway
+		}
+
+		if path := os.Getenv("JWT_JWKS_FILE"); path != "" {
+			keySet, err := auth.NewKeySetFromFile(path)
+			if err != nil {
+				return nil, err
+			}
+			config.KeySet = keySet
+		} else if url := os.Getenv("JWT_JWKS_URL"); url != "" {
+			keySet, err := auth.NewKeySetFromURL(ctx, url)
+			if err != nil {
+				return nil, err
+			}
+
+			refreshInterval := 15 * time.Minute
+			if intervalStr := os.Getenv("JWT_JWKS_REFRESH_INTERVAL"); intervalStr != "" {
+				refreshInterval, err = time.ParseDuration(intervalStr)
+				if err != nil {
+					return nil, fmt.Errorf("invalid JWT_JWKS_REFRESH_INTERVAL: %w", err)
+				}
+			}
+			go keySet.StartRefresh(ctx, refreshInterval)
+
+			config.KeySet = keySet
+		}
+
+		return auth.NewJWTAuthenticator(config)
+	}
+
+	return nil, fmt.Errorf("unsupported auth mode: %s", mode)
+}
+
+func createDecisionLogger(ctx context.Context, publisher *events.Publisher) (*decision.Logger, error) {
+	sinkType := os.Getenv("DECISION_LOG_SINK")
+	if sinkType == "" {
+		return nil, nil
+	}
+
+	var sink decision.Sink
+	switch sinkType {
+	case "LOG":
+		sink = decision.NewLogSink()
+	case "POSTGRES":
+		sink = decision.NewPostgresSink(repository.NewDecisionLogRepository())
+	case "QUEUE":
+		if publisher == nil {
+			return nil, fmt.Errorf("DECISION_LOG_SINK=QUEUE requires QUEUE_PROVIDER to be set")
+		}
+		sink = decision.NewQueueSink(publisher)
+	default:
+		return nil, fmt.Errorf("unsupported decision log sink: %s", sinkType)
+	}
+
+	config := decision.Config{
+		SampleRate:      1,
+		AlwaysLogDenied: os.Getenv("DECISION_LOG_ALWAYS_LOG_DENIED") != "false",
+		BufferSize:      1000,
+	}
+
+	if rateStr := os.Getenv("DECISION_LOG_SAMPLE_RATE"); rateStr != "" {
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid DECISION_LOG_SAMPLE_RATE: must be between 0 and 1")
+		}
+		config.SampleRate = rate
+	}
+
+	if sizeStr := os.Getenv("DECISION_LOG_BUFFER_SIZE"); sizeStr != "" {
+		size, err := strconv.Atoi(sizeStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DECISION_LOG_BUFFER_SIZE: %w", err)
+		}
+		config.BufferSize = size
+	}
+
+	logger.Info(ctx, "Decision logging enabled", nil, "sink", sinkType, "sample_rate", strconv.FormatFloat(config.SampleRate, 'f', -1, 64))
+	return decision.NewLogger(sink, config), nil
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"rbac-service/internal/auth"
+	"rbac-service/internal/model"
+	"rbac-service/internal/repository"
+	"rbac-service/internal/service"
+)
+
+type APIKeyAppService struct {
+	apiKeyService *service.APIKeyService
+	publisher     EventPublisher
+}
+
+func NewAPIKeyAppService(apiKeyService *service.APIKeyService, publisher EventPublisher) *APIKeyAppService {
+	return &APIKeyAppService{
+		apiKeyService: apiKeyService,
+		publisher:     publisher,
+	}
+}
+
+func (a *APIKeyAppService) CreateAPIKey(ctx context.Context, req model.CreateAPIKeyRequest) (*model.CreateAPIKeyResponse, error) {
+	if tenantID, scoped := scopedTenant(ctx); scoped {
+		if req.TenantID != tenantID {
+			return nil, fmt.Errorf("%w: %q", service.ErrTenantNotAuthorized, req.TenantID)
+		}
+		if err := a.apiKeyService.CheckRolesInTenant(ctx, req.RoleIDs, tenantID); err != nil {
+			return nil, err
+		}
+	}
+
+	var createdBy string
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		createdBy = principal.Type + ":" + principal.ID
+	}
+
+	var events func(key *model.APIKey) []model.Event
+	if a.publisher != nil {
+		events = func(key *model.APIKey) []model.Event {
+			roleIDs := req.RoleIDs
+			if roleIDs == nil {
+				roleIDs = []string{}
+			}
+			payload := model.APIKeyCreatedPayload{APIKeyID: key.ID, Name: key.Name, TenantID: key.TenantID, RoleIDs: roleIDs}
+			return []model.Event{newEvent(model.EventAPIKeyCreated, payload)}
+		}
+	}
+
+	key, rawKey, err := a.apiKeyService.CreateAPIKey(ctx, req.Name, req.TenantID, createdBy, req.ExpiresAt, req.RoleIDs, events)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CreateAPIKeyResponse{APIKey: *key, Key: rawKey}, nil
+}
+
+func (a *APIKeyAppService) ListAPIKeys(ctx context.Context, tenantID string) ([]model.APIKey, error) {
+	if scopedTenantID, scoped := scopedTenant(ctx); scoped {
+		tenantID = scopedTenantID
+	}
+	return a.apiKeyService.ListAPIKeys(ctx, tenantID)
+}
+
+func (a *APIKeyAppService) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	return a.authorizedKey(ctx, id)
+}
+
+func (a *APIKeyAppService) RevokeAPIKey(ctx context.Context, id string) error {
+	if _, err := a.authorizedKey(ctx, id); err != nil {
+		return err
+	}
+
+	var events []model.Event
+	if a.publisher != nil {
+		events = append(events, newEvent(model.EventAPIKeyRevoked, model.APIKeyRevokedPayload{APIKeyID: id}))
+	}
+
+	return a.apiKeyService.RevokeAPIKey(ctx, id, a.roleEvents(id), events...)
+}
+
+func (a *APIKeyAppService) BulkAssignRoles(ctx context.Context, keyID string, req model.BulkAPIKeyRoleRequest) error {
+	if _, err := a.authorizedKey(ctx, keyID); err != nil {
+		return err
+	}
+	if tenantID, scoped := scopedTenant(ctx); scoped {
+		if err := a.apiKeyService.CheckRolesInTenant(ctx, req.RoleIDs, tenantID); err != nil {
+			return err
+		}
+	}
+
+	return a.apiKeyService.AssignRoles(ctx, keyID, req.RoleIDs, a.roleEvents(keyID))
+}
+
+func (a *APIKeyAppService) BulkRemoveRoles(ctx context.Context, keyID string, req model.BulkAPIKeyRoleRequest) error {
+	if _, err := a.authorizedKey(ctx, keyID); err != nil {
+		return err
+	}
+
+	return a.apiKeyService.RemoveRoles(ctx, keyID, req.RoleIDs, a.roleEvents(keyID))
+}
+
+// authorizedKey loads an API key, hiding keys of other tenants from a
+// tenant-scoped caller
+func (a *APIKeyAppService) authorizedKey(ctx context.Context, id string) (*model.APIKey, error) {
+	key, err := a.apiKeyService.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if tenantID, scoped := scopedTenant(ctx); scoped && key.TenantID != tenantID {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+// scopedTenant returns the tenant a caller is confined to: the tenant it was
+// authorized for through api_key.manage_tenant_associated. Callers holding
+// the permission globally are not confined.
+func scopedTenant(ctx context.Context) (string, bool) {
+	scope, ok := auth.ScopeFromContext(ctx)
+	if !ok || scope.Global {
+		return "", false
+	}
+	return scope.TenantID, true
+}
+
+// roleEvents builds the roles changed event of an API key, if the mutation
+// changed anything
+func (a *APIKeyAppService) roleEvents(keyID string) model.MembershipChangeEvents {
+	if a.publisher == nil {
+		return nil
+	}
+
+	return func(change model.MembershipChange) []model.Event {
+		if change.Empty() {
+			return nil
+		}
+		payload := model.APIKeyRolesChangedPayload{APIKeyID: keyID, MembershipChange: change}
+		return []model.Event{newEvent(model.EventAPIKeyRolesChanged, payload)}
+	}
+}
@@ -0,0 +1,21 @@
+package app
+
+import (
+	"context"
+	"rbac-service/internal/model"
+	"rbac-service/internal/service"
+)
+
+type AuditAppService struct {
+	auditService *service.AuditService
+}
+
+func NewAuditAppService(auditService *service.AuditService) *AuditAppService {
+	return &AuditAppService{
+		auditService: auditService,
+	}
+}
+
+func (a *AuditAppService) ListAuditLog(ctx context.Context, filter model.AuditLogFilter) ([]model.AuditLogEntry, error) {
+	return a.auditService.ListAuditLog(ctx, filter)
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"rbac-service/internal/model"
+	"rbac-service/internal/service"
+)
+
+// DeadLetterQueue defines the queue operations needed to manage dead-lettered events
+type DeadLetterQueue interface {
+	PurgeDeadLetters(ctx context.Context) (int, error)
+}
+
+type DeadLetterAppService struct {
+	deadLetterService *service.DeadLetterService
+	eventAuditApp     *EventAuditAppService
+	queue             DeadLetterQueue
+}
+
+func NewDeadLetterAppService(deadLetterService *service.DeadLetterService, eventAuditApp *EventAuditAppService, queue DeadLetterQueue) *DeadLetterAppService {
+	return &DeadLetterAppService{
+		deadLetterService: deadLetterService,
+		eventAuditApp:     eventAuditApp,
+		queue:             queue,
+	}
+}
+
+func (a *DeadLetterAppService) ListDeadLetters(ctx context.Context, limit, offset int) ([]model.DeadLetterEvent, error) {
+	return a.deadLetterService.ListDeadLetters(ctx, limit, offset)
+}
+
+func (a *DeadLetterAppService) GetDeadLetter(ctx context.Context, id string) (*model.DeadLetterEvent, error) {
+	event, err := a.deadLetterService.GetDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetter := service.ToDeadLetterEvent(event)
+	return &deadLetter, nil
+}
+
+// ReplayDeadLetter republishes a dead-lettered event so it is consumed again.
+// It is reprocessed like any other event, so it stays dead-lettered if it
+// cannot be published.
+func (a *DeadLetterAppService) ReplayDeadLetter(ctx context.Context, id string) error {
+	event, err := a.deadLetterService.GetDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return a.eventAuditApp.reprocess(ctx, event)
+}
+
+// PurgeDeadLetters empties the dead-letter queue and deletes dead-lettered events
+func (a *DeadLetterAppService) PurgeDeadLetters(ctx context.Context) (*model.PurgeDeadLettersResponse, error) {
+	queueMessages, err := a.queue.PurgeDeadLetters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := a.deadLetterService.PurgeDeadLetters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.PurgeDeadLettersResponse{QueueMessages: queueMessages, Events: events}, nil
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/model"
+	"rbac-service/internal/service"
+)
+
+// EventRecovery defines the event system operations needed to republish and
+// reprocess events and report on their retention
+type EventRecovery interface {
+	ReprocessEvent(ctx context.Context, event *model.ConsumedEvent) error
+	NotifyOutbox()
+	RetentionStats() (model.EventRetentionStats, error)
+}
+
+type EventAuditAppService struct {
+	eventAuditService *service.EventAuditService
+	recovery          EventRecovery
+}
+
+func NewEventAuditAppService(eventAuditService *service.EventAuditService, recovery EventRecovery) *EventAuditAppService {
+	return &EventAuditAppService{
+		eventAuditService: eventAuditService,
+		recovery:          recovery,
+	}
+}
+
+func (a *EventAuditAppService) ListPublishedEvents(ctx context.Context, filter model.EventFilter) ([]model.PublishedEventRecord, error) {
+	return a.eventAuditService.ListPublishedEvents(ctx, filter)
+}
+
+func (a *EventAuditAppService) GetPublishedEvent(ctx context.Context, id string) (*model.PublishedEventRecord, error) {
+	return a.eventAuditService.GetPublishedEvent(ctx, id)
+}
+
+// RepublishEvent makes a pending, unroutable or failed outbox event due now
+// and wakes the relay to publish it
+func (a *EventAuditAppService) RepublishEvent(ctx context.Context, id string) error {
+	if err := a.eventAuditService.RequeuePublishedEvent(ctx, id); err != nil {
+		return err
+	}
+
+	a.recovery.NotifyOutbox()
+	return nil
+}
+
+func (a *EventAuditAppService) ListConsumedEvents(ctx context.Context, filter model.EventFilter) ([]model.ConsumedEventRecord, error) {
+	return a.eventAuditService.ListConsumedEvents(ctx, filter)
+}
+
+func (a *EventAuditAppService) GetConsumedEvent(ctx context.Context, id string) (*model.ConsumedEventRecord, error) {
+	event, err := a.eventAuditService.GetConsumedEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	record := event.Record()
+	return &record, nil
+}
+
+// ReprocessEvent republishes a failed, dead-lettered or replayed event so it is
+// consumed again. The event is marked replayed first, so the consumer's status
+// update is not overwritten if it finishes quickly.
+func (a *EventAuditAppService) ReprocessEvent(ctx context.Context, id string) error {
+	event, err := a.eventAuditService.GetConsumedEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return a.reprocess(ctx, event)
+}
+
+// reprocess marks an event replayed, provided its status has not changed since
+// it was read, then republishes it. If it cannot be published its status is
+// restored, so it can be reprocessed again.
+func (a *EventAuditAppService) reprocess(ctx context.Context, event *model.ConsumedEvent) error {
+	if err := a.eventAuditService.MarkReprocessing(ctx, event); err != nil {
+		return err
+	}
+
+	if err := a.recovery.ReprocessEvent(ctx, event); err != nil {
+		if restoreErr := a.eventAuditService.RestoreStatus(ctx, event); restoreErr != nil {
+			logger.Error(ctx, "Failed to restore consumed event status", restoreErr, "event_id", event.ID)
+			return errors.Join(err, restoreErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// RetentionStats returns the rows pruned from the event audit tables since the
+// service started
+func (a *EventAuditAppService) RetentionStats() (model.EventRetentionStats, error) {
+	return a.recovery.RetentionStats()
+}
@@ -19,51 +19,76 @@ func NewGroupAppService(groupService *service.GroupService, publisher EventPubli
 }
 
 func (a *GroupAppService) CreateGroup(ctx context.Context, req model.CreateGroupRequest) (*model.Group, error) {
-	return a.groupService.CreateGroup(ctx, req.Name, req.TenantID)
+	var events func(group *model.Group) []model.Event
+	if a.publisher != nil {
+		events = func(group *model.Group) []model.Event {
+			payload := model.GroupCreatedPayload{GroupID: group.ID, Name: group.Name, TenantID: group.TenantID}
+			return []model.Event{newEvent(model.EventGroupCreateSuccess, group), newEvent(model.EventGroupCreated, payload)}
+		}
+	}
+
+	return a.groupService.CreateGroup(ctx, req.Name, req.TenantID, events)
 }
 
 func (a *GroupAppService) BulkAssignPermissions(ctx context.Context, groupID string, req model.BulkGroupPermissionRequest) error {
-	return a.groupService.AssignPermissions(ctx, groupID, req.Permissions)
+	return a.groupService.AssignPermissions(ctx, groupID, req.Permissions, a.permissionEvents(groupID, req.Permissions, model.EventGroupPermissionAssignSuccess))
 }
 
 func (a *GroupAppService) BulkRemovePermissions(ctx context.Context, groupID string, req model.BulkGroupPermissionRequest) error {
-	return a.groupService.RemovePermissions(ctx, groupID, req.Permissions)
+	return a.groupService.RemovePermissions(ctx, groupID, req.Permissions, a.permissionEvents(groupID, req.Permissions, model.EventGroupPermissionRemoveSuccess))
 }
 
 func (a *GroupAppService) BulkSyncPermissions(ctx context.Context, groupID string, req model.BulkGroupPermissionRequest) error {
-	return a.groupService.SyncPermissions(ctx, groupID, req.Permissions)
+	return a.groupService.SyncPermissions(ctx, groupID, req.Permissions, a.permissionEvents(groupID, req.Permissions, model.EventGroupPermissionSyncSuccess))
 }
 
 func (a *GroupAppService) BulkAssignUsers(ctx context.Context, groupID string, req model.BulkUserGroupRequest) error {
-	err := a.groupService.AssignUsers(ctx, groupID, req.UserIDs)
-	if err != nil {
-		return err
+	return a.groupService.AssignUsers(ctx, groupID, req.UserIDs, a.userEvents(groupID, req.UserIDs, model.EventUserGroupAssignSuccess))
+}
+
+func (a *GroupAppService) BulkRemoveUsers(ctx context.Context, groupID string, req model.BulkUserGroupRequest) error {
+	return a.groupService.RemoveUsers(ctx, groupID, req.UserIDs, a.userEvents(groupID, req.UserIDs, model.EventUserGroupRemoveSuccess))
+}
+
+// permissionEvents builds the success event of a permission mutation, and the
+// permissions changed event if the mutation changed anything. The events are
+// written to the outbox with the change itself.
+func (a *GroupAppService) permissionEvents(groupID string, permissions []model.Permission, successType string) model.PermissionChangeEvents {
+	if a.publisher == nil {
+		return nil
 	}
 
-	if a.publisher != nil {
-		payload := map[string]interface{}{
-			"group_id": groupID,
-			"user_ids": req.UserIDs,
+	return func(change model.PermissionChange) []model.Event {
+		payload := model.GroupPermissionPayload{GroupID: groupID, Permissions: permissions}
+		events := []model.Event{newEvent(successType, payload)}
+
+		if !change.Empty() {
+			changed := model.GroupPermissionsChangedPayload{GroupID: groupID, PermissionChange: change}
+			events = append(events, newEvent(model.EventGroupPermissionsChanged, changed))
 		}
-		_ = a.publisher.Publish(ctx, "rbac.user_group.assign.success", payload)
+		return events
 	}
-
-	return nil
 }
 
-func (a *GroupAppService) BulkRemoveUsers(ctx context.Context, groupID string, req model.BulkUserGroupRequest) error {
-	err := a.groupService.RemoveUsers(ctx, groupID, req.UserIDs)
-	if err != nil {
-		return err
+// userEvents builds the success event of a user assignment or removal, and the
+// users changed event if any user was actually added or removed. The events are
+// written to the outbox with the change itself.
+func (a *GroupAppService) userEvents(groupID string, userIDs []string, successType string) model.MembershipChangeEvents {
+	if a.publisher == nil {
+		return nil
 	}
 
-	if a.publisher != nil {
+	return func(change model.MembershipChange) []model.Event {
 		payload := map[string]interface{}{
 			"group_id": groupID,
-			"user_ids": req.UserIDs,
+			"user_ids": userIDs,
 		}
-		_ = a.publisher.Publish(ctx, "rbac.user_group.remove.success", payload)
-	}
+		events := []model.Event{newEvent(successType, payload)}
 
-	return nil
+		if !change.Empty() {
+			changed := model.GroupUsersChangedPayload{GroupID: groupID, MembershipChange: change}
+			events = append(events, newEvent(model.EventGroupUsersChanged, changed))
+		}
+		return events
+	}
 }
@@ -1,8 +1,24 @@
 package app
 
-import "context"
+import (
+	"context"
+	"rbac-service/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // EventPublisher defines the interface for publishing events
 type EventPublisher interface {
 	Publish(ctx context.Context, eventType string, payload interface{}) error
 }
+
+// newEvent creates an event to be written to the outbox
+func newEvent(eventType string, payload interface{}) model.Event {
+	return model.Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Payload:   payload,
+		Timestamp: time.Now(),
+	}
+}
@@ -19,51 +19,76 @@ func NewRoleAppService(roleService *service.RoleService, publisher EventPublishe
 }
 
 func (a *RoleAppService) CreateRole(ctx context.Context, req model.CreateRoleRequest) (*model.Role, error) {
-	return a.roleService.CreateRole(ctx, req.Name, req.TenantID)
+	var events func(role *model.Role) []model.Event
+	if a.publisher != nil {
+		events = func(role *model.Role) []model.Event {
+			payload := model.RoleCreatedPayload{RoleID: role.ID, Name: role.Name, TenantID: role.TenantID}
+			return []model.Event{newEvent(model.EventRoleCreateSuccess, role), newEvent(model.EventRoleCreated, payload)}
+		}
+	}
+
+	return a.roleService.CreateRole(ctx, req.Name, req.TenantID, events)
 }
 
 func (a *RoleAppService) BulkAssignPermissions(ctx context.Context, roleID string, req model.BulkRolePermissionRequest) error {
-	return a.roleService.AssignPermissions(ctx, roleID, req.Permissions)
+	return a.roleService.AssignPermissions(ctx, roleID, req.Permissions, a.permissionEvents(roleID, req.Permissions, model.EventRolePermissionAssignSuccess))
 }
 
 func (a *RoleAppService) BulkRemovePermissions(ctx context.Context, roleID string, req model.BulkRolePermissionRequest) error {
-	return a.roleService.RemovePermissions(ctx, roleID, req.Permissions)
+	return a.roleService.RemovePermissions(ctx, roleID, req.Permissions, a.permissionEvents(roleID, req.Permissions, model.EventRolePermissionRemoveSuccess))
 }
 
 func (a *RoleAppService) BulkSyncPermissions(ctx context.Context, roleID string, req model.BulkRolePermissionRequest) error {
-	return a.roleService.SyncPermissions(ctx, roleID, req.Permissions)
+	return a.roleService.SyncPermissions(ctx, roleID, req.Permissions, a.permissionEvents(roleID, req.Permissions, model.EventRolePermissionSyncSuccess))
 }
 
 func (a *RoleAppService) BulkAssignUsers(ctx context.Context, roleID string, req model.BulkUserRoleRequest) error {
-	err := a.roleService.AssignUsers(ctx, roleID, req.UserIDs)
-	if err != nil {
-		return err
+	return a.roleService.AssignUsers(ctx, roleID, req.UserIDs, a.userEvents(roleID, req.UserIDs, model.EventUserRoleAssignSuccess))
+}
+
+func (a *RoleAppService) BulkRemoveUsers(ctx context.Context, roleID string, req model.BulkUserRoleRequest) error {
+	return a.roleService.RemoveUsers(ctx, roleID, req.UserIDs, a.userEvents(roleID, req.UserIDs, model.EventUserRoleRemoveSuccess))
+}
+
+// permissionEvents builds the success event of a permission mutation, and the
+// permissions changed event if the mutation changed anything. The events are
+// written to the outbox with the change itself.
+func (a *RoleAppService) permissionEvents(roleID string, permissions []model.Permission, successType string) model.PermissionChangeEvents {
+	if a.publisher == nil {
+		return nil
 	}
 
-	if a.publisher != nil {
-		payload := map[string]interface{}{
-			"role_id":  roleID,
-			"user_ids": req.UserIDs,
+	return func(change model.PermissionChange) []model.Event {
+		payload := model.RolePermissionPayload{RoleID: roleID, Permissions: permissions}
+		events := []model.Event{newEvent(successType, payload)}
+
+		if !change.Empty() {
+			changed := model.RolePermissionsChangedPayload{RoleID: roleID, PermissionChange: change}
+			events = append(events, newEvent(model.EventRolePermissionsChanged, changed))
 		}
-		_ = a.publisher.Publish(ctx, "rbac.user_role.assign.success", payload)
+		return events
 	}
-
-	return nil
 }
 
-func (a *RoleAppService) BulkRemoveUsers(ctx context.Context, roleID string, req model.BulkUserRoleRequest) error {
-	err := a.roleService.RemoveUsers(ctx, roleID, req.UserIDs)
-	if err != nil {
-		return err
+// userEvents builds the success event of a user assignment or removal, and the
+// users changed event if any user was actually added or removed. The events are
+// written to the outbox with the change itself.
+func (a *RoleAppService) userEvents(roleID string, userIDs []string, successType string) model.MembershipChangeEvents {
+	if a.publisher == nil {
+		return nil
 	}
 
-	if a.publisher != nil {
+	return func(change model.MembershipChange) []model.Event {
 		payload := map[string]interface{}{
 			"role_id":  roleID,
-			"user_ids": req.UserIDs,
+			"user_ids": userIDs,
 		}
-		_ = a.publisher.Publish(ctx, "rbac.user_role.remove.success", payload)
-	}
+		events := []model.Event{newEvent(successType, payload)}
 
-	return nil
+		if !change.Empty() {
+			changed := model.RoleUsersChangedPayload{RoleID: roleID, MembershipChange: change}
+			events = append(events, newEvent(model.EventRoleUsersChanged, changed))
+		}
+		return events
+	}
 }
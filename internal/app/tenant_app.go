@@ -8,22 +8,43 @@ import (
 
 type TenantAppService struct {
 	tenantService *service.TenantService
+	publisher     EventPublisher
 }
 
-func NewTenantAppService(tenantService *service.TenantService) *TenantAppService {
+func NewTenantAppService(tenantService *service.TenantService, publisher EventPublisher) *TenantAppService {
 	return &TenantAppService{
 		tenantService: tenantService,
+		publisher:     publisher,
 	}
 }
 
 func (a *TenantAppService) BulkAssignPermissions(ctx context.Context, req model.BulkTenantPermissionRequest) error {
-	return a.tenantService.AssignPermissions(ctx, req.TenantID, req.Permissions)
+	return a.tenantService.AssignPermissions(ctx, req.TenantID, req.Permissions, a.entitlementEvents(req, model.EventTenantPermissionAssignSuccess))
 }
 
 func (a *TenantAppService) BulkRemovePermissions(ctx context.Context, req model.BulkTenantPermissionRequest) error {
-	return a.tenantService.RemovePermissions(ctx, req.TenantID, req.Permissions)
+	return a.tenantService.RemovePermissions(ctx, req.TenantID, req.Permissions, a.entitlementEvents(req, model.EventTenantPermissionRemoveSuccess))
 }
 
 func (a *TenantAppService) BulkSyncPermissions(ctx context.Context, req model.BulkTenantPermissionRequest) error {
-	return a.tenantService.SyncPermissions(ctx, req.TenantID, req.Permissions)
+	return a.tenantService.SyncPermissions(ctx, req.TenantID, req.Permissions, a.entitlementEvents(req, model.EventTenantPermissionSyncSuccess))
+}
+
+// entitlementEvents builds the success event of an entitlement mutation, and
+// the entitlements changed event if the mutation changed anything. The events
+// are written to the outbox with the change itself.
+func (a *TenantAppService) entitlementEvents(req model.BulkTenantPermissionRequest, successType string) model.PermissionChangeEvents {
+	if a.publisher == nil {
+		return nil
+	}
+
+	return func(change model.PermissionChange) []model.Event {
+		payload := model.TenantPermissionPayload{TenantID: req.TenantID, Permissions: req.Permissions}
+		events := []model.Event{newEvent(successType, payload)}
+
+		if !change.Empty() {
+			events = append(events, newEvent(model.EventTenantEntitlementsChanged, change))
+		}
+		return events
+	}
 }
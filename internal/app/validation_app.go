@@ -2,20 +2,41 @@ package app
 
 import (
 	"context"
+	"rbac-service/internal/decision"
 	"rbac-service/internal/model"
 	"rbac-service/internal/service"
+	"time"
 )
 
 type ValidationAppService struct {
-	permService *service.PermissionService
+	permService    *service.PermissionService
+	decisionLogger *decision.Logger
 }
 
-func NewValidationAppService(permService *service.PermissionService) *ValidationAppService {
+func NewValidationAppService(permService *service.PermissionService, decisionLogger *decision.Logger) *ValidationAppService {
 	return &ValidationAppService{
-		permService: permService,
+		permService:    permService,
+		decisionLogger: decisionLogger,
 	}
 }
 
 func (a *ValidationAppService) CheckPermission(ctx context.Context, req model.CheckPermissionRequest) (bool, error) {
-	return a.permService.CheckPermission(ctx, req)
+	start := time.Now()
+	allowed, err := a.permService.CheckPermission(ctx, req)
+
+	entry := &model.DecisionLog{
+		Source:      model.DecisionSourceCheckPermission,
+		PrincipalID: req.UserID,
+		TenantID:    req.TenantID,
+		Permissions: req.Permissions,
+		Condition:   req.Condition,
+		Allowed:     allowed,
+		LatencyMs:   float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	a.decisionLogger.Record(ctx, entry)
+
+	return allowed, err
 }
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"rbac-service/internal/model"
+	"strings"
+)
+
+// APIKeyVerifier resolves a plaintext API key to an active key
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, rawKey string) (*model.APIKey, error)
+}
+
+// APIKeyAuthenticator authenticates service principals by API key, read from
+// the X-API-Key header or an "Authorization: ApiKey <key>" header
+type APIKeyAuthenticator struct {
+	verifier APIKeyVerifier
+}
+
+// NewAPIKeyAuthenticator creates a new API key authenticator
+func NewAPIKeyAuthenticator(verifier APIKeyVerifier) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{
+		verifier: verifier,
+	}
+}
+
+// Authenticate implements Authenticator
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	rawKey := r.Header.Get("X-API-Key")
+	if rawKey == "" {
+		scheme, value, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if ok && strings.EqualFold(scheme, "ApiKey") {
+			rawKey = value
+		}
+	}
+	if rawKey == "" {
+		return nil, ErrNoCredentials
+	}
+
+	key, err := a.verifier.VerifyAPIKey(r.Context(), rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	return &Principal{
+		ID:       key.ID,
+		Type:     PrincipalAPIKey,
+		TenantID: key.TenantID,
+	}, nil
+}
+
+// ChainAuthenticator tries each authenticator in order, moving on only when
+// an authenticator finds no credentials it understands
+type ChainAuthenticator struct {
+	authenticators []Authenticator
+}
+
+// NewChainAuthenticator creates a new chain of authenticators
+func NewChainAuthenticator(authenticators ...Authenticator) *ChainAuthenticator {
+	return &ChainAuthenticator{
+		authenticators: authenticators,
+	}
+}
+
+// Authenticate implements Authenticator
+func (a *ChainAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	for _, authenticator := range a.authenticators {
+		principal, err := authenticator.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return principal, err
+	}
+	return nil, ErrNoCredentials
+}
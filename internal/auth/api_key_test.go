@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"rbac-service/internal/model"
+	"testing"
+)
+
+// stubAuthenticator returns a fixed result and records that it was asked
+type stubAuthenticator struct {
+	principal *Principal
+	err       error
+	called    bool
+}
+
+func (s *stubAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	s.called = true
+	return s.principal, s.err
+}
+
+func TestChainAuthenticator(t *testing.T) {
+	apiKey := &Principal{ID: "key-1", Type: PrincipalAPIKey}
+	user := &Principal{ID: "user-1", Type: PrincipalUser}
+	invalid := errors.New("bad signature")
+
+	tests := []struct {
+		name          string
+		first, second *stubAuthenticator
+		wantPrincipal *Principal
+		wantErr       error
+		wantSecond    bool
+	}{
+		{
+			name:          "first authenticator wins",
+			first:         &stubAuthenticator{principal: apiKey},
+			second:        &stubAuthenticator{principal: user},
+			wantPrincipal: apiKey,
+		},
+		{
+			name:          "falls through when first finds no credentials",
+			first:         &stubAuthenticator{err: ErrNoCredentials},
+			second:        &stubAuthenticator{principal: user},
+			wantPrincipal: user,
+			wantSecond:    true,
+		},
+		{
+			name:    "invalid credentials stop the chain",
+			first:   &stubAuthenticator{err: invalid},
+			second:  &stubAuthenticator{principal: user},
+			wantErr: invalid,
+		},
+		{
+			name:       "no authenticator finds credentials",
+			first:      &stubAuthenticator{err: ErrNoCredentials},
+			second:     &stubAuthenticator{err: ErrNoCredentials},
+			wantErr:    ErrNoCredentials,
+			wantSecond: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := NewChainAuthenticator(tt.first, tt.second)
+			principal, err := chain.Authenticate(httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate returned %v, want %v", err, tt.wantErr)
+			}
+			if principal != tt.wantPrincipal {
+				t.Fatalf("principal = %+v, want %+v", principal, tt.wantPrincipal)
+			}
+			if tt.second.called != tt.wantSecond {
+				t.Fatalf("second authenticator called = %v, want %v", tt.second.called, tt.wantSecond)
+			}
+		})
+	}
+}
+
+// stubVerifier accepts a single key
+type stubVerifier struct{}
+
+func (stubVerifier) VerifyAPIKey(ctx context.Context, rawKey string) (*model.APIKey, error) {
+	if rawKey != "rbk_valid" {
+		return nil, errors.New("unknown API key")
+	}
+	return &model.APIKey{ID: "key-1", TenantID: "tenant-1"}, nil
+}
+
+func TestAPIKeyAuthenticatorInChain(t *testing.T) {
+	// API keys are tried first, so a bearer token falls through to the user authenticator
+	user := &stubAuthenticator{principal: &Principal{ID: "user-1", Type: PrincipalUser}}
+	chain := NewChainAuthenticator(NewAPIKeyAuthenticator(stubVerifier{}), user)
+
+	tests := []struct {
+		name    string
+		header  string
+		value   string
+		wantID  string
+		wantErr error
+	}{
+		{name: "X-API-Key header", header: "X-API-Key", value: "rbk_valid", wantID: "key-1"},
+		{name: "ApiKey authorization scheme", header: "Authorization", value: "ApiKey rbk_valid", wantID: "key-1"},
+		{name: "unknown key", header: "X-API-Key", value: "rbk_other", wantErr: ErrInvalidCredentials},
+		{name: "bearer token", header: "Authorization", value: "Bearer token", wantID: "user-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(tt.header, tt.value)
+
+			principal, err := chain.Authenticate(r)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate returned %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || principal.ID != tt.wantID {
+				t.Fatalf("Authenticate = %+v, %v, want %s", principal, err, tt.wantID)
+			}
+		})
+	}
+}
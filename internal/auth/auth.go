@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Principal types
+const (
+	PrincipalUser   = "user"
+	PrincipalAPIKey = "api_key"
+	PrincipalSystem = "system"
+)
+
+var (
+	// ErrNoCredentials is returned when a request carries no credentials
+	ErrNoCredentials = errors.New("no credentials provided")
+	// ErrInvalidCredentials is returned when credentials are present but not valid
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the authenticated identity making a request
+type Principal struct {
+	ID       string
+	Type     string
+	TenantID string
+}
+
+// Authenticator resolves the principal of an incoming HTTP request
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+type contextKey string
+
+const (
+	principalKey contextKey = "auth_principal"
+	scopeKey     contextKey = "auth_scope"
+)
+
+// Scope is what a request was authorized for by the permission middleware
+type Scope struct {
+	// TenantID is the tenant the permission was checked in, if any
+	TenantID string
+	// Global is set when the principal holds the permission globally rather
+	// than only within TenantID
+	Global bool
+}
+
+// WithPrincipal returns a context carrying the authenticated principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// WithScope returns a context carrying the authorized scope
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey, scope)
+}
+
+// ScopeFromContext returns the authorized scope, if the request passed a permission check
+func ScopeFromContext(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeKey).(Scope)
+	return scope, ok
+}
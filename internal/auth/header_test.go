@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrustedHeaderAuthenticator(t *testing.T) {
+	tests := []struct {
+		name       string
+		proxies    []string
+		remoteAddr string
+		userID     string
+		wantErr    error
+	}{
+		{name: "no proxies configured", remoteAddr: "203.0.113.7:4321", userID: "user-1"},
+		{name: "from trusted proxy address", proxies: []string{"10.0.0.5"}, remoteAddr: "10.0.0.5:4321", userID: "user-1"},
+		{name: "from trusted proxy range", proxies: []string{"192.168.0.1", " 10.0.0.0/8 "}, remoteAddr: "10.1.2.3:4321", userID: "user-1"},
+		{name: "from trusted IPv6 proxy", proxies: []string{"fd00::/8"}, remoteAddr: "[fd00::1]:4321", userID: "user-1"},
+		{name: "from untrusted peer", proxies: []string{"10.0.0.0/8"}, remoteAddr: "203.0.113.7:4321", userID: "user-1", wantErr: ErrInvalidCredentials},
+		{name: "from peer next to a trusted address", proxies: []string{"10.0.0.5"}, remoteAddr: "10.0.0.6:4321", userID: "user-1", wantErr: ErrInvalidCredentials},
+		{name: "without identity headers", proxies: []string{"10.0.0.0/8"}, remoteAddr: "203.0.113.7:4321", wantErr: ErrNoCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewTrustedHeaderAuthenticator("", "", tt.proxies)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			r.Header.Set("X-User-ID", tt.userID)
+			r.Header.Set("X-Tenant-ID", "tenant-1")
+			// Forwarding headers don't make an untrusted peer trusted
+			r.Header.Set("X-Forwarded-For", "10.0.0.5")
+
+			principal, err := a.Authenticate(r)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate returned %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate failed: %v", err)
+			}
+			if principal.ID != "user-1" || principal.TenantID != "tenant-1" || principal.Type != PrincipalUser {
+				t.Fatalf("principal = %+v, want user-1 in tenant-1", principal)
+			}
+		})
+	}
+}
+
+func TestTrustedHeaderAuthenticatorRejectsInvalidProxy(t *testing.T) {
+	for _, proxy := range []string{"gateway", "10.0.0.0/33"} {
+		if _, err := NewTrustedHeaderAuthenticator("", "", []string{proxy}); err == nil {
+			t.Fatalf("trusted proxy %q accepted", proxy)
+		}
+	}
+}
+
+func TestTrustedHeaderAuthenticatorCustomHeaders(t *testing.T) {
+	a, err := NewTrustedHeaderAuthenticator("X-Auth-User", "X-Auth-Tenant", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-User-ID", "spoofed")
+	if _, err := a.Authenticate(r); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("default header accepted: %v", err)
+	}
+
+	r.Header.Set("X-Auth-User", "user-1")
+	r.Header.Set("X-Auth-Tenant", "tenant-1")
+	principal, err := a.Authenticate(r)
+	if err != nil || principal.ID != "user-1" || principal.TenantID != "tenant-1" {
+		t.Fatalf("Authenticate = %+v, %v, want user-1 in tenant-1", principal, err)
+	}
+}
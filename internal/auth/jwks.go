@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"rbac-service/internal/logger"
+	"sync"
+	"time"
+)
+
+// jwk is a single JSON Web Key
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// KeySet holds RSA public keys loaded from a JWKS file or URL
+type KeySet struct {
+	source      string
+	isURL       bool
+	httpClient  *http.Client
+	minRefresh  time.Duration
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	lastRefresh time.Time
+}
+
+// NewKeySetFromFile loads a JWKS document from a local file
+func NewKeySetFromFile(path string) (*KeySet, error) {
+	ks := &KeySet{source: path}
+	if err := ks.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// NewKeySetFromURL loads a JWKS document from a URL
+func NewKeySetFromURL(ctx context.Context, url string) (*KeySet, error) {
+	ks := &KeySet{
+		source:     url,
+		isURL:      true,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		minRefresh: time.Minute,
+	}
+	if err := ks.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Key returns the public key for a key ID. An unknown key ID triggers a
+// rate-limited refresh so that rotated keys are picked up.
+func (k *KeySet) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	k.mu.RLock()
+	key, ok := k.lookupUnsafe(kid)
+	stale := k.isURL && time.Since(k.lastRefresh) >= k.minRefresh
+	k.mu.RUnlock()
+
+	if ok {
+		return key, nil
+	}
+
+	if stale {
+		if err := k.Refresh(ctx); err != nil {
+			logger.Warn(ctx, "Failed to refresh JWKS", nil, "source", k.source, "error", err.Error())
+		}
+
+		k.mu.RLock()
+		key, ok = k.lookupUnsafe(kid)
+		k.mu.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown key id: %s", kid)
+}
+
+// lookupUnsafe finds a key by ID; an empty ID matches when only one key is present
+func (k *KeySet) lookupUnsafe(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// Refresh reloads the key set from its source
+func (k *KeySet) Refresh(ctx context.Context) error {
+	data, err := k.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, key := range doc.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+
+		pub, err := parseRSAKey(key)
+		if err != nil {
+			return fmt.Errorf("failed to parse JWK %s: %w", key.Kid, err)
+		}
+		keys[key.Kid] = pub
+	}
+
+	if len(keys) == 0 {
+		return fmt.Errorf("JWKS from %s contains no RSA signing keys", k.source)
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.lastRefresh = time.Now()
+	k.mu.Unlock()
+
+	return nil
+}
+
+// StartRefresh periodically reloads a URL-backed key set until ctx is cancelled
+func (k *KeySet) StartRefresh(ctx context.Context, interval time.Duration) {
+	if !k.isURL || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Refresh(ctx); err != nil {
+				logger.Error(ctx, "Failed to refresh JWKS", err, "source", k.source)
+			}
+		}
+	}
+}
+
+func (k *KeySet) fetch(ctx context.Context) ([]byte, error) {
+	if !k.isURL {
+		data, err := os.ReadFile(k.source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+		}
+		return data, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build JWKS request: %w", err)
+	}
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS response: %w", err)
+	}
+	return data, nil
+}
+
+func parseRSAKey(key jwk) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(key.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+
+	eBytes, err := base64.RawURLEncoding.DecodeString(key.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+
+	e := new(big.Int).SetBytes(eBytes)
+	if !e.IsInt64() || e.Int64() > int64(^uint32(0)) {
+		return nil, fmt.Errorf("exponent out of range")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(e.Int64()),
+	}, nil
+}
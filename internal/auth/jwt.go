@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTConfig configures bearer token validation
+type JWTConfig struct {
+	// HMACSecret enables HS256 tokens when set
+	HMACSecret []byte
+	// KeySet enables RS256 tokens when set
+	KeySet *KeySet
+	// Issuer and Audience are validated when set
+	Issuer   string
+	Audience string
+	// UserClaim and TenantClaim name the claims holding the user and tenant IDs.
+	// Nested claims can be addressed with dots (e.g. "ctx.tenant_id").
+	UserClaim   string
+	TenantClaim string
+	// Leeway allows for clock skew when validating exp/nbf/iat
+	Leeway time.Duration
+}
+
+// JWTAuthenticator validates bearer tokens from the Authorization header
+type JWTAuthenticator struct {
+	config  JWTConfig
+	methods []string
+}
+
+// NewJWTAuthenticator creates a new JWT authenticator
+func NewJWTAuthenticator(config JWTConfig) (*JWTAuthenticator, error) {
+	var methods []string
+	if len(config.HMACSecret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if config.KeySet != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("JWT authenticator requires an HMAC secret or a JWKS key set")
+	}
+
+	if config.UserClaim == "" {
+		config.UserClaim = "sub"
+	}
+	if config.TenantClaim == "" {
+		config.TenantClaim = "tenant_id"
+	}
+
+	return &JWTAuthenticator{
+		config:  config,
+		methods: methods,
+	}, nil
+}
+
+// Authenticate implements Authenticator
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, ErrNoCredentials
+	}
+
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, fmt.Errorf("%w: expected bearer token", ErrInvalidCredentials)
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(a.methods),
+		jwt.WithLeeway(a.config.Leeway),
+		jwt.WithExpirationRequired(),
+	}
+	if a.config.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(a.config.Issuer))
+	}
+	if a.config.Audience != "" {
+		opts = append(opts, jwt.WithAudience(a.config.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.Alg() {
+		case jwt.SigningMethodHS256.Alg():
+			return a.config.HMACSecret, nil
+		case jwt.SigningMethodRS256.Alg():
+			kid, _ := t.Header["kid"].(string)
+			return a.config.KeySet.Key(r.Context(), kid)
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %s", t.Method.Alg())
+		}
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	userID := claimString(claims, a.config.UserClaim)
+	if userID == "" {
+		return nil, fmt.Errorf("%w: missing %s claim", ErrInvalidCredentials, a.config.UserClaim)
+	}
+
+	return &Principal{
+		ID:       userID,
+		Type:     PrincipalUser,
+		TenantID: claimString(claims, a.config.TenantClaim),
+	}, nil
+}
+
+// claimString resolves a dotted claim path to a string value
+func claimString(claims jwt.MapClaims, path string) string {
+	var current interface{} = map[string]interface{}(claims)
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		current = m[part]
+	}
+
+	switch v := current.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	default:
+		return ""
+	}
+}
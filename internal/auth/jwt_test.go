@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testSecret = []byte("test-secret")
+
+// testKey is an RSA signing key published in a JWKS document under kid
+type testKey struct {
+	kid string
+	key *rsa.PrivateKey
+}
+
+func newTestKey(t *testing.T, kid string) testKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{kid: kid, key: key}
+}
+
+// jwks renders a JWKS document for keys
+func jwks(t *testing.T, keys ...testKey) []byte {
+	t.Helper()
+
+	doc := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	for _, k := range keys {
+		doc.Keys = append(doc.Keys, jwk{
+			Kid: k.kid,
+			Kty: "RSA",
+			Alg: "RS256",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(k.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.key.E)).Bytes()),
+		})
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// jwksServer serves a JWKS document that tests can rotate
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	doc     []byte
+	fetches int
+}
+
+func newJWKSServer(t *testing.T, doc []byte) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{doc: doc}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.fetches++
+		w.Write(s.doc)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) rotate(doc []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.doc = doc
+}
+
+func (s *jwksServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":       "user-1",
+		"tenant_id": "tenant-1",
+		"iss":       "https://issuer.example",
+		"aud":       "rbac-service",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func signHS256(t *testing.T, claims jwt.MapClaims, secret []byte) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func signRS256(t *testing.T, claims jwt.MapClaims, key testKey) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = key.kid
+	signed, err := token.SignedString(key.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func authenticateBearer(a Authenticator, token string) (*Principal, error) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return a.Authenticate(r)
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	key := newTestKey(t, "key-1")
+	keySet, err := NewKeySetFromURL(context.Background(), newJWKSServer(t, jwks(t, key)).URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewJWTAuthenticator(JWTConfig{
+		HMACSecret: testSecret,
+		KeySet:     keySet,
+		Issuer:     "https://issuer.example",
+		Audience:   "rbac-service",
+		Leeway:     5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	with := func(change func(claims jwt.MapClaims)) jwt.MapClaims {
+		claims := validClaims()
+		change(claims)
+		return claims
+	}
+
+	noneToken, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs384Token, err := jwt.NewWithClaims(jwt.SigningMethodHS384, validClaims()).SignedString(testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "HS256", token: signHS256(t, validClaims(), testSecret)},
+		{name: "RS256", token: signRS256(t, validClaims(), key)},
+		{name: "alg none", token: noneToken, wantErr: true},
+		{name: "alg not allowed", token: hs384Token, wantErr: true},
+		{name: "wrong secret", token: signHS256(t, validClaims(), []byte("other-secret")), wantErr: true},
+		{name: "wrong RSA key", token: signRS256(t, validClaims(), testKey{kid: "key-1", key: newTestKey(t, "").key}), wantErr: true},
+		{name: "expired", token: signHS256(t, with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }), testSecret), wantErr: true},
+		{name: "expired within leeway", token: signHS256(t, with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Second).Unix() }), testSecret)},
+		{name: "without exp", token: signHS256(t, with(func(c jwt.MapClaims) { delete(c, "exp") }), testSecret), wantErr: true},
+		{name: "not yet valid", token: signHS256(t, with(func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(time.Minute).Unix() }), testSecret), wantErr: true},
+		{name: "valid from now", token: signHS256(t, with(func(c jwt.MapClaims) { c["nbf"] = time.Now().Unix() }), testSecret)},
+		{name: "wrong issuer", token: signHS256(t, with(func(c jwt.MapClaims) { c["iss"] = "https://other.example" }), testSecret), wantErr: true},
+		{name: "without issuer", token: signHS256(t, with(func(c jwt.MapClaims) { delete(c, "iss") }), testSecret), wantErr: true},
+		{name: "wrong audience", token: signHS256(t, with(func(c jwt.MapClaims) { c["aud"] = "other-service" }), testSecret), wantErr: true},
+		{name: "audience list", token: signHS256(t, with(func(c jwt.MapClaims) { c["aud"] = []string{"other-service", "rbac-service"} }), testSecret)},
+		{name: "without user claim", token: signHS256(t, with(func(c jwt.MapClaims) { delete(c, "sub") }), testSecret), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := authenticateBearer(a, tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("Authenticate returned %+v, %v, want ErrInvalidCredentials", principal, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate failed: %v", err)
+			}
+			if principal.ID != "user-1" || principal.TenantID != "tenant-1" || principal.Type != PrincipalUser {
+				t.Fatalf("principal = %+v, want user-1 in tenant-1", principal)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticatorAcceptsOnlyConfiguredAlgorithms(t *testing.T) {
+	key := newTestKey(t, "key-1")
+	keySet, err := NewKeySetFromURL(context.Background(), newJWKSServer(t, jwks(t, key)).URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hmacOnly, err := NewJWTAuthenticator(JWTConfig{HMACSecret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authenticateBearer(hmacOnly, signRS256(t, validClaims(), key)); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("RS256 token accepted without a key set: %v", err)
+	}
+
+	// An HS256 token signed with the public key must not pass as RS256
+	rsaOnly, err := NewJWTAuthenticator(JWTConfig{KeySet: keySet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := jwks(t, key)
+	if _, err := authenticateBearer(rsaOnly, signHS256(t, validClaims(), publicKey)); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("HS256 token accepted with only a key set: %v", err)
+	}
+
+	if _, err := NewJWTAuthenticator(JWTConfig{}); err == nil {
+		t.Fatal("authenticator created without a secret or key set")
+	}
+}
+
+func TestJWTAuthenticatorCredentials(t *testing.T) {
+	a, err := NewJWTAuthenticator(JWTConfig{HMACSecret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, err := a.Authenticate(r); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("request without Authorization returned %v, want ErrNoCredentials", err)
+	}
+
+	for _, header := range []string{"Basic dXNlcjpwYXNz", "Bearer", "token"} {
+		r.Header.Set("Authorization", header)
+		if _, err := a.Authenticate(r); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Authorization %q returned %v, want ErrInvalidCredentials", header, err)
+		}
+	}
+}
+
+func TestJWTAuthenticatorCustomClaims(t *testing.T) {
+	a, err := NewJWTAuthenticator(JWTConfig{HMACSecret: testSecret, UserClaim: "uid", TenantClaim: "ctx.tenant"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := jwt.MapClaims{
+		"sub": "ignored",
+		"uid": float64(42),
+		"ctx": map[string]interface{}{"tenant": "tenant-1"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	principal, err := authenticateBearer(a, signHS256(t, claims, testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.ID != "42" || principal.TenantID != "tenant-1" {
+		t.Fatalf("principal = %+v, want 42 in tenant-1", principal)
+	}
+}
+
+func TestKeySetRefreshesOnUnknownKeyID(t *testing.T) {
+	oldKey := newTestKey(t, "old")
+	newKey := newTestKey(t, "new")
+	server := newJWKSServer(t, jwks(t, oldKey))
+
+	keySet, err := NewKeySetFromURL(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewJWTAuthenticator(JWTConfig{KeySet: keySet})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The issuer rotates to a new key
+	server.rotate(jwks(t, newKey))
+	token := signRS256(t, validClaims(), newKey)
+
+	// Within the minimum refresh interval an unknown key ID doesn't refetch
+	if _, err := authenticateBearer(a, token); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("token with unknown key ID accepted before refresh: %v", err)
+	}
+	if got := server.fetchCount(); got != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1", got)
+	}
+
+	keySet.mu.Lock()
+	keySet.lastRefresh = time.Now().Add(-keySet.minRefresh)
+	keySet.mu.Unlock()
+
+	if _, err := authenticateBearer(a, token); err != nil {
+		t.Fatalf("token signed with rotated key rejected: %v", err)
+	}
+	if got := server.fetchCount(); got != 2 {
+		t.Fatalf("JWKS fetched %d times, want 2", got)
+	}
+
+	// Keys that were rotated out are no longer accepted
+	if _, err := authenticateBearer(a, signRS256(t, validClaims(), oldKey)); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("token signed with rotated-out key accepted: %v", err)
+	}
+}
+
+func TestKeySetFromFile(t *testing.T) {
+	key := newTestKey(t, "key-1")
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks(t, key), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keySet, err := NewKeySetFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A token without a key ID matches the only key
+	got, err := keySet.Key(context.Background(), "")
+	if err != nil || got.N.Cmp(key.key.N) != 0 {
+		t.Fatalf("Key(\"\") = %v, want the only key", err)
+	}
+	if _, err := keySet.Key(context.Background(), "other"); err == nil {
+		t.Fatal("unknown key ID found")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"keys": [{"kid": "ec", "kty": "EC"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewKeySetFromFile(path); err == nil {
+		t.Fatal("key set without RSA keys loaded")
+	}
+}
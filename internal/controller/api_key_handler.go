@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"rbac-service/internal/app"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/model"
+	"rbac-service/internal/repository"
+	"rbac-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	apiKeyApp *app.APIKeyAppService
+}
+
+func NewAPIKeyHandler(apiKeyApp *app.APIKeyAppService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyApp: apiKeyApp,
+	}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req model.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	resp, err := h.apiKeyApp.CreateAPIKey(c.Request.Context(), req)
+	if err != nil {
+		h.handleError(c, "Failed to create api key", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.apiKeyApp.ListAPIKeys(c.Request.Context(), c.Query("tenant_id"))
+	if err != nil {
+		logger.Error(c.Request.Context(), "Failed to list api keys", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+func (h *APIKeyHandler) GetAPIKey(c *gin.Context) {
+	key, err := h.apiKeyApp.GetAPIKey(c.Request.Context(), c.Param("key_id"))
+	if err != nil {
+		h.handleError(c, "Failed to get api key", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	if err := h.apiKeyApp.RevokeAPIKey(c.Request.Context(), c.Param("key_id")); err != nil {
+		h.handleError(c, "Failed to revoke api key", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
+
+func (h *APIKeyHandler) BulkAssignRoles(c *gin.Context) {
+	keyID := c.Param("key_id")
+	var req model.BulkAPIKeyRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.apiKeyApp.BulkAssignRoles(c.Request.Context(), keyID, req); err != nil {
+		h.handleError(c, "Failed to assign roles to api key", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Roles assigned successfully"})
+}
+
+func (h *APIKeyHandler) BulkRemoveRoles(c *gin.Context) {
+	keyID := c.Param("key_id")
+	var req model.BulkAPIKeyRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.apiKeyApp.BulkRemoveRoles(c.Request.Context(), keyID, req); err != nil {
+		h.handleError(c, "Failed to remove roles from api key", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Roles removed successfully"})
+}
+
+func (h *APIKeyHandler) handleError(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTenantNotAuthorized), errors.Is(err, service.ErrRoleOutsideTenant):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		logger.Error(c.Request.Context(), msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
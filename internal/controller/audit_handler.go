@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"rbac-service/internal/auth"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/model"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditLogReader lists audit log entries; implemented by app.AuditAppService
+type AuditLogReader interface {
+	ListAuditLog(ctx context.Context, filter model.AuditLogFilter) ([]model.AuditLogEntry, error)
+}
+
+type AuditHandler struct {
+	auditApp AuditLogReader
+}
+
+func NewAuditHandler(auditApp AuditLogReader) *AuditHandler {
+	return &AuditHandler{
+		auditApp: auditApp,
+	}
+}
+
+func (h *AuditHandler) ListAuditLog(c *gin.Context) {
+	filter := model.AuditLogFilter{
+		ActorID:    c.Query("actor_id"),
+		TenantID:   c.Query("tenant_id"),
+		Operation:  c.Query("operation"),
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+		RequestID:  c.Query("request_id"),
+	}
+
+	// Readers without the global permission only see the tenant they were
+	// authorized for, whatever tenant_id they ask for
+	if scope, ok := auth.ScopeFromContext(c.Request.Context()); ok && !scope.Global {
+		if scope.TenantID == "" || (filter.TenantID != "" && filter.TenantID != scope.TenantID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied for the requested tenant"})
+			return
+		}
+		filter.TenantID = scope.TenantID
+	}
+
+	var err error
+	if filter.From, err = parseTimeQuery(c, "from"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.To, err = parseTimeQuery(c, "to"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.Limit, err = parseIntQuery(c, "limit"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.Offset, err = parseIntQuery(c, "offset"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.auditApp.ListAuditLog(c.Request.Context(), filter)
+	if err != nil {
+		logger.Error(c.Request.Context(), "Failed to list audit log", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// parseTimeQuery parses an optional RFC 3339 query parameter
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: expected RFC 3339 timestamp", name)
+	}
+	return &t, nil
+}
+
+// parseIntQuery parses an optional integer query parameter
+func parseIntQuery(c *gin.Context, name string) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: expected integer", name)
+	}
+	return n, nil
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"rbac-service/internal/auth"
+	"rbac-service/internal/model"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordingAuditReader records the filter it was asked for
+type recordingAuditReader struct {
+	filter *model.AuditLogFilter
+}
+
+func (r *recordingAuditReader) ListAuditLog(ctx context.Context, filter model.AuditLogFilter) ([]model.AuditLogEntry, error) {
+	r.filter = &filter
+	return []model.AuditLogEntry{}, nil
+}
+
+// serveAuditLog runs ListAuditLog behind a stand-in for RequirePermission
+// that grants the given scope
+func serveAuditLog(t *testing.T, scope auth.Scope, target string, header string) (*httptest.ResponseRecorder, *recordingAuditReader) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	reader := &recordingAuditReader{}
+	handler := NewAuditHandler(reader)
+
+	r := gin.New()
+	r.GET("/audit", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.WithScope(c.Request.Context(), scope))
+		c.Next()
+	}, handler.ListAuditLog)
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if header != "" {
+		req.Header.Set("X-Tenant-ID", header)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w, reader
+}
+
+func TestListAuditLogTenantScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		scope      auth.Scope
+		target     string
+		header     string
+		wantStatus int
+		wantTenant string
+	}{
+		{
+			name:       "tenant reader asking for another tenant",
+			scope:      auth.Scope{TenantID: "tenant-a"},
+			target:     "/audit?tenant_id=tenant-b",
+			header:     "tenant-a",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "tenant reader without a tenant filter",
+			scope:      auth.Scope{TenantID: "tenant-a"},
+			target:     "/audit",
+			wantStatus: http.StatusOK,
+			wantTenant: "tenant-a",
+		},
+		{
+			name:       "tenant reader asking for its own tenant",
+			scope:      auth.Scope{TenantID: "tenant-a"},
+			target:     "/audit?tenant_id=tenant-a",
+			wantStatus: http.StatusOK,
+			wantTenant: "tenant-a",
+		},
+		{
+			name:       "tenant reader without a scoped tenant",
+			scope:      auth.Scope{},
+			target:     "/audit",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "global reader without a tenant filter",
+			scope:      auth.Scope{TenantID: "tenant-a", Global: true},
+			target:     "/audit",
+			header:     "tenant-a",
+			wantStatus: http.StatusOK,
+			wantTenant: "",
+		},
+		{
+			name:       "global reader filtering by tenant",
+			scope:      auth.Scope{TenantID: "tenant-a", Global: true},
+			target:     "/audit?tenant_id=tenant-b",
+			header:     "tenant-a",
+			wantStatus: http.StatusOK,
+			wantTenant: "tenant-b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, reader := serveAuditLog(t, tt.scope, tt.target, tt.header)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if reader.filter != nil {
+					t.Fatalf("audit log listed with tenant %q for a rejected request", reader.filter.TenantID)
+				}
+				return
+			}
+			if reader.filter.TenantID != tt.wantTenant {
+				t.Fatalf("filter tenant = %q, want %q", reader.filter.TenantID, tt.wantTenant)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"rbac-service/internal/app"
+	"rbac-service/internal/events"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeadLetterHandler struct {
+	deadLetterApp *app.DeadLetterAppService
+}
+
+func NewDeadLetterHandler(deadLetterApp *app.DeadLetterAppService) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		deadLetterApp: deadLetterApp,
+	}
+}
+
+func (h *DeadLetterHandler) ListDeadLetters(c *gin.Context) {
+	limit, err := parseIntQuery(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	offset, err := parseIntQuery(c, "offset")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deadLetters, err := h.deadLetterApp.ListDeadLetters(c.Request.Context(), limit, offset)
+	if err != nil {
+		logger.Error(c.Request.Context(), "Failed to list dead-lettered events", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": deadLetters})
+}
+
+func (h *DeadLetterHandler) GetDeadLetter(c *gin.Context) {
+	deadLetter, err := h.deadLetterApp.GetDeadLetter(c.Request.Context(), c.Param("event_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrDeadLetterNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		logger.Error(c.Request.Context(), "Failed to get dead-lettered event", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deadLetter)
+}
+
+func (h *DeadLetterHandler) ReplayDeadLetter(c *gin.Context) {
+	if err := h.deadLetterApp.ReplayDeadLetter(c.Request.Context(), c.Param("event_id")); err != nil {
+		h.handleQueueError(c, "Failed to replay dead-lettered event", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Event replayed successfully"})
+}
+
+func (h *DeadLetterHandler) PurgeDeadLetters(c *gin.Context) {
+	resp, err := h.deadLetterApp.PurgeDeadLetters(c.Request.Context())
+	if err != nil {
+		h.handleQueueError(c, "Failed to purge dead-lettered events", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *DeadLetterHandler) handleQueueError(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrDeadLetterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEventNotRecoverable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, events.ErrEventSystemDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		logger.Error(c.Request.Context(), msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"rbac-service/internal/app"
+	"rbac-service/internal/events"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/model"
+	"rbac-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EventAuditHandler struct {
+	eventAuditApp *app.EventAuditAppService
+}
+
+func NewEventAuditHandler(eventAuditApp *app.EventAuditAppService) *EventAuditHandler {
+	return &EventAuditHandler{
+		eventAuditApp: eventAuditApp,
+	}
+}
+
+func (h *EventAuditHandler) ListPublishedEvents(c *gin.Context) {
+	filter, err := parseEventFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records, err := h.eventAuditApp.ListPublishedEvents(c.Request.Context(), filter)
+	if err != nil {
+		logger.Error(c.Request.Context(), "Failed to list published events", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": records})
+}
+
+func (h *EventAuditHandler) GetPublishedEvent(c *gin.Context) {
+	record, err := h.eventAuditApp.GetPublishedEvent(c.Request.Context(), c.Param("event_id"))
+	if err != nil {
+		h.handleError(c, "Failed to get published event", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+func (h *EventAuditHandler) RepublishEvent(c *gin.Context) {
+	if err := h.eventAuditApp.RepublishEvent(c.Request.Context(), c.Param("event_id")); err != nil {
+		h.handleError(c, "Failed to republish event", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Event queued for publishing"})
+}
+
+func (h *EventAuditHandler) ListConsumedEvents(c *gin.Context) {
+	filter, err := parseEventFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records, err := h.eventAuditApp.ListConsumedEvents(c.Request.Context(), filter)
+	if err != nil {
+		logger.Error(c.Request.Context(), "Failed to list consumed events", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": records})
+}
+
+func (h *EventAuditHandler) GetConsumedEvent(c *gin.Context) {
+	record, err := h.eventAuditApp.GetConsumedEvent(c.Request.Context(), c.Param("event_id"))
+	if err != nil {
+		h.handleError(c, "Failed to get consumed event", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+func (h *EventAuditHandler) ReprocessEvent(c *gin.Context) {
+	if err := h.eventAuditApp.ReprocessEvent(c.Request.Context(), c.Param("event_id")); err != nil {
+		h.handleError(c, "Failed to reprocess event", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Event republished for reprocessing"})
+}
+
+func (h *EventAuditHandler) GetRetentionStats(c *gin.Context) {
+	stats, err := h.eventAuditApp.RetentionStats()
+	if err != nil {
+		h.handleError(c, "Failed to get event retention stats", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *EventAuditHandler) handleError(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrPublishedEventNotFound), errors.Is(err, service.ErrConsumedEventNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEventNotRecoverable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, events.ErrEventSystemDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		logger.Error(c.Request.Context(), msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// parseEventFilter reads the status, type, time range and paging query parameters
+func parseEventFilter(c *gin.Context) (model.EventFilter, error) {
+	filter := model.EventFilter{
+		Status:    c.Query("status"),
+		EventType: c.Query("event_type"),
+	}
+
+	var err error
+	if filter.From, err = parseTimeQuery(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseTimeQuery(c, "to"); err != nil {
+		return filter, err
+	}
+	if filter.Limit, err = parseIntQuery(c, "limit"); err != nil {
+		return filter, err
+	}
+	if filter.Offset, err = parseIntQuery(c, "offset"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
@@ -11,14 +11,24 @@ func SetupRouter(
 	roleHandler *RoleHandler,
 	groupHandler *GroupHandler,
 	validationHandler *ValidationHandler,
+	apiKeyHandler *APIKeyHandler,
+	auditHandler *AuditHandler,
+	deadLetterHandler *DeadLetterHandler,
+	eventAuditHandler *EventAuditHandler,
+	authMiddleware *middleware.AuthMiddleware,
 	permMiddleware *middleware.PermissionMiddleware,
 ) *gin.Engine {
 	r := gin.Default()
+	r.Use(middleware.RequestID())
 
 	v1 := r.Group("/api/v1")
 	{
+		// Management endpoints require an authenticated principal
+		managed := v1.Group("")
+		managed.Use(authMiddleware.Authenticate())
+
 		// Tenant
-		tenant := v1.Group("/tenant")
+		tenant := managed.Group("/tenant")
 		tenant.Use(permMiddleware.RequirePermission("tenant_permission.manage", "tenant_permission.manage")) // No associated variant for tenant permissions explicitly defined as different logic, but using same code for now or maybe just one. User said "tenant_permission.manage".
 		{
 			tenant.POST("/permissions/bulk", tenantHandler.BulkAssignPermissions) // Deprecated
@@ -28,7 +38,7 @@ func SetupRouter(
 		}
 
 		// Roles
-		roles := v1.Group("/roles")
+		roles := managed.Group("/roles")
 		{
 			roles.POST("", permMiddleware.RequirePermission("role.manage", "role.manage_tenant_associated"), roleHandler.CreateRole)
 
@@ -50,7 +60,7 @@ func SetupRouter(
 		}
 
 		// Groups
-		groups := v1.Group("/groups")
+		groups := managed.Group("/groups")
 		{
 			groups.POST("", permMiddleware.RequirePermission("group.manage", "group.manage_tenant_associated"), groupHandler.CreateGroup)
 
@@ -71,6 +81,44 @@ func SetupRouter(
 			}
 		}
 
+		// API Keys
+		apiKeys := managed.Group("/api-keys")
+		apiKeys.Use(permMiddleware.RequirePermission("api_key.manage", "api_key.manage_tenant_associated"))
+		{
+			apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+			apiKeys.GET("", apiKeyHandler.ListAPIKeys)
+			apiKeys.GET("/:key_id", apiKeyHandler.GetAPIKey)
+			apiKeys.DELETE("/:key_id", apiKeyHandler.RevokeAPIKey)
+			apiKeys.POST("/:key_id/roles/bulk", apiKeyHandler.BulkAssignRoles)
+			apiKeys.DELETE("/:key_id/roles/bulk", apiKeyHandler.BulkRemoveRoles)
+		}
+
+		// Audit
+		managed.GET("/audit", permMiddleware.RequirePermission("audit_log.read", "audit_log.read_tenant_associated"), auditHandler.ListAuditLog)
+
+		// Dead-lettered events
+		deadLetters := managed.Group("/events/dead-letters")
+		deadLetters.Use(permMiddleware.RequirePermission("event.manage", "event.manage"))
+		{
+			deadLetters.GET("", deadLetterHandler.ListDeadLetters)
+			deadLetters.DELETE("", deadLetterHandler.PurgeDeadLetters)
+			deadLetters.GET("/:event_id", deadLetterHandler.GetDeadLetter)
+			deadLetters.POST("/:event_id/replay", deadLetterHandler.ReplayDeadLetter)
+		}
+
+		// Event audit
+		eventAudit := managed.Group("/admin/events")
+		eventAudit.Use(permMiddleware.RequirePermission("event.manage", "event.manage"))
+		{
+			eventAudit.GET("/published", eventAuditHandler.ListPublishedEvents)
+			eventAudit.GET("/published/:event_id", eventAuditHandler.GetPublishedEvent)
+			eventAudit.POST("/published/:event_id/republish", eventAuditHandler.RepublishEvent)
+			eventAudit.GET("/consumed", eventAuditHandler.ListConsumedEvents)
+			eventAudit.GET("/consumed/:event_id", eventAuditHandler.GetConsumedEvent)
+			eventAudit.POST("/consumed/:event_id/reprocess", eventAuditHandler.ReprocessEvent)
+			eventAudit.GET("/retention", eventAuditHandler.GetRetentionStats)
+		}
+
 		// Validation
 		v1.POST("/check-permission", validationHandler.CheckPermission)
 	}
@@ -0,0 +1,150 @@
+package decision
+
+import (
+	"context"
+	"math/rand"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/model"
+	"rbac-service/internal/reqctx"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sink persists or forwards permission decisions
+type Sink interface {
+	Write(ctx context.Context, decision *model.DecisionLog) error
+}
+
+// Config holds the decision logger settings
+type Config struct {
+	// SampleRate is the fraction of decisions recorded, between 0 and 1
+	SampleRate float64
+	// AlwaysLogDenied records every denied or failed check regardless of SampleRate
+	AlwaysLogDenied bool
+	// BufferSize is the number of decisions queued before new ones are dropped
+	BufferSize int
+}
+
+// Logger samples permission decisions and writes them to a sink in the
+// background, so permission checks never wait on the sink. A nil *Logger is
+// valid and records nothing.
+type Logger struct {
+	sink   Sink
+	config Config
+	queue  chan *model.DecisionLog
+	stop   chan struct{}
+
+	mu   sync.Mutex
+	rand *rand.Rand
+
+	wg sync.WaitGroup
+}
+
+// NewLogger creates a new decision logger
+func NewLogger(sink Sink, config Config) *Logger {
+	if config.SampleRate < 0 {
+		config.SampleRate = 0
+	}
+	if config.SampleRate > 1 {
+		config.SampleRate = 1
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = 1000
+	}
+
+	return &Logger{
+		sink:   sink,
+		config: config,
+		queue:  make(chan *model.DecisionLog, config.BufferSize),
+		stop:   make(chan struct{}),
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Start begins writing queued decisions to the sink
+func (l *Logger) Start(ctx context.Context) {
+	if l == nil {
+		return
+	}
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		for {
+			select {
+			case decision := <-l.queue:
+				l.write(ctx, decision)
+			case <-l.stop:
+				// Flush whatever is still buffered
+				for {
+					select {
+					case decision := <-l.queue:
+						l.write(ctx, decision)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+}
+
+func (l *Logger) write(ctx context.Context, decision *model.DecisionLog) {
+	if err := l.sink.Write(ctx, decision); err != nil {
+		logger.Error(ctx, "Failed to write permission decision", err, "decision_id", decision.ID)
+	}
+}
+
+// Stop flushes queued decisions and waits for the writer to finish
+func (l *Logger) Stop() {
+	if l == nil {
+		return
+	}
+
+	close(l.stop)
+	l.wg.Wait()
+}
+
+// Record samples a decision and queues it for the sink. It never blocks;
+// decisions are dropped when the buffer is full.
+func (l *Logger) Record(ctx context.Context, decision *model.DecisionLog) {
+	if l == nil {
+		return
+	}
+
+	denied := !decision.Allowed || decision.Error != ""
+	if !(denied && l.config.AlwaysLogDenied) && !l.sample() {
+		return
+	}
+
+	if decision.ID == "" {
+		decision.ID = uuid.New().String()
+	}
+	if decision.OccurredAt.IsZero() {
+		decision.OccurredAt = time.Now()
+	}
+	if decision.RequestID == "" {
+		decision.RequestID = reqctx.RequestID(ctx)
+	}
+
+	select {
+	case l.queue <- decision:
+	default:
+		logger.Warn(ctx, "Decision log buffer full, dropping decision", nil, "decision_id", decision.ID)
+	}
+}
+
+func (l *Logger) sample() bool {
+	if l.config.SampleRate >= 1 {
+		return true
+	}
+	if l.config.SampleRate <= 0 {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rand.Float64() < l.config.SampleRate
+}
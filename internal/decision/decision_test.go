@@ -0,0 +1,203 @@
+package decision
+
+import (
+	"context"
+	"errors"
+	"rbac-service/internal/model"
+	"rbac-service/internal/reqctx"
+	"sync"
+	"testing"
+)
+
+// recordingSink keeps the decisions written to it
+type recordingSink struct {
+	mu        sync.Mutex
+	decisions []*model.DecisionLog
+	err       error
+}
+
+func (s *recordingSink) Write(ctx context.Context, decision *model.DecisionLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.decisions = append(s.decisions, decision)
+	return s.err
+}
+
+func (s *recordingSink) written() []*model.DecisionLog {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*model.DecisionLog(nil), s.decisions...)
+}
+
+// record runs a logger over the given decisions and returns what reached the sink
+func record(t *testing.T, config Config, decisions ...*model.DecisionLog) []*model.DecisionLog {
+	t.Helper()
+
+	sink := &recordingSink{}
+	l := NewLogger(sink, config)
+	l.Start(context.Background())
+	for _, decision := range decisions {
+		l.Record(context.Background(), decision)
+	}
+	l.Stop()
+	return sink.written()
+}
+
+func TestLoggerSampling(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   []string
+	}{
+		{
+			name:   "everything sampled",
+			config: Config{SampleRate: 1},
+			want:   []string{"allowed", "denied", "failed"},
+		},
+		{
+			name:   "nothing sampled",
+			config: Config{SampleRate: 0},
+			want:   nil,
+		},
+		{
+			name:   "nothing sampled but denials",
+			config: Config{SampleRate: 0, AlwaysLogDenied: true},
+			want:   []string{"denied", "failed"},
+		},
+		{
+			name:   "rate above 1 is clamped",
+			config: Config{SampleRate: 5},
+			want:   []string{"allowed", "denied", "failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			written := record(t, tt.config,
+				&model.DecisionLog{ID: "allowed", Allowed: true},
+				&model.DecisionLog{ID: "denied", Allowed: false},
+				&model.DecisionLog{ID: "failed", Allowed: true, Error: "store unavailable"},
+			)
+
+			if len(written) != len(tt.want) {
+				t.Fatalf("wrote %d decisions, want %v", len(written), tt.want)
+			}
+			for i, decision := range written {
+				if decision.ID != tt.want[i] {
+					t.Fatalf("decision %d = %q, want %q", i, decision.ID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoggerSamplesFraction(t *testing.T) {
+	decisions := make([]*model.DecisionLog, 2000)
+	for i := range decisions {
+		decisions[i] = &model.DecisionLog{Allowed: true}
+	}
+
+	written := record(t, Config{SampleRate: 0.25, BufferSize: len(decisions)}, decisions...)
+	if len(written) < 350 || len(written) > 650 {
+		t.Fatalf("sampled %d of %d decisions at rate 0.25", len(written), len(decisions))
+	}
+}
+
+func TestLoggerFillsInDecision(t *testing.T) {
+	sink := &recordingSink{}
+	l := NewLogger(sink, Config{SampleRate: 1})
+	l.Start(context.Background())
+	l.Record(reqctx.WithRequestID(context.Background(), "req-1"), &model.DecisionLog{Allowed: true})
+	l.Stop()
+
+	written := sink.written()
+	if len(written) != 1 {
+		t.Fatalf("wrote %d decisions, want 1", len(written))
+	}
+	if written[0].ID == "" || written[0].OccurredAt.IsZero() || written[0].RequestID != "req-1" {
+		t.Fatalf("decision = %+v, want an ID, a time and request ID req-1", written[0])
+	}
+}
+
+func TestLoggerDropsWhenBufferFull(t *testing.T) {
+	sink := &recordingSink{}
+	l := NewLogger(sink, Config{SampleRate: 1, BufferSize: 2})
+
+	// Not started, so nothing drains the buffer
+	for i := 0; i < 5; i++ {
+		l.Record(context.Background(), &model.DecisionLog{Allowed: true})
+	}
+
+	l.Start(context.Background())
+	l.Stop()
+
+	if written := sink.written(); len(written) != 2 {
+		t.Fatalf("wrote %d decisions, want the 2 buffered", len(written))
+	}
+}
+
+func TestLoggerKeepsWritingAfterSinkError(t *testing.T) {
+	sink := &recordingSink{err: errors.New("sink unavailable")}
+	l := NewLogger(sink, Config{SampleRate: 1})
+	l.Start(context.Background())
+	l.Record(context.Background(), &model.DecisionLog{Allowed: true})
+	l.Record(context.Background(), &model.DecisionLog{Allowed: true})
+	l.Stop()
+
+	if written := sink.written(); len(written) != 2 {
+		t.Fatalf("wrote %d decisions, want 2", len(written))
+	}
+}
+
+func TestNilLoggerRecordsNothing(t *testing.T) {
+	var l *Logger
+	l.Start(context.Background())
+	l.Record(context.Background(), &model.DecisionLog{})
+	l.Stop()
+}
+
+// recordingPublisher keeps the events published through it
+type recordingPublisher struct {
+	eventType string
+	payload   interface{}
+}
+
+func (p *recordingPublisher) PublishDirect(ctx context.Context, eventType string, payload interface{}) error {
+	p.eventType = eventType
+	p.payload = payload
+	return nil
+}
+
+func TestQueueSinkPublishesDecisionEvent(t *testing.T) {
+	publisher := &recordingPublisher{}
+	decision := &model.DecisionLog{ID: "decision-1", Allowed: true}
+
+	if err := NewQueueSink(publisher).Write(context.Background(), decision); err != nil {
+		t.Fatal(err)
+	}
+	if publisher.eventType != model.EventPermissionDecision || publisher.payload != decision {
+		t.Fatalf("published %q with %v, want %q with the decision", publisher.eventType, publisher.payload, model.EventPermissionDecision)
+	}
+}
+
+// recordingStore keeps the decisions stored through it
+type recordingStore struct {
+	decisions []*model.DecisionLog
+}
+
+func (s *recordingStore) CreateDecisionLog(ctx context.Context, decision *model.DecisionLog) error {
+	s.decisions = append(s.decisions, decision)
+	return nil
+}
+
+func TestPostgresSinkStoresDecision(t *testing.T) {
+	store := &recordingStore{}
+	decision := &model.DecisionLog{ID: "decision-1"}
+
+	if err := NewPostgresSink(store).Write(context.Background(), decision); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.decisions) != 1 || store.decisions[0] != decision {
+		t.Fatalf("stored %v, want the decision", store.decisions)
+	}
+}
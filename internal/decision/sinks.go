@@ -0,0 +1,68 @@
+package decision
+
+import (
+	"context"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/model"
+)
+
+// LogSink writes decisions as structured log lines
+type LogSink struct{}
+
+// NewLogSink creates a new log sink
+func NewLogSink() *LogSink {
+	return &LogSink{}
+}
+
+// Write logs the decision
+func (s *LogSink) Write(ctx context.Context, decision *model.DecisionLog) error {
+	logger.Info(ctx, "Permission decision", decision, "decision")
+	return nil
+}
+
+// Store persists decisions
+type Store interface {
+	CreateDecisionLog(ctx context.Context, decision *model.DecisionLog) error
+}
+
+// PostgresSink writes decisions to the decision_log table
+type PostgresSink struct {
+	store Store
+}
+
+// NewPostgresSink creates a new Postgres sink
+func NewPostgresSink(store Store) *PostgresSink {
+	return &PostgresSink{
+		store: store,
+	}
+}
+
+// Write stores the decision
+func (s *PostgresSink) Write(ctx context.Context, decision *model.DecisionLog) error {
+	return s.store.CreateDecisionLog(ctx, decision)
+}
+
+// Publisher publishes events to the queue. It is implemented by
+// events.Publisher.
+type Publisher interface {
+	PublishDirect(ctx context.Context, eventType string, payload interface{}) error
+}
+
+// QueueSink publishes decisions as events. They are sent straight to the queue
+// provider rather than through the outbox, so a sampled check doesn't cost a
+// database write; a decision that fails to publish is logged and lost.
+type QueueSink struct {
+	publisher Publisher
+}
+
+// NewQueueSink creates a new queue sink
+func NewQueueSink(publisher Publisher) *QueueSink {
+	return &QueueSink{
+		publisher: publisher,
+	}
+}
+
+// Write publishes the decision
+func (s *QueueSink) Write(ctx context.Context, decision *model.DecisionLog) error {
+	return s.publisher.PublishDirect(ctx, model.EventPermissionDecision, decision)
+}
@@ -0,0 +1,32 @@
+package events
+
+import (
+	"context"
+	"rbac-service/internal/model"
+	"time"
+)
+
+// AuditStore records published and consumed events. The publisher uses it as
+// the outbox and the consumer to detect redeliveries. It is implemented by
+// repository.EventAuditRepository.
+type AuditStore interface {
+	// EnqueuePublishedEvent writes an event to the outbox as pending
+	EnqueuePublishedEvent(ctx context.Context, event model.Event) error
+	// ClaimPendingEvents returns due outbox events, hiding them from other
+	// relays for the lease
+	ClaimPendingEvents(ctx context.Context, limit int, lease time.Duration) ([]model.PublishedEvent, error)
+	// UpdatePublishedEvent sets the final status of an outbox event
+	UpdatePublishedEvent(ctx context.Context, id, status string, errorMessage *string) error
+	// ReschedulePublishedEvent leaves an outbox event pending until the next attempt
+	ReschedulePublishedEvent(ctx context.Context, id, errorMessage string, attempts int, nextAttemptAt time.Time) error
+
+	// ClaimConsumedEvent marks an event as processing before it is handled
+	ClaimConsumedEvent(ctx context.Context, event *model.ConsumedEvent, lease time.Duration) (*model.ConsumedEvent, bool, error)
+	// UpdateConsumedEvent records the outcome of handling an event
+	UpdateConsumedEvent(ctx context.Context, id, status string, errorMessage *string, retryCount int) error
+
+	// PrunePublishedEvents deletes a batch of outbox events finished before the cutoff
+	PrunePublishedEvents(ctx context.Context, statuses []string, before time.Time, limit int, archive func([]model.PublishedEvent) error) (int, error)
+	// PruneConsumedEvents deletes a batch of consumed events finished before the cutoff
+	PruneConsumedEvents(ctx context.Context, statuses []string, before time.Time, limit int, archive func([]model.ConsumedEvent) error) (int, error)
+}
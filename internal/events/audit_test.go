@@ -0,0 +1,289 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"rbac-service/internal/model"
+	"rbac-service/internal/repository"
+	"rbac-service/internal/reqctx"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryAuditStore is an in-memory events.AuditStore with the semantics of
+// repository.EventAuditRepository
+type memoryAuditStore struct {
+	mu        sync.Mutex
+	published map[string]*model.PublishedEvent
+	consumed  map[string]*model.ConsumedEvent
+	claims    int
+	// pruneCalls counts the batches pruned
+	pruneCalls int
+}
+
+func newMemoryAuditStore() *memoryAuditStore {
+	return &memoryAuditStore{
+		published: make(map[string]*model.PublishedEvent),
+		consumed:  make(map[string]*model.ConsumedEvent),
+	}
+}
+
+func (s *memoryAuditStore) EnqueuePublishedEvent(ctx context.Context, event model.Event) error {
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return err
+	}
+
+	record := &model.PublishedEvent{
+		ID:            event.ID,
+		EventType:     event.Type,
+		Payload:       payload,
+		Status:        model.StatusPending,
+		NextAttemptAt: event.Timestamp,
+		CreatedAt:     event.Timestamp,
+		UpdatedAt:     event.Timestamp,
+	}
+	correlationID := event.CorrelationID
+	if correlationID == "" {
+		correlationID = reqctx.CorrelationID(ctx)
+	}
+	if correlationID != "" {
+		record.CorrelationID = &correlationID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.published[event.ID] = record
+	return nil
+}
+
+func (s *memoryAuditStore) ClaimPendingEvents(ctx context.Context, limit int, lease time.Duration) ([]model.PublishedEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var due []model.PublishedEvent
+	for _, event := range s.published {
+		if event.Status == model.StatusPending && !event.NextAttemptAt.After(now) {
+			due = append(due, *event)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].CreatedAt.Before(due[j].CreatedAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for _, event := range due {
+		s.published[event.ID].NextAttemptAt = now.Add(lease)
+	}
+	return due, nil
+}
+
+func (s *memoryAuditStore) UpdatePublishedEvent(ctx context.Context, id, status string, errorMessage *string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if event, ok := s.published[id]; ok {
+		event.Status = status
+		event.ErrorMessage = errorMessage
+		event.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (s *memoryAuditStore) ReschedulePublishedEvent(ctx context.Context, id, errorMessage string, attempts int, nextAttemptAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if event, ok := s.published[id]; ok {
+		event.ErrorMessage = &errorMessage
+		event.Attempts = attempts
+		event.NextAttemptAt = nextAttemptAt
+		event.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (s *memoryAuditStore) ClaimConsumedEvent(ctx context.Context, event *model.ConsumedEvent, lease time.Duration) (*model.ConsumedEvent, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.claims++
+
+	existing, ok := s.consumed[event.ID]
+	if !ok {
+		claimed := *event
+		s.consumed[event.ID] = &claimed
+		return nil, true, nil
+	}
+
+	previous := *existing
+	if previous.Status == model.StatusCompleted {
+		return &previous, false, nil
+	}
+
+	now := time.Now()
+	if previous.Status == model.StatusProcessing && previous.UpdatedAt.After(now.Add(-lease)) {
+		return &previous, false, repository.ErrConsumedEventInProgress
+	}
+
+	existing.Status = model.StatusProcessing
+	existing.UpdatedAt = now
+	return &previous, true, nil
+}
+
+func (s *memoryAuditStore) UpdateConsumedEvent(ctx context.Context, id, status string, errorMessage *string, retryCount int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if event, ok := s.consumed[id]; ok {
+		event.Status = status
+		event.ErrorMessage = errorMessage
+		event.RetryCount = retryCount
+		event.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (s *memoryAuditStore) PrunePublishedEvents(ctx context.Context, statuses []string, before time.Time, limit int, archive func([]model.PublishedEvent) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var batch []model.PublishedEvent
+	for _, event := range s.published {
+		if slices.Contains(statuses, event.Status) && event.UpdatedAt.Before(before) {
+			batch = append(batch, *event)
+		}
+	}
+	sort.Slice(batch, func(i, j int) bool { return batch[i].UpdatedAt.Before(batch[j].UpdatedAt) })
+	if len(batch) > limit {
+		batch = batch[:limit]
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	// Like the transaction, nothing is deleted unless the archive succeeds
+	if archive != nil {
+		if err := archive(batch); err != nil {
+			return 0, err
+		}
+	}
+
+	s.pruneCalls++
+	for _, event := range batch {
+		delete(s.published, event.ID)
+	}
+	return len(batch), nil
+}
+
+func (s *memoryAuditStore) PruneConsumedEvents(ctx context.Context, statuses []string, before time.Time, limit int, archive func([]model.ConsumedEvent) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var batch []model.ConsumedEvent
+	for _, event := range s.consumed {
+		if slices.Contains(statuses, event.Status) && event.UpdatedAt.Before(before) {
+			batch = append(batch, *event)
+		}
+	}
+	sort.Slice(batch, func(i, j int) bool { return batch[i].UpdatedAt.Before(batch[j].UpdatedAt) })
+	if len(batch) > limit {
+		batch = batch[:limit]
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	if archive != nil {
+		if err := archive(batch); err != nil {
+			return 0, err
+		}
+	}
+
+	s.pruneCalls++
+	for _, event := range batch {
+		delete(s.consumed, event.ID)
+	}
+	return len(batch), nil
+}
+
+// setConsumed records a consumed event as if an earlier delivery had left it
+func (s *memoryAuditStore) setConsumed(id, status string, updatedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.consumed[id] = &model.ConsumedEvent{
+		ID:        id,
+		EventType: testRequestType,
+		Status:    status,
+		CreatedAt: updatedAt,
+		UpdatedAt: updatedAt,
+	}
+}
+
+// consumedEvent returns a copy of a consumed event record
+func (s *memoryAuditStore) consumedEvent(id string) (model.ConsumedEvent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.consumed[id]
+	if !ok {
+		return model.ConsumedEvent{}, false
+	}
+	return *event, true
+}
+
+// publishedEvent returns a copy of an outbox record
+func (s *memoryAuditStore) publishedEvent(id string) (model.PublishedEvent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.published[id]
+	if !ok {
+		return model.PublishedEvent{}, false
+	}
+	return *event, true
+}
+
+// publishedOfType returns copies of the outbox records of an event type
+func (s *memoryAuditStore) publishedOfType(eventType string) []model.PublishedEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []model.PublishedEvent
+	for _, event := range s.published {
+		if event.EventType == eventType {
+			events = append(events, *event)
+		}
+	}
+	return events
+}
+
+func (s *memoryAuditStore) claimCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.claims
+}
+
+// consumedStatus returns the status of a consumed event, or "" if it has none
+func (s *memoryAuditStore) consumedStatus(id string) string {
+	event, _ := s.consumedEvent(id)
+	return event.Status
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
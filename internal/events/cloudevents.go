@@ -0,0 +1,319 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"rbac-service/internal/model"
+	"strings"
+	"time"
+)
+
+// Formats of published events
+const (
+	// FormatLegacy publishes the service's own {id, type, payload, timestamp} envelope
+	FormatLegacy = "LEGACY"
+	// FormatCloudEventsStructured publishes a CloudEvents JSON document
+	FormatCloudEventsStructured = "CLOUDEVENTS_STRUCTURED"
+	// FormatCloudEventsBinary publishes the payload as the body and the
+	// CloudEvents attributes as headers
+	FormatCloudEventsBinary = "CLOUDEVENTS_BINARY"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	DefaultEventSource     = "/rbac-service"
+
+	HeaderContentType          = "content-type"
+	ContentTypeJSON            = "application/json"
+	ContentTypeCloudEventsJSON = "application/cloudevents+json"
+
+	// cloudEventsHeaderPrefix prefixes the attribute headers of binary mode messages
+	cloudEventsHeaderPrefix = "ce_"
+)
+
+// cloudEventsHeaderPrefixes are the attribute header prefixes accepted when
+// consuming: the Kafka and HTTP bindings and both forms of the AMQP binding
+var cloudEventsHeaderPrefixes = []string{"ce_", "ce-", "cloudEvents:", "cloudEvents_"}
+
+// ErrMalformedEvent is returned when a message is not a valid event in any supported format
+var ErrMalformedEvent = errors.New("malformed event")
+
+// EnvelopeConfig controls how published events are encoded
+type EnvelopeConfig struct {
+	Format string
+	// Source is the CloudEvents source of published events
+	Source string
+	// DataSchemaBaseURL, when set, gives published events a dataschema of <base>/<type>
+	DataSchemaBaseURL string
+}
+
+// cloudEvent is the structured mode JSON form of a CloudEvent
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            *time.Time      `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      string          `json:"data_base64,omitempty"`
+	CorrelationID   string          `json:"correlationid,omitempty"`
+	CausationID     string          `json:"causationid,omitempty"`
+}
+
+// legacyEvent is model.Event with the payload left undecoded
+type legacyEvent struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload"`
+	Timestamp     time.Time       `json:"timestamp"`
+	CorrelationID string          `json:"correlation_id"`
+	CausationID   string          `json:"causation_id"`
+}
+
+// Validate checks the format is supported
+func (c EnvelopeConfig) Validate() error {
+	switch c.Format {
+	case "", FormatLegacy, FormatCloudEventsStructured, FormatCloudEventsBinary:
+		return nil
+	}
+	return fmt.Errorf("unsupported event format: %s", c.Format)
+}
+
+// Encode returns the message body and headers of an event in the configured format
+func (c EnvelopeConfig) Encode(event model.Event) ([]byte, map[string]string, error) {
+	if c.Format == "" || c.Format == FormatLegacy {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal event: %w", err)
+		}
+		return body, nil, nil
+	}
+
+	data, err := json.Marshal(event.Payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+
+	ce := cloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              event.ID,
+		Source:          event.Source,
+		Type:            event.Type,
+		Subject:         event.Subject,
+		DataContentType: ContentTypeJSON,
+		DataSchema:      event.DataSchema,
+		Data:            data,
+		CorrelationID:   event.CorrelationID,
+		CausationID:     event.CausationID,
+	}
+	if ce.Source == "" {
+		ce.Source = c.Source
+	}
+	if ce.Source == "" {
+		ce.Source = DefaultEventSource
+	}
+	if ce.Subject == "" {
+		ce.Subject = eventSubject(data)
+	}
+	if ce.DataSchema == "" && c.DataSchemaBaseURL != "" {
+		ce.DataSchema = strings.TrimSuffix(c.DataSchemaBaseURL, "/") + "/" + event.Type
+	}
+	if !event.Timestamp.IsZero() {
+		t := event.Timestamp.UTC()
+		ce.Time = &t
+	}
+
+	if c.Format == FormatCloudEventsStructured {
+		body, err := json.Marshal(ce)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal event: %w", err)
+		}
+		return body, map[string]string{HeaderContentType: ContentTypeCloudEventsJSON}, nil
+	}
+
+	headers := map[string]string{
+		HeaderContentType:                       ContentTypeJSON,
+		cloudEventsHeaderPrefix + "specversion": ce.SpecVersion,
+		cloudEventsHeaderPrefix + "id":          ce.ID,
+		cloudEventsHeaderPrefix + "source":      ce.Source,
+		cloudEventsHeaderPrefix + "type":        ce.Type,
+	}
+	if ce.Subject != "" {
+		headers[cloudEventsHeaderPrefix+"subject"] = ce.Subject
+	}
+	if ce.Time != nil {
+		headers[cloudEventsHeaderPrefix+"time"] = ce.Time.Format(time.RFC3339Nano)
+	}
+	if ce.DataSchema != "" {
+		headers[cloudEventsHeaderPrefix+"dataschema"] = ce.DataSchema
+	}
+	if ce.CorrelationID != "" {
+		headers[cloudEventsHeaderPrefix+"correlationid"] = ce.CorrelationID
+	}
+	if ce.CausationID != "" {
+		headers[cloudEventsHeaderPrefix+"causationid"] = ce.CausationID
+	}
+	return data, headers, nil
+}
+
+// eventSubject names the tenant and role, group or API key an event is about,
+// taken from its payload
+func eventSubject(data json.RawMessage) string {
+	var ids struct {
+		TenantID string `json:"tenant_id"`
+		RoleID   string `json:"role_id"`
+		GroupID  string `json:"group_id"`
+		APIKeyID string `json:"api_key_id"`
+	}
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return ""
+	}
+
+	var parts []string
+	if ids.TenantID != "" {
+		parts = append(parts, "tenants/"+ids.TenantID)
+	}
+	switch {
+	case ids.RoleID != "":
+		parts = append(parts, "roles/"+ids.RoleID)
+	case ids.GroupID != "":
+		parts = append(parts, "groups/"+ids.GroupID)
+	case ids.APIKeyID != "":
+		parts = append(parts, "api_keys/"+ids.APIKeyID)
+	}
+	return strings.Join(parts, "/")
+}
+
+// DecodeEvent parses a message as a CloudEvent in binary or structured mode,
+// or as a legacy event. Binary mode is recognised by a specversion header and
+// structured mode by its content type or a specversion field. The payload of
+// the returned event is the raw JSON data. The returned body is the message in
+// a self-contained form, so binary mode events keep their attributes when
+// stored or dead-lettered without headers.
+func DecodeEvent(msg Message) (model.Event, []byte, error) {
+	if attrs := cloudEventHeaders(msg.Headers); attrs != nil {
+		ce := cloudEvent{
+			SpecVersion:     attrs["specversion"],
+			ID:              attrs["id"],
+			Source:          attrs["source"],
+			Type:            attrs["type"],
+			Subject:         attrs["subject"],
+			DataContentType: msg.Headers[HeaderContentType],
+			DataSchema:      attrs["dataschema"],
+			Data:            msg.Body,
+			CorrelationID:   attrs["correlationid"],
+			CausationID:     attrs["causationid"],
+		}
+		if ts := attrs["time"]; ts != "" {
+			t, err := time.Parse(time.RFC3339Nano, ts)
+			if err != nil {
+				return model.Event{}, nil, fmt.Errorf("%w: invalid time attribute: %v", ErrMalformedEvent, err)
+			}
+			ce.Time = &t
+		}
+
+		event, err := ce.toEvent()
+		if err != nil {
+			return model.Event{}, nil, err
+		}
+
+		body, err := json.Marshal(ce)
+		if err != nil {
+			return model.Event{}, nil, fmt.Errorf("failed to marshal event: %w", err)
+		}
+		return event, body, nil
+	}
+
+	structured := strings.HasPrefix(msg.Headers[HeaderContentType], ContentTypeCloudEventsJSON)
+	if !structured {
+		// Brokers that drop headers still carry the specversion field
+		var probe struct {
+			SpecVersion string `json:"specversion"`
+		}
+		if err := json.Unmarshal(msg.Body, &probe); err != nil {
+			return model.Event{}, nil, fmt.Errorf("%w: %v", ErrMalformedEvent, err)
+		}
+		structured = probe.SpecVersion != ""
+	}
+
+	if structured {
+		var ce cloudEvent
+		if err := json.Unmarshal(msg.Body, &ce); err != nil {
+			return model.Event{}, nil, fmt.Errorf("%w: %v", ErrMalformedEvent, err)
+		}
+		event, err := ce.toEvent()
+		if err != nil {
+			return model.Event{}, nil, err
+		}
+		return event, msg.Body, nil
+	}
+
+	var legacy legacyEvent
+	if err := json.Unmarshal(msg.Body, &legacy); err != nil {
+		return model.Event{}, nil, fmt.Errorf("%w: %v", ErrMalformedEvent, err)
+	}
+
+	event := model.Event{
+		ID:            legacy.ID,
+		Type:          legacy.Type,
+		Payload:       legacy.Payload,
+		Timestamp:     legacy.Timestamp,
+		CorrelationID: legacy.CorrelationID,
+		CausationID:   legacy.CausationID,
+	}
+	return event, msg.Body, nil
+}
+
+// cloudEventHeaders returns the CloudEvents attributes carried in headers, or
+// nil if the message is not in binary mode
+func cloudEventHeaders(headers map[string]string) map[string]string {
+	for _, prefix := range cloudEventsHeaderPrefixes {
+		if _, ok := headers[prefix+"specversion"]; !ok {
+			continue
+		}
+
+		attrs := make(map[string]string)
+		for k, v := range headers {
+			if strings.HasPrefix(k, prefix) {
+				attrs[strings.TrimPrefix(k, prefix)] = v
+			}
+		}
+		return attrs
+	}
+	return nil
+}
+
+// toEvent checks the required attributes and converts the CloudEvent
+func (ce cloudEvent) toEvent() (model.Event, error) {
+	if ce.SpecVersion != CloudEventsSpecVersion {
+		return model.Event{}, fmt.Errorf("%w: unsupported specversion %q", ErrMalformedEvent, ce.SpecVersion)
+	}
+	if ce.ID == "" || ce.Source == "" || ce.Type == "" {
+		return model.Event{}, fmt.Errorf("%w: id, source and type are required", ErrMalformedEvent)
+	}
+	if ce.DataBase64 != "" {
+		return model.Event{}, fmt.Errorf("%w: binary data is not supported", ErrMalformedEvent)
+	}
+	if ct := ce.DataContentType; ct != "" && !strings.HasPrefix(ct, ContentTypeJSON) && !strings.Contains(ct, "+json") {
+		return model.Event{}, fmt.Errorf("%w: unsupported datacontenttype %q", ErrMalformedEvent, ct)
+	}
+
+	event := model.Event{
+		ID:            ce.ID,
+		Type:          ce.Type,
+		Payload:       ce.Data,
+		CorrelationID: ce.CorrelationID,
+		CausationID:   ce.CausationID,
+		Source:        ce.Source,
+		Subject:       ce.Subject,
+		DataSchema:    ce.DataSchema,
+	}
+	if ce.Time != nil {
+		event.Timestamp = *ce.Time
+	}
+	return event, nil
+}
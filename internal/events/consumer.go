@@ -3,90 +3,236 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"rbac-service/internal/auth"
 	"rbac-service/internal/logger"
 	"rbac-service/internal/model"
 	"rbac-service/internal/repository"
+	"rbac-service/internal/reqctx"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
+)
+
+// Dead-letter headers describing why a message was dead-lettered
+const (
+	HeaderDeadLetterReason = "x-dead-letter-reason"
+	HeaderDeadLetterError  = "x-dead-letter-error"
+	HeaderOriginalQueue    = "x-original-queue"
+	HeaderOriginalType     = "x-original-event-type"
+	HeaderRetryCount       = "x-retry-count"
+	HeaderFailedAt         = "x-failed-at"
+)
+
+// Retry headers tracking delayed retries of a message
+const (
+	HeaderRetryAttempt = "x-retry-attempt"
+	HeaderLastError    = "x-last-error"
+)
+
+// consumerClaimLease is how long a delivery being processed holds its claim.
+// A redelivery within the lease is checked again once it runs out; after it,
+// the previous delivery is assumed lost and processing resumes.
+const consumerClaimLease = 2 * time.Minute
+
+// Dead-letter reasons
+const (
+	DeadLetterReasonMalformed        = "malformed"
+	DeadLetterReasonInvalidPayload   = "invalid_payload"
+	DeadLetterReasonRetriesExhausted = "retries_exhausted"
+	DeadLetterReasonDeliveryLimit    = "delivery_limit"
 )
 
 // Consumer handles consuming events with audit trail
 type Consumer struct {
-	provider   QueueProvider
-	auditRepo  *repository.EventAuditRepository
-	router     *EventRouter
-	queue      string
-	maxRetries int
+	provider           QueueProvider
+	auditRepo          AuditStore
+	router             *EventRouter
+	publisher          *Publisher
+	queue              string
+	deadLetterExchange string
+	maxRetries         int
+	workers            int
 }
 
 // NewConsumer creates a new consumer
 func NewConsumer(
 	provider QueueProvider,
-	auditRepo *repository.EventAuditRepository,
+	auditRepo AuditStore,
 	router *EventRouter,
+	publisher *Publisher,
 	queue string,
+	deadLetterExchange string,
 	maxRetries int,
+	workers int,
 ) *Consumer {
 	return &Consumer{
-		provider:   provider,
-		auditRepo:  auditRepo,
-		router:     router,
-		queue:      queue,
-		maxRetries: maxRetries,
+		provider:           provider,
+		auditRepo:          auditRepo,
+		router:             router,
+		publisher:          publisher,
+		queue:              queue,
+		deadLetterExchange: deadLetterExchange,
+		maxRetries:         maxRetries,
+		workers:            workers,
 	}
 }
 
 // Start starts the consumer
 func (c *Consumer) Start(ctx context.Context) error {
-	logger.Info(ctx, "Starting consumer", nil, "queue", c.queue)
+	logger.Info(ctx, "Starting consumer", nil, "queue", c.queue, "workers", fmt.Sprintf("%d", c.workers))
+
+	opts := ConsumeOptions{
+		Workers:            c.workers,
+		KeyFunc:            orderingKey,
+		DeadLetterExchange: c.deadLetterExchange,
+	}
+	return c.provider.Consume(ctx, c.queue, c.handleMessage, opts)
+}
 
-	return c.provider.Consume(ctx, c.queue, c.handleMessage)
+// orderingKey keeps events for the same role, group or tenant in delivery
+// order, so an assign and a remove for the same role are handled one after
+// the other. Other events are keyed by their ID. Ordering holds only until an
+// event fails: its retry is a delayed copy that re-enters the queue behind
+// later events with the same key, which are handled first.
+func orderingKey(msg Message) string {
+	event, _, err := DecodeEvent(msg)
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		RoleID   string `json:"role_id"`
+		GroupID  string `json:"group_id"`
+		TenantID string `json:"tenant_id"`
+	}
+	if data, ok := event.Payload.(json.RawMessage); ok {
+		json.Unmarshal(data, &payload)
+	}
+
+	switch {
+	case payload.RoleID != "":
+		return "role:" + payload.RoleID
+	case payload.GroupID != "":
+		return "group:" + payload.GroupID
+	case payload.TenantID != "":
+		return "tenant:" + payload.TenantID
+	default:
+		return event.ID
+	}
 }
 
-// handleMessage processes a single message
-func (c *Consumer) handleMessage(ctx context.Context, body []byte) error {
-	// Parse event
-	var event model.Event
-	err := json.Unmarshal(body, &event)
+// handleMessage processes a single message. A failed attempt is retried by
+// scheduling a delayed copy of the message and acknowledging this one, so the
+// delivery goroutine never waits out the backoff. The retry gives up its place
+// in the ordering of its key (see orderingKey).
+func (c *Consumer) handleMessage(ctx context.Context, msg Message) error {
+	attempt := retryAttempt(msg.Headers)
+
+	// Parse event, which may be a CloudEvent or a legacy event
+	event, body, err := DecodeEvent(msg)
 	if err != nil {
-		logger.Error(ctx, "Failed to unmarshal event", err)
-		return nil // Don't retry malformed events
+		logger.Error(ctx, "Failed to decode event", err)
+		// Don't retry malformed events
+		return c.deadLetter(ctx, "", msg.Body, DeadLetterReasonMalformed, err.Error(), attempt)
 	}
 
-	logger.Info(ctx, "Received event", nil, "event_id", event.ID, "event_type", event.Type)
+	if event.ID == "" {
+		logger.Error(ctx, "Received event without ID", nil, "event_type", event.Type)
+		// Redeliveries can't be detected without an ID
+		return c.deadLetter(ctx, event.Type, body, DeadLetterReasonMalformed, "event has no id", attempt)
+	}
 
-	// Create audit entry with processing status
+	// Changes made by event handlers are attributed to the consumer in the audit log
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{ID: "event_consumer", Type: auth.PrincipalSystem})
+	ctx = reqctx.WithRequestID(ctx, event.ID)
+
+	// Events published by the handler continue the chain started by the original request
+	correlationID := event.CorrelationID
+	if correlationID == "" {
+		correlationID = event.ID
+	}
+	ctx = reqctx.WithCorrelationID(ctx, correlationID)
+	ctx = reqctx.WithCausationID(ctx, event.ID)
+
+	logger.Info(ctx, "Received event", nil, "event_id", event.ID, "event_type", event.Type, "attempt", fmt.Sprintf("%d", attempt+1))
+
+	// Claim the event, skipping redeliveries of events that already completed
 	auditEvent := &model.ConsumedEvent{
-		ID:        event.ID,
-		EventType: event.Type,
-		Payload:   body,
-		Status:    model.StatusProcessing,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:         event.ID,
+		EventType:  event.Type,
+		Payload:    body,
+		Status:     model.StatusProcessing,
+		RetryCount: attempt,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
 	}
 
-	err = c.auditRepo.CreateConsumedEvent(ctx, auditEvent)
+	previous, claimed, err := c.auditRepo.ClaimConsumedEvent(ctx, auditEvent, consumerClaimLease)
+	if errors.Is(err, repository.ErrConsumedEventInProgress) {
+		return c.recheckLater(ctx, event, msg, previous)
+	}
 	if err != nil {
 		logger.Error(ctx, "Failed to create consumed event audit entry", err, "event_id", event.ID)
-		// Continue processing even if audit fails? Maybe better to retry.
 		return fmt.Errorf("failed to create audit entry: %w", err)
 	}
 
-	// Process event with retry
-	err = c.processWithRetry(ctx, event)
+	if !claimed {
+		logger.Info(ctx, "Event already processed, acknowledging redelivery", nil, "event_id", event.ID, "event_type", event.Type)
+		return nil
+	}
+
+	if previous != nil && previous.Status == model.StatusProcessing {
+		logger.Warn(ctx, "Resuming event left in processing after its lease expired", nil, "event_id", event.ID, "event_type", event.Type)
+	}
+
+	// Process event
+	err = c.router.Dispatch(ctx, event)
 
 	if err != nil {
-		// Update audit entry to failed
+		// Shutting down: leave the event for redelivery rather than counting an attempt
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// Retrying won't fix the payload
+		if errors.Is(err, ErrInvalidPayload) {
+			return c.reject(ctx, event, body, err, attempt)
+		}
+
 		errMsg := err.Error()
-		updateErr := c.auditRepo.UpdateConsumedEvent(ctx, event.ID, model.StatusFailed, &errMsg, c.maxRetries)
+		status := model.StatusFailed
+
+		if attempt < c.maxRetries {
+			// Schedule the next attempt with exponential backoff
+			retryErr := c.scheduleRetry(ctx, event, msg, attempt+1, errMsg)
+			updateErr := c.auditRepo.UpdateConsumedEvent(ctx, event.ID, status, &errMsg, attempt+1)
+			if updateErr != nil {
+				logger.Error(ctx, "Failed to update consumed event audit entry", updateErr, "event_id", event.ID)
+			}
+			return retryErr
+		}
+
+		// Move the event to the dead-letter queue instead of requeueing it forever
+		errMsg = fmt.Sprintf("failed to process event after %d retries: %s", c.maxRetries, errMsg)
+		dlErr := c.deadLetter(ctx, event.Type, body, DeadLetterReasonRetriesExhausted, errMsg, attempt)
+		if dlErr == nil {
+			status = model.StatusDeadLettered
+			c.publishFailed(ctx, event, err.Error())
+		}
+
+		updateErr := c.auditRepo.UpdateConsumedEvent(ctx, event.ID, status, &errMsg, attempt)
 		if updateErr != nil {
 			logger.Error(ctx, "Failed to update consumed event audit entry", updateErr, "event_id", event.ID)
 		}
-		return err
+		return dlErr
 	}
 
 	// Update audit entry to completed
-	updateErr := c.auditRepo.UpdateConsumedEvent(ctx, event.ID, model.StatusCompleted, nil, 0)
+	updateErr := c.auditRepo.UpdateConsumedEvent(ctx, event.ID, model.StatusCompleted, nil, attempt)
 	if updateErr != nil {
 		logger.Error(ctx, "Failed to update consumed event audit entry", updateErr, "event_id", event.ID)
 	}
@@ -94,32 +240,150 @@ func (c *Consumer) handleMessage(ctx context.Context, body []byte) error {
 	return nil
 }
 
-// processWithRetry processes an event with retry logic
-func (c *Consumer) processWithRetry(ctx context.Context, event model.Event) error {
-	var lastErr error
+// reject dead-letters an event whose payload is invalid and publishes the
+// failed completion event of its request type
+func (c *Consumer) reject(ctx context.Context, event model.Event, body []byte, err error, attempt int) error {
+	errMsg := err.Error()
+	logger.Warn(ctx, "Rejecting event with invalid payload", nil, "event_id", event.ID, "event_type", event.Type, "error", errMsg)
 
-	for attempt := 0; attempt <= c.maxRetries; attempt++ {
-		err := c.router.Dispatch(ctx, event)
-		if err == nil {
-			return nil
-		}
+	status := model.StatusFailed
+	dlErr := c.deadLetter(ctx, event.Type, body, DeadLetterReasonInvalidPayload, errMsg, attempt)
+	if dlErr == nil {
+		status = model.StatusDeadLettered
+		c.publishFailed(ctx, event, errMsg)
+	}
 
-		lastErr = err
-		logger.Warn(ctx, "Failed to process event, retrying", nil,
-			"event_id", event.ID,
-			"event_type", event.Type,
-			"attempt", fmt.Sprintf("%d", attempt+1),
-			"max_retries", fmt.Sprintf("%d", c.maxRetries),
-			"error", err.Error(),
-		)
+	updateErr := c.auditRepo.UpdateConsumedEvent(ctx, event.ID, status, &errMsg, attempt)
+	if updateErr != nil {
+		logger.Error(ctx, "Failed to update consumed event audit entry", updateErr, "event_id", event.ID)
+	}
+	return dlErr
+}
 
-		if attempt < c.maxRetries {
-			// Exponential backoff
-			backoff := time.Duration(1<<uint(attempt)) * time.Second
-			logger.Info(ctx, "Waiting before retry", nil, "backoff", backoff.String())
-			time.Sleep(backoff)
-		}
+// publishFailed publishes the failed completion event of a dead-lettered
+// request. Handlers only publish success events: a failed attempt may still be
+// retried, so requesters are told of the failure once the consumer gives up.
+func (c *Consumer) publishFailed(ctx context.Context, event model.Event, errMsg string) {
+	failedType, ok := failedEventType(event.Type)
+	if !ok || c.publisher == nil {
+		return
+	}
+
+	// Echo whatever identifiers the payload has; an invalid payload may miss some
+	payload, _ := DecodePayload[model.ErrorPayload](event.Payload)
+	payload.Error = errMsg
+
+	failed := model.Event{
+		ID:        uuid.New().String(),
+		Type:      failedType,
+		Payload:   payload,
+		Timestamp: time.Now(),
+	}
+	if err := c.publisher.Enqueue(ctx, failed); err != nil {
+		logger.Error(ctx, "Failed to enqueue completion event", err, "event_type", failed.Type, "event_id", failed.ID)
+	}
+}
+
+// failedEventType returns the failed completion event of a request event type
+func failedEventType(eventType string) (string, bool) {
+	if !strings.HasSuffix(eventType, ".request") {
+		return "", false
+	}
+	return strings.TrimSuffix(eventType, ".request") + ".failed", true
+}
+
+// scheduleRetry publishes a delayed copy of the message carrying the next attempt number
+func (c *Consumer) scheduleRetry(ctx context.Context, event model.Event, msg Message, attempt int, errMsg string) error {
+	// Exponential backoff: 1s, 2s, 4s, ...
+	backoff := time.Duration(1<<uint(attempt-1)) * time.Second
+
+	headers := make(map[string]string, len(msg.Headers)+2)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[HeaderRetryAttempt] = strconv.Itoa(attempt)
+	headers[HeaderLastError] = errMsg
+
+	logger.Warn(ctx, "Failed to process event, scheduling retry", nil,
+		"event_id", event.ID,
+		"event_type", event.Type,
+		"attempt", fmt.Sprintf("%d", attempt),
+		"max_retries", fmt.Sprintf("%d", c.maxRetries),
+		"backoff", backoff.String(),
+		"error", errMsg,
+	)
+
+	err := c.provider.PublishDelayed(ctx, c.queue, msg.Body, headers, backoff)
+	if err != nil {
+		// Fall back to an immediate requeue
+		logger.Error(ctx, "Failed to schedule retry", err, "event_id", event.ID)
+		return fmt.Errorf("failed to schedule retry: %w", err)
+	}
+
+	return nil
+}
+
+// recheckLater handles a redelivery of an event another delivery is still
+// processing. Running the handler now would process the event twice at once,
+// and dropping it would lose the event if the other delivery never finishes,
+// so a delayed copy is scheduled for when the claim's lease runs out. The
+// delay is rounded up to a retry backoff, so providers that keep a queue per
+// delay reuse the retry queues.
+func (c *Consumer) recheckLater(ctx context.Context, event model.Event, msg Message, previous *model.ConsumedEvent) error {
+	remaining := consumerClaimLease - time.Since(previous.UpdatedAt)
+	delay := time.Second
+	for delay < remaining {
+		delay *= 2
 	}
 
-	return fmt.Errorf("failed to process event after %d retries: %w", c.maxRetries, lastErr)
+	logger.Info(ctx, "Event is being processed by another delivery, checking again later", nil,
+		"event_id", event.ID,
+		"event_type", event.Type,
+		"delay", delay.String(),
+	)
+
+	err := c.provider.PublishDelayed(ctx, c.queue, msg.Body, msg.Headers, delay)
+	if err != nil {
+		logger.Error(ctx, "Failed to schedule redelivery check", err, "event_id", event.ID)
+		return fmt.Errorf("failed to schedule redelivery check: %w", err)
+	}
+
+	return nil
+}
+
+// retryAttempt returns the number of failed attempts recorded on the message
+func retryAttempt(headers map[string]string) int {
+	attempt, err := strconv.Atoi(headers[HeaderRetryAttempt])
+	if err != nil || attempt < 0 {
+		return 0
+	}
+	return attempt
+}
+
+// deadLetter publishes a message that cannot be processed to the dead-letter
+// exchange with failure metadata headers. The original message is acknowledged
+// only if this succeeds.
+func (c *Consumer) deadLetter(ctx context.Context, eventType string, body []byte, reason, errMsg string, retryCount int) error {
+	routingKey := eventType
+	if routingKey == "" {
+		routingKey = "unknown"
+	}
+
+	headers := map[string]string{
+		HeaderDeadLetterReason: reason,
+		HeaderDeadLetterError:  errMsg,
+		HeaderOriginalQueue:    c.queue,
+		HeaderOriginalType:     eventType,
+		HeaderRetryCount:       fmt.Sprintf("%d", retryCount),
+		HeaderFailedAt:         time.Now().UTC().Format(time.RFC3339),
+	}
+
+	err := c.provider.Publish(ctx, c.deadLetterExchange, routingKey, body, headers)
+	if err != nil {
+		logger.Error(ctx, "Failed to dead-letter message", err, "event_type", eventType, "reason", reason)
+		return fmt.Errorf("failed to dead-letter message: %w", err)
+	}
+
+	logger.Warn(ctx, "Message dead-lettered", nil, "event_type", eventType, "reason", reason, "error", errMsg)
+	return nil
 }
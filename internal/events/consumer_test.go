@@ -0,0 +1,206 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"rbac-service/internal/events"
+	"rbac-service/internal/events/memory"
+	"rbac-service/internal/model"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testQueue              = "test.requests"
+	testDeadLetterExchange = "test.dlx"
+	testDeadLetterQueue    = "test.dead_letter"
+	testRequestType        = "test.thing.request"
+	testFailedType         = "test.thing.failed"
+)
+
+// consumerHarness runs a consumer on the in-memory provider, counting the
+// calls to its handler
+type consumerHarness struct {
+	provider *memory.MemoryProvider
+	store    *memoryAuditStore
+
+	mu      sync.Mutex
+	calls   int
+	failFor int
+}
+
+// newConsumerHarness starts a consumer whose handler fails its first failFor calls
+func newConsumerHarness(t *testing.T, maxRetries, failFor int) *consumerHarness {
+	t.Helper()
+
+	ctx := context.Background()
+	h := &consumerHarness{
+		provider: memory.NewMemoryProvider(),
+		store:    newMemoryAuditStore(),
+		failFor:  failFor,
+	}
+	t.Cleanup(func() { h.provider.Close() })
+
+	if err := h.provider.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.provider.DeclareQueue(ctx, testQueue); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.provider.DeclareExchange(ctx, testDeadLetterExchange, "topic"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.provider.DeclareQueue(ctx, testDeadLetterQueue); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.provider.BindQueue(ctx, testDeadLetterQueue, testDeadLetterExchange, "#"); err != nil {
+		t.Fatal(err)
+	}
+
+	router := events.NewEventRouter()
+	router.Register(testRequestType, func(ctx context.Context, event model.Event) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		h.calls++
+		if h.calls <= h.failFor {
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+
+	publisher := events.NewPublisher(h.provider, h.store, "test.events", events.EnvelopeConfig{})
+	consumer := events.NewConsumer(h.provider, h.store, router, publisher, testQueue, testDeadLetterExchange, maxRetries, 1)
+	if err := consumer.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+// deliver publishes a request event straight to the consumed queue
+func (h *consumerHarness) deliver(t *testing.T, id string) {
+	t.Helper()
+
+	body, err := json.Marshal(model.Event{
+		ID:        id,
+		Type:      testRequestType,
+		Payload:   map[string]string{"thing_id": "thing-1"},
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.provider.Publish(context.Background(), "", testQueue, body, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (h *consumerHarness) handlerCalls() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.calls
+}
+
+func TestConsumerAcknowledgesCompletedEventWithoutHandling(t *testing.T) {
+	h := newConsumerHarness(t, 3, 0)
+	h.store.setConsumed("event-1", model.StatusCompleted, time.Now().Add(-time.Minute))
+
+	h.deliver(t, "event-1")
+	waitFor(t, "redelivery to be claimed", func() bool { return h.store.claimCount() == 1 })
+	time.Sleep(50 * time.Millisecond)
+
+	if calls := h.handlerCalls(); calls != 0 {
+		t.Fatalf("handler called %d times for a completed event, want 0", calls)
+	}
+	if status := h.store.consumedStatus("event-1"); status != model.StatusCompleted {
+		t.Fatalf("status = %q, want %q", status, model.StatusCompleted)
+	}
+}
+
+func TestConsumerResumesStaleProcessingEvent(t *testing.T) {
+	h := newConsumerHarness(t, 3, 0)
+	h.store.setConsumed("event-1", model.StatusProcessing, time.Now().Add(-time.Hour))
+
+	h.deliver(t, "event-1")
+	waitFor(t, "event to complete", func() bool { return h.store.consumedStatus("event-1") == model.StatusCompleted })
+
+	if calls := h.handlerCalls(); calls != 1 {
+		t.Fatalf("handler called %d times, want 1", calls)
+	}
+}
+
+func TestConsumerDefersEventProcessedByAnotherDelivery(t *testing.T) {
+	h := newConsumerHarness(t, 3, 0)
+	// The other delivery's lease runs out in about a second
+	h.store.setConsumed("event-1", model.StatusProcessing, time.Now().Add(-2*time.Minute+time.Second))
+
+	h.deliver(t, "event-1")
+	waitFor(t, "redelivery to be claimed", func() bool { return h.store.claimCount() == 1 })
+
+	if calls := h.handlerCalls(); calls != 0 {
+		t.Fatalf("handler called %d times while another delivery holds the event, want 0", calls)
+	}
+
+	// The other delivery finishes before the deferred copy is checked
+	h.store.UpdateConsumedEvent(context.Background(), "event-1", model.StatusCompleted, nil, 0)
+
+	waitFor(t, "deferred copy to be checked", func() bool { return h.store.claimCount() == 2 })
+	time.Sleep(50 * time.Millisecond)
+
+	if calls := h.handlerCalls(); calls != 0 {
+		t.Fatalf("handler called %d times for an event completed by another delivery, want 0", calls)
+	}
+}
+
+func TestConsumerRetriesFailedEventOnRedelivery(t *testing.T) {
+	h := newConsumerHarness(t, 3, 0)
+	h.store.setConsumed("event-1", model.StatusFailed, time.Now())
+
+	h.deliver(t, "event-1")
+	waitFor(t, "event to complete", func() bool { return h.store.consumedStatus("event-1") == model.StatusCompleted })
+
+	if calls := h.handlerCalls(); calls != 1 {
+		t.Fatalf("handler called %d times, want 1", calls)
+	}
+}
+
+func TestConsumerRetriesFailedAttempt(t *testing.T) {
+	h := newConsumerHarness(t, 3, 1)
+
+	h.deliver(t, "event-1")
+	waitFor(t, "event to complete", func() bool { return h.store.consumedStatus("event-1") == model.StatusCompleted })
+
+	if calls := h.handlerCalls(); calls != 2 {
+		t.Fatalf("handler called %d times, want 2", calls)
+	}
+	if event, _ := h.store.consumedEvent("event-1"); event.RetryCount != 1 {
+		t.Fatalf("retry count = %d, want 1", event.RetryCount)
+	}
+	if failed := h.store.publishedOfType(testFailedType); len(failed) != 0 {
+		t.Fatalf("%d failed events published for a retried event, want 0", len(failed))
+	}
+}
+
+func TestConsumerPublishesFailedEventOnceRetriesRunOut(t *testing.T) {
+	h := newConsumerHarness(t, 1, 2)
+
+	h.deliver(t, "event-1")
+	waitFor(t, "event to be dead-lettered", func() bool { return h.store.consumedStatus("event-1") == model.StatusDeadLettered })
+
+	failed := h.store.publishedOfType(testFailedType)
+	if len(failed) != 1 {
+		t.Fatalf("%d failed events published, want 1", len(failed))
+	}
+
+	var payload model.ErrorPayload
+	if err := json.Unmarshal(failed[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Error != "temporary failure" {
+		t.Fatalf("error = %q, want %q", payload.Error, "temporary failure")
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"rbac-service/internal/app"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/model"
+)
+
+// GroupHandlers contains handlers for group and group permission events. The
+// success event of a request is written to the outbox by the application
+// service in the transaction that applies it.
+type GroupHandlers struct {
+	groupApp *app.GroupAppService
+}
+
+// NewGroupHandlers creates new group handlers
+func NewGroupHandlers(groupApp *app.GroupAppService) *GroupHandlers {
+	return &GroupHandlers{
+		groupApp: groupApp,
+	}
+}
+
+// HandleCreateRequest handles group creation requests. The success event
+// carries the created group.
+func (h *GroupHandlers) HandleCreateRequest(ctx context.Context, event model.Event, payload model.CreateGroupPayload) error {
+	logger.Info(ctx, "Processing group create request", nil, "name", payload.Name, "tenant_id", payload.TenantID)
+
+	group, err := h.groupApp.CreateGroup(ctx, model.CreateGroupRequest{Name: payload.Name, TenantID: payload.TenantID})
+	if err != nil {
+		logger.Error(ctx, "Failed to create group", err, "name", payload.Name)
+		return err
+	}
+
+	logger.Info(ctx, "Successfully created group", nil, "group_id", group.ID)
+	return nil
+}
+
+// HandleAssignPermissionsRequest handles group permission assignment requests
+func (h *GroupHandlers) HandleAssignPermissionsRequest(ctx context.Context, event model.Event, payload model.GroupPermissionPayload) error {
+	return h.handlePermissions(ctx, payload, "assign", h.groupApp.BulkAssignPermissions)
+}
+
+// HandleRemovePermissionsRequest handles group permission removal requests
+func (h *GroupHandlers) HandleRemovePermissionsRequest(ctx context.Context, event model.Event, payload model.GroupPermissionPayload) error {
+	return h.handlePermissions(ctx, payload, "remove", h.groupApp.BulkRemovePermissions)
+}
+
+// HandleSyncPermissionsRequest handles requests replacing a group's permissions
+func (h *GroupHandlers) HandleSyncPermissionsRequest(ctx context.Context, event model.Event, payload model.GroupPermissionPayload) error {
+	return h.handlePermissions(ctx, payload, "sync", h.groupApp.BulkSyncPermissions)
+}
+
+func (h *GroupHandlers) handlePermissions(
+	ctx context.Context,
+	payload model.GroupPermissionPayload,
+	operation string,
+	apply func(ctx context.Context, groupID string, req model.BulkGroupPermissionRequest) error,
+) error {
+	logger.Info(ctx, "Processing group permission "+operation+" request", nil,
+		"group_id", payload.GroupID,
+		"permission_count", fmt.Sprintf("%d", len(payload.Permissions)),
+	)
+
+	err := apply(ctx, payload.GroupID, model.BulkGroupPermissionRequest{Permissions: payload.Permissions})
+	if err != nil {
+		logger.Error(ctx, "Failed to "+operation+" group permissions", err, "group_id", payload.GroupID)
+		return err
+	}
+
+	logger.Info(ctx, "Successfully applied group permission "+operation, nil, "group_id", payload.GroupID)
+	return nil
+}
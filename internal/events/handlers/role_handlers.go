@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"rbac-service/internal/app"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/model"
+)
+
+// RoleHandlers contains handlers for role and role permission events. The
+// success event of a request is written to the outbox by the application
+// service in the transaction that applies it.
+type RoleHandlers struct {
+	roleApp *app.RoleAppService
+}
+
+// NewRoleHandlers creates new role handlers
+func NewRoleHandlers(roleApp *app.RoleAppService) *RoleHandlers {
+	return &RoleHandlers{
+		roleApp: roleApp,
+	}
+}
+
+// HandleCreateRequest handles role creation requests. The success event
+// carries the created role.
+func (h *RoleHandlers) HandleCreateRequest(ctx context.Context, event model.Event, payload model.CreateRolePayload) error {
+	logger.Info(ctx, "Processing role create request", nil, "name", payload.Name, "tenant_id", payload.TenantID)
+
+	role, err := h.roleApp.CreateRole(ctx, model.CreateRoleRequest{Name: payload.Name, TenantID: payload.TenantID})
+	if err != nil {
+		logger.Error(ctx, "Failed to create role", err, "name", payload.Name)
+		return err
+	}
+
+	logger.Info(ctx, "Successfully created role", nil, "role_id", role.ID)
+	return nil
+}
+
+// HandleAssignPermissionsRequest handles role permission assignment requests
+func (h *RoleHandlers) HandleAssignPermissionsRequest(ctx context.Context, event model.Event, payload model.RolePermissionPayload) error {
+	return h.handlePermissions(ctx, payload, "assign", h.roleApp.BulkAssignPermissions)
+}
+
+// HandleRemovePermissionsRequest handles role permission removal requests
+func (h *RoleHandlers) HandleRemovePermissionsRequest(ctx context.Context, event model.Event, payload model.RolePermissionPayload) error {
+	return h.handlePermissions(ctx, payload, "remove", h.roleApp.BulkRemovePermissions)
+}
+
+// HandleSyncPermissionsRequest handles requests replacing a role's permissions
+func (h *RoleHandlers) HandleSyncPermissionsRequest(ctx context.Context, event model.Event, payload model.RolePermissionPayload) error {
+	return h.handlePermissions(ctx, payload, "sync", h.roleApp.BulkSyncPermissions)
+}
+
+func (h *RoleHandlers) handlePermissions(
+	ctx context.Context,
+	payload model.RolePermissionPayload,
+	operation string,
+	apply func(ctx context.Context, roleID string, req model.BulkRolePermissionRequest) error,
+) error {
+	logger.Info(ctx, "Processing role permission "+operation+" request", nil,
+		"role_id", payload.RoleID,
+		"permission_count", fmt.Sprintf("%d", len(payload.Permissions)),
+	)
+
+	err := apply(ctx, payload.RoleID, model.BulkRolePermissionRequest{Permissions: payload.Permissions})
+	if err != nil {
+		logger.Error(ctx, "Failed to "+operation+" role permissions", err, "role_id", payload.RoleID)
+		return err
+	}
+
+	logger.Info(ctx, "Successfully applied role permission "+operation, nil, "role_id", payload.RoleID)
+	return nil
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"rbac-service/internal/app"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/model"
+)
+
+// TenantHandlers contains handlers for tenant entitlement events. The
+// success event of a request is written to the outbox by the application
+// service in the transaction that applies it.
+type TenantHandlers struct {
+	tenantApp *app.TenantAppService
+}
+
+// NewTenantHandlers creates new tenant handlers
+func NewTenantHandlers(tenantApp *app.TenantAppService) *TenantHandlers {
+	return &TenantHandlers{
+		tenantApp: tenantApp,
+	}
+}
+
+// HandleAssignPermissionsRequest handles requests entitling a tenant to permissions
+func (h *TenantHandlers) HandleAssignPermissionsRequest(ctx context.Context, event model.Event, payload model.TenantPermissionPayload) error {
+	return h.handlePermissions(ctx, payload, "assign", h.tenantApp.BulkAssignPermissions)
+}
+
+// HandleRemovePermissionsRequest handles requests withdrawing a tenant's permissions
+func (h *TenantHandlers) HandleRemovePermissionsRequest(ctx context.Context, event model.Event, payload model.TenantPermissionPayload) error {
+	return h.handlePermissions(ctx, payload, "remove", h.tenantApp.BulkRemovePermissions)
+}
+
+// HandleSyncPermissionsRequest handles requests replacing a tenant's permissions
+func (h *TenantHandlers) HandleSyncPermissionsRequest(ctx context.Context, event model.Event, payload model.TenantPermissionPayload) error {
+	return h.handlePermissions(ctx, payload, "sync", h.tenantApp.BulkSyncPermissions)
+}
+
+func (h *TenantHandlers) handlePermissions(
+	ctx context.Context,
+	payload model.TenantPermissionPayload,
+	operation string,
+	apply func(ctx context.Context, req model.BulkTenantPermissionRequest) error,
+) error {
+	logger.Info(ctx, "Processing tenant permission "+operation+" request", nil,
+		"tenant_id", payload.TenantID,
+		"permission_count", fmt.Sprintf("%d", len(payload.Permissions)),
+	)
+
+	err := apply(ctx, model.BulkTenantPermissionRequest{TenantID: payload.TenantID, Permissions: payload.Permissions})
+	if err != nil {
+		logger.Error(ctx, "Failed to "+operation+" tenant permissions", err, "tenant_id", payload.TenantID)
+		return err
+	}
+
+	logger.Info(ctx, "Successfully applied tenant permission "+operation, nil, "tenant_id", payload.TenantID)
+	return nil
+}
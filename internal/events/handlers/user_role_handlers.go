@@ -2,45 +2,28 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"rbac-service/internal/app"
-	"rbac-service/internal/events"
 	"rbac-service/internal/logger"
 	"rbac-service/internal/model"
-	"time"
-
-	"github.com/google/uuid"
 )
 
-// UserRoleHandlers contains handlers for user-role events
+// UserRoleHandlers contains handlers for user-role events. The success
+// event of a request is written to the outbox by the application service in
+// the transaction that applies it.
 type UserRoleHandlers struct {
-	roleApp   *app.RoleAppService
-	publisher *events.Publisher
+	roleApp *app.RoleAppService
 }
 
 // NewUserRoleHandlers creates new user-role handlers
-func NewUserRoleHandlers(roleApp *app.RoleAppService, publisher *events.Publisher) *UserRoleHandlers {
+func NewUserRoleHandlers(roleApp *app.RoleAppService) *UserRoleHandlers {
 	return &UserRoleHandlers{
-		roleApp:   roleApp,
-		publisher: publisher,
+		roleApp: roleApp,
 	}
 }
 
 // HandleAssignRequest handles user-role assignment requests
-func (h *UserRoleHandlers) HandleAssignRequest(ctx context.Context, event model.Event) error {
-	// Parse payload
-	var payload model.UserRolePayload
-	payloadBytes, err := json.Marshal(event.Payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
-	err = json.Unmarshal(payloadBytes, &payload)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal payload: %w", err)
-	}
-
+func (h *UserRoleHandlers) HandleAssignRequest(ctx context.Context, event model.Event, payload model.UserRolePayload) error {
 	logger.Info(ctx, "Processing user-role assign request", nil,
 		"role_id", payload.RoleID,
 		"user_count", fmt.Sprintf("%d", len(payload.UserIDs)),
@@ -48,58 +31,18 @@ func (h *UserRoleHandlers) HandleAssignRequest(ctx context.Context, event model.
 
 	// Call application service
 	req := model.BulkUserRoleRequest{UserIDs: payload.UserIDs}
-	err = h.roleApp.BulkAssignUsers(ctx, payload.RoleID, req)
-
-	// Prepare completion event
-	completionEvent := model.Event{
-		ID:        uuid.New().String(),
-		Type:      model.EventUserRoleAssignSuccess,
-		Timestamp: time.Now(),
-	}
-
+	err := h.roleApp.BulkAssignUsers(ctx, payload.RoleID, req)
 	if err != nil {
-		// Failed event
-		completionEvent.Type = model.EventUserRoleAssignFailed
-		completionEvent.Payload = model.ErrorPayload{
-			UserIDs: payload.UserIDs,
-			RoleID:  payload.RoleID,
-			Error:   err.Error(),
-		}
-
 		logger.Error(ctx, "Failed to assign users to role", err, "role_id", payload.RoleID)
-	} else {
-		// Success event
-		completionEvent.Payload = payload
-		logger.Info(ctx, "Successfully assigned users to role", nil, "role_id", payload.RoleID)
-	}
-
-	// Publish completion event
-	publishErr := h.publisher.PublishWithRetry(ctx, completionEvent, 3)
-	if publishErr != nil {
-		logger.Error(ctx, "Failed to publish completion event", publishErr,
-			"event_type", completionEvent.Type,
-			"event_id", completionEvent.ID,
-		)
-		// Don't fail the handler if publishing fails
+		return err
 	}
 
-	return err
+	logger.Info(ctx, "Successfully assigned users to role", nil, "role_id", payload.RoleID)
+	return nil
 }
 
 // HandleRemoveRequest handles user-role removal requests
-func (h *UserRoleHandlers) HandleRemoveRequest(ctx context.Context, event model.Event) error {
-	// Parse payload
-	var payload model.UserRolePayload
-	payloadBytes, err := json.Marshal(event.Payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
-	err = json.Unmarshal(payloadBytes, &payload)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal payload: %w", err)
-	}
-
+func (h *UserRoleHandlers) HandleRemoveRequest(ctx context.Context, event model.Event, payload model.UserRolePayload) error {
 	logger.Info(ctx, "Processing user-role remove request", nil,
 		"role_id", payload.RoleID,
 		"user_count", fmt.Sprintf("%d", len(payload.UserIDs)),
@@ -107,40 +50,12 @@ func (h *UserRoleHandlers) HandleRemoveRequest(ctx context.Context, event model.
 
 	// Call application service
 	req := model.BulkUserRoleRequest{UserIDs: payload.UserIDs}
-	err = h.roleApp.BulkRemoveUsers(ctx, payload.RoleID, req)
-
-	// Prepare completion event
-	completionEvent := model.Event{
-		ID:        uuid.New().String(),
-		Type:      model.EventUserRoleRemoveSuccess,
-		Timestamp: time.Now(),
-	}
-
+	err := h.roleApp.BulkRemoveUsers(ctx, payload.RoleID, req)
 	if err != nil {
-		// Failed event
-		completionEvent.Type = model.EventUserRoleRemoveFailed
-		completionEvent.Payload = model.ErrorPayload{
-			UserIDs: payload.UserIDs,
-			RoleID:  payload.RoleID,
-			Error:   err.Error(),
-		}
-
 		logger.Error(ctx, "Failed to remove users from role", err, "role_id", payload.RoleID)
-	} else {
-		// Success event
-		completionEvent.Payload = payload
-		logger.Info(ctx, "Successfully removed users from role", nil, "role_id", payload.RoleID)
-	}
-
-	// Publish completion event
-	publishErr := h.publisher.PublishWithRetry(ctx, completionEvent, 3)
-	if publishErr != nil {
-		logger.Error(ctx, "Failed to publish completion event", publishErr,
-			"event_type", completionEvent.Type,
-			"event_id", completionEvent.ID,
-		)
-		// Don't fail the handler if publishing fails
+		return err
 	}
 
-	return err
+	logger.Info(ctx, "Successfully removed users from role", nil, "role_id", payload.RoleID)
+	return nil
 }
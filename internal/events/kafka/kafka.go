@@ -0,0 +1,741 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rbac-service/internal/events"
+	"rbac-service/internal/logger"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Internal headers carrying routing information across Kafka
+const (
+	headerRoutingKey = "x-routing-key"
+	headerDeliverAt  = "x-deliver-at"
+)
+
+const (
+	// directTopicSuffix names the per-queue topic used for the default exchange and delayed messages
+	directTopicSuffix = ".direct"
+	// maxRedeliveryBackoff caps the wait between attempts at a failed message
+	maxRedeliveryBackoff = 30 * time.Second
+	// defaultMaxDeliveries bounds the attempts at a failed message when no limit is configured
+	defaultMaxDeliveries = 5
+)
+
+// KafkaProvider implements the QueueProvider interface for Kafka. Exchanges map
+// to topics and routing keys to message keys, so messages with the same routing
+// key stay in one partition. A queue is a consumer group named after the queue;
+// its bindings are matched client-side and messages that match no binding are
+// skipped. Offsets are committed only after the handler succeeds.
+type KafkaProvider struct {
+	brokers           []string
+	partitions        int
+	replicationFactor int
+	maxDeliveries     int
+	mu                sync.RWMutex
+	closed            bool
+	writer            *kafka.Writer
+	client            *kafka.Client
+	exchanges         map[string]string    // exchange name -> exchange type
+	bindings          map[string][]binding // queue name -> bindings
+	consumers         []*consumer
+}
+
+type binding struct {
+	exchange   string
+	routingKey string
+}
+
+// consumer tracks a running consumer so it can be drained
+type consumer struct {
+	readers   []*kafka.Reader
+	pool      *events.WorkerPool
+	submitMu  sync.Mutex // WorkerPool.Submit must not be called concurrently
+	stopFetch context.CancelFunc
+	cancel    context.CancelFunc
+	done      chan struct{} // closed when all fetch loops exit
+}
+
+// NewKafkaProvider creates a new Kafka provider. A message that fails
+// maxDeliveries times is moved to the dead-letter exchange (default 5).
+func NewKafkaProvider(brokers []string, partitions, replicationFactor, maxDeliveries int) (*KafkaProvider, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("Kafka brokers are required")
+	}
+	if maxDeliveries <= 0 {
+		maxDeliveries = defaultMaxDeliveries
+	}
+
+	return &KafkaProvider{
+		brokers:           brokers,
+		partitions:        partitions,
+		replicationFactor: replicationFactor,
+		maxDeliveries:     maxDeliveries,
+		exchanges:         make(map[string]string),
+		bindings:          make(map[string][]binding),
+	}, nil
+}
+
+// Connect creates the writer and admin client and verifies the brokers are reachable
+func (k *KafkaProvider) Connect(ctx context.Context) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.closed {
+		return fmt.Errorf("provider is closed")
+	}
+
+	client := &kafka.Client{
+		Addr:    kafka.TCP(k.brokers...),
+		Timeout: 10 * time.Second,
+	}
+
+	if _, err := client.Metadata(ctx, &kafka.MetadataRequest{}); err != nil {
+		return fmt.Errorf("failed to connect to Kafka: %w", err)
+	}
+
+	if k.writer != nil {
+		k.writer.Close()
+	}
+
+	k.client = client
+	k.writer = &kafka.Writer{
+		Addr:         kafka.TCP(k.brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 10 * time.Millisecond,
+	}
+
+	logger.Info(ctx, "Kafka provider connected", nil, "brokers", fmt.Sprintf("%d", len(k.brokers)))
+	return nil
+}
+
+// Close stops all consumers and closes the writer
+func (k *KafkaProvider) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.closed = true
+
+	for _, c := range k.consumers {
+		c.stopFetch()
+		c.cancel()
+		c.closeReaders()
+	}
+	k.consumers = nil
+
+	if k.writer != nil {
+		k.writer.Close()
+		k.writer = nil
+	}
+	return nil
+}
+
+// Publish writes a message to the exchange's topic, keyed by routing key.
+// The default exchange ("") writes to the direct topic of the queue named by the routing key.
+func (k *KafkaProvider) Publish(ctx context.Context, exchange, routingKey string, body []byte, headers map[string]string) error {
+	topic := exchange
+	if exchange == "" {
+		topic = directTopic(routingKey)
+	}
+
+	return k.write(ctx, topic, routingKey, body, headers)
+}
+
+// PublishDelayed writes a message to the queue's direct topic with the time it
+// becomes deliverable. The consumer holds it until then.
+func (k *KafkaProvider) PublishDelayed(ctx context.Context, queue string, body []byte, headers map[string]string, delay time.Duration) error {
+	withDeadline := make(map[string]string, len(headers)+1)
+	for key, v := range headers {
+		withDeadline[key] = v
+	}
+	withDeadline[headerDeliverAt] = strconv.FormatInt(time.Now().Add(delay).UnixMilli(), 10)
+
+	return k.write(ctx, directTopic(queue), queue, body, withDeadline)
+}
+
+func (k *KafkaProvider) write(ctx context.Context, topic, routingKey string, body []byte, headers map[string]string) error {
+	k.mu.RLock()
+	writer := k.writer
+	closed := k.closed
+	k.mu.RUnlock()
+
+	if closed {
+		return fmt.Errorf("provider is closed")
+	}
+	if writer == nil {
+		return fmt.Errorf("provider is not connected")
+	}
+
+	msgHeaders := make([]kafka.Header, 0, len(headers)+1)
+	msgHeaders = append(msgHeaders, kafka.Header{Key: headerRoutingKey, Value: []byte(routingKey)})
+	for key, v := range headers {
+		msgHeaders = append(msgHeaders, kafka.Header{Key: key, Value: []byte(v)})
+	}
+
+	err := writer.WriteMessages(ctx, kafka.Message{
+		Topic:   topic,
+		Key:     []byte(routingKey),
+		Value:   body,
+		Headers: msgHeaders,
+		Time:    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	return nil
+}
+
+// Consume starts consuming messages from a queue. One consumer group reads the
+// topics the queue is bound to and another reads the queue's direct topic.
+// A failed message is retried in place with backoff, since committing past it
+// would lose it, up to maxDeliveries times; then it is moved to the
+// dead-letter exchange and committed.
+func (k *KafkaProvider) Consume(ctx context.Context, queue string, handler events.MessageHandler, opts events.ConsumeOptions) error {
+	opts = opts.Normalize()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.closed {
+		return fmt.Errorf("provider is closed")
+	}
+
+	bindings := append([]binding(nil), k.bindings[queue]...)
+	if len(bindings) == 0 {
+		return fmt.Errorf("failed to start consuming: queue %s has no bindings", queue)
+	}
+
+	exchanges := make(map[string]string, len(k.exchanges))
+	for name, kind := range k.exchanges {
+		exchanges[name] = kind
+	}
+
+	topics := make([]string, 0, len(bindings))
+	seen := make(map[string]bool)
+	for _, b := range bindings {
+		if !seen[b.exchange] {
+			topics = append(topics, b.exchange)
+			seen[b.exchange] = true
+		}
+	}
+
+	consumerCtx, cancel := context.WithCancel(ctx)
+	fetchCtx, stopFetch := context.WithCancel(consumerCtx)
+
+	c := &consumer{
+		readers: []*kafka.Reader{
+			k.newReader(queue, topics),
+			k.newReader(directTopic(queue), []string{directTopic(queue)}),
+		},
+		pool:      events.NewWorkerPool(consumerCtx, opts, redeliver(queue, k.maxDeliveries, handler, k.deadLetter(queue, opts.DeadLetterExchange))),
+		stopFetch: stopFetch,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	k.consumers = append(k.consumers, c)
+
+	// Limits unacknowledged messages across both readers, like a RabbitMQ prefetch count
+	inFlight := make(chan struct{}, opts.Prefetch)
+
+	matches := func(msg kafka.Message, routingKey string) bool {
+		for _, b := range bindings {
+			if b.exchange == msg.Topic && matchBinding(exchanges[b.exchange], b.routingKey, routingKey) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(c.readers))
+	go c.fetch(fetchCtx, consumerCtx, &wg, c.readers[0], queue, inFlight, matches)
+	go c.fetch(fetchCtx, consumerCtx, &wg, c.readers[1], queue, inFlight, nil)
+	go func() {
+		wg.Wait()
+		close(c.done)
+	}()
+
+	logger.Info(consumerCtx, "Started consuming from queue", nil, "queue", queue, "workers", fmt.Sprintf("%d", opts.Workers))
+	return nil
+}
+
+func (k *KafkaProvider) newReader(groupID string, topics []string) *kafka.Reader {
+	return kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     k.brokers,
+		GroupID:     groupID,
+		GroupTopics: topics,
+		StartOffset: kafka.FirstOffset,
+		MaxWait:     500 * time.Millisecond,
+	})
+}
+
+// fetch reads messages from a reader and submits them to the worker pool. When
+// matches is nil every message is delivered, once its x-deliver-at time is
+// reached. Messages that are not due yet are held by deliverLater, so later
+// messages keep being read meanwhile.
+func (c *consumer) fetch(
+	fetchCtx, consumerCtx context.Context,
+	wg *sync.WaitGroup,
+	reader *kafka.Reader,
+	queue string,
+	inFlight chan struct{},
+	matches func(msg kafka.Message, routingKey string) bool,
+) {
+	defer wg.Done()
+
+	offsets := newOffsetTracker()
+
+	commit := func(msg kafka.Message) {
+		last, ok := offsets.complete(msg)
+		if !ok {
+			return
+		}
+		if err := reader.CommitMessages(consumerCtx, last); err != nil {
+			// The message will be redelivered and skipped as already consumed
+			logger.Warn(consumerCtx, "Failed to commit offset", nil, "queue", queue, "topic", last.Topic, "error", err.Error())
+		}
+	}
+
+	submit := func(msg kafka.Message, delivery events.Message) {
+		c.submitMu.Lock()
+		c.pool.Submit(delivery, func(err error) {
+			defer func() { <-inFlight }()
+			if err != nil {
+				// The consumer is shutting down or the message could not be
+				// dead-lettered; the offset stays uncommitted so it is
+				// delivered again after a restart or rebalance
+				logger.Error(consumerCtx, "Message left uncommitted", err, "queue", queue, "topic", msg.Topic)
+				return
+			}
+			commit(msg)
+		})
+		c.submitMu.Unlock()
+	}
+
+	for {
+		select {
+		case inFlight <- struct{}{}:
+		case <-fetchCtx.Done():
+			logger.Info(consumerCtx, "Consumer stopped", nil, "queue", queue, "topic_group", reader.Config().GroupID)
+			return
+		}
+
+		msg, err := reader.FetchMessage(fetchCtx)
+		if err != nil {
+			<-inFlight
+			if fetchCtx.Err() != nil {
+				logger.Info(consumerCtx, "Consumer stopped", nil, "queue", queue, "topic_group", reader.Config().GroupID)
+				return
+			}
+			logger.Error(consumerCtx, "Failed to fetch message", err, "queue", queue)
+			continue
+		}
+
+		offsets.track(msg)
+		delivery, deliverAt := toMessage(msg)
+
+		if matches != nil && !matches(msg, delivery.RoutingKey) {
+			// Not bound to this queue
+			commit(msg)
+			<-inFlight
+			continue
+		}
+
+		if wait := time.Until(deliverAt); wait > 0 {
+			// A held message doesn't count against the prefetch until it is due
+			<-inFlight
+			wg.Add(1)
+			go c.deliverLater(fetchCtx, wg, wait, inFlight, func() { submit(msg, delivery) })
+			continue
+		}
+
+		submit(msg, delivery)
+	}
+}
+
+// deliverLater waits until a delayed message is due and then submits it. The
+// message stays uncommitted meanwhile, so offsets after it are not committed
+// either and a restart delivers it again. Waiting messages are dropped when
+// fetching stops; Drain runs only after they have returned.
+func (c *consumer) deliverLater(fetchCtx context.Context, wg *sync.WaitGroup, wait time.Duration, inFlight chan struct{}, submit func()) {
+	defer wg.Done()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-fetchCtx.Done():
+		return
+	}
+
+	select {
+	case inFlight <- struct{}{}:
+	case <-fetchCtx.Done():
+		return
+	}
+
+	submit()
+}
+
+// redeliver wraps a handler so a failed message is handled again after a
+// backoff, up to maxDeliveries times, and then passed to deadLetter. It returns
+// an error only if the message could not be dead-lettered or the consumer
+// stopped, leaving the offset uncommitted.
+func redeliver(queue string, maxDeliveries int, handler events.MessageHandler, deadLetter func(ctx context.Context, msg events.Message, deliveries int, cause error) error) events.MessageHandler {
+	return func(ctx context.Context, msg events.Message) error {
+		backoff := time.Second
+		for delivery := 1; ; delivery++ {
+			err := handler(ctx, msg)
+			if err == nil {
+				return nil
+			}
+
+			if delivery >= maxDeliveries {
+				logger.Error(ctx, "Failed to process message", err, "queue", queue, "deliveries", fmt.Sprintf("%d", delivery))
+				return deadLetter(ctx, msg, delivery, err)
+			}
+
+			logger.Error(ctx, "Failed to process message", err, "queue", queue, "retry_in", backoff.String())
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			backoff *= 2
+			if backoff > maxRedeliveryBackoff {
+				backoff = maxRedeliveryBackoff
+			}
+		}
+	}
+}
+
+// deadLetter returns a function that publishes a message that reached the
+// delivery limit to the dead-letter exchange, or drops it if there is none
+func (k *KafkaProvider) deadLetter(queue, deadLetterExchange string) func(ctx context.Context, msg events.Message, deliveries int, cause error) error {
+	return func(ctx context.Context, msg events.Message, deliveries int, cause error) error {
+		count := fmt.Sprintf("%d", deliveries)
+
+		if deadLetterExchange == "" {
+			logger.Warn(ctx, "Message reached delivery limit, dropping", nil, "queue", queue, "deliveries", count)
+			return nil
+		}
+
+		headers := make(map[string]string, len(msg.Headers)+5)
+		for key, v := range msg.Headers {
+			headers[key] = v
+		}
+		headers[events.HeaderDeadLetterReason] = events.DeadLetterReasonDeliveryLimit
+		headers[events.HeaderDeadLetterError] = cause.Error()
+		headers[events.HeaderOriginalQueue] = queue
+		headers[events.HeaderRetryCount] = count
+		headers[events.HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339)
+
+		if err := k.Publish(ctx, deadLetterExchange, msg.RoutingKey, msg.Body, headers); err != nil {
+			return fmt.Errorf("failed to dead-letter message: %w", err)
+		}
+
+		logger.Warn(ctx, "Message reached delivery limit, dead-lettered", nil, "queue", queue, "deliveries", count, "exchange", deadLetterExchange)
+		return nil
+	}
+}
+
+// StopConsuming stops fetching and waits for in-flight messages to be handled
+func (k *KafkaProvider) StopConsuming(ctx context.Context) error {
+	k.mu.Lock()
+	consumers := k.consumers
+	k.consumers = nil
+	k.mu.Unlock()
+
+	for _, c := range consumers {
+		c.stopFetch()
+	}
+
+	for _, c := range consumers {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for consumer to stop: %w", ctx.Err())
+		}
+
+		if err := c.pool.Drain(ctx); err != nil {
+			return fmt.Errorf("timed out waiting for in-flight messages: %w", err)
+		}
+
+		c.cancel()
+		c.closeReaders()
+	}
+
+	return nil
+}
+
+func (c *consumer) closeReaders() {
+	for _, r := range c.readers {
+		r.Close()
+	}
+}
+
+// HealthCheck verifies the brokers respond to a metadata request
+func (k *KafkaProvider) HealthCheck(ctx context.Context) error {
+	client, err := k.getClient()
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.Metadata(ctx, &kafka.MetadataRequest{}); err != nil {
+		return fmt.Errorf("failed to reach Kafka brokers: %w", err)
+	}
+	return nil
+}
+
+// DeclareExchange creates the topic backing an exchange of type topic, direct or fanout
+func (k *KafkaProvider) DeclareExchange(ctx context.Context, exchange, exchangeType string) error {
+	switch exchangeType {
+	case "topic", "direct", "fanout":
+	default:
+		return fmt.Errorf("failed to declare exchange: unsupported type %s", exchangeType)
+	}
+
+	if err := k.createTopic(ctx, exchange); err != nil {
+		return fmt.Errorf("failed to declare exchange: %w", err)
+	}
+
+	k.mu.Lock()
+	k.exchanges[exchange] = exchangeType
+	k.mu.Unlock()
+
+	logger.Info(ctx, "Exchange declared", nil, "exchange", exchange, "type", exchangeType)
+	return nil
+}
+
+// DeclareQueue creates the direct topic of a queue
+func (k *KafkaProvider) DeclareQueue(ctx context.Context, queue string) (string, error) {
+	if err := k.createTopic(ctx, directTopic(queue)); err != nil {
+		return "", fmt.Errorf("failed to declare queue: %w", err)
+	}
+
+	logger.Info(ctx, "Queue declared", nil, "queue", queue)
+	return queue, nil
+}
+
+// BindQueue records a binding of a queue to an exchange. Bindings are matched
+// by the consumer, so they must be declared before Consume is called.
+func (k *KafkaProvider) BindQueue(ctx context.Context, queue, exchange, routingKey string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.exchanges[exchange]; !ok {
+		return fmt.Errorf("failed to bind queue: exchange %s not found", exchange)
+	}
+
+	for _, b := range k.bindings[queue] {
+		if b.exchange == exchange && b.routingKey == routingKey {
+			return nil
+		}
+	}
+
+	k.bindings[queue] = append(k.bindings[queue], binding{exchange: exchange, routingKey: routingKey})
+	logger.Info(ctx, "Queue bound to exchange", nil, "queue", queue, "exchange", exchange, "routing_key", routingKey)
+	return nil
+}
+
+// PurgeQueue moves the queue's consumer group offsets to the end of its topics
+// and returns the number of messages skipped. It fails while the queue has
+// active consumers, since the broker rejects commits from outside the group.
+func (k *KafkaProvider) PurgeQueue(ctx context.Context, queue string) (int, error) {
+	k.mu.RLock()
+	topics := make([]string, 0, len(k.bindings[queue]))
+	for _, b := range k.bindings[queue] {
+		topics = append(topics, b.exchange)
+	}
+	k.mu.RUnlock()
+
+	count := 0
+	if len(topics) > 0 {
+		n, err := k.skipToEnd(ctx, queue, topics)
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge queue: %w", err)
+		}
+		count += n
+	}
+
+	n, err := k.skipToEnd(ctx, directTopic(queue), []string{directTopic(queue)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge queue: %w", err)
+	}
+	count += n
+
+	logger.Info(ctx, "Queue purged", nil, "queue", queue, "messages", fmt.Sprintf("%d", count))
+	return count, nil
+}
+
+// skipToEnd commits the latest offset of every partition of the topics for a
+// consumer group and returns how many uncommitted messages were skipped
+func (k *KafkaProvider) skipToEnd(ctx context.Context, groupID string, topics []string) (int, error) {
+	client, err := k.getClient()
+	if err != nil {
+		return 0, err
+	}
+
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: topics})
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch topic metadata: %w", err)
+	}
+
+	listReq := &kafka.ListOffsetsRequest{Topics: make(map[string][]kafka.OffsetRequest)}
+	fetchReq := &kafka.OffsetFetchRequest{GroupID: groupID, Topics: make(map[string][]int)}
+	for _, t := range meta.Topics {
+		if t.Error != nil {
+			return 0, fmt.Errorf("failed to fetch metadata of topic %s: %w", t.Name, t.Error)
+		}
+		for _, p := range t.Partitions {
+			listReq.Topics[t.Name] = append(listReq.Topics[t.Name], kafka.FirstOffsetOf(p.ID), kafka.LastOffsetOf(p.ID))
+			fetchReq.Topics[t.Name] = append(fetchReq.Topics[t.Name], p.ID)
+		}
+	}
+
+	listed, err := client.ListOffsets(ctx, listReq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list offsets: %w", err)
+	}
+
+	fetched, err := client.OffsetFetch(ctx, fetchReq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch committed offsets: %w", err)
+	}
+
+	committed := make(map[string]map[int]int64)
+	for topic, partitions := range fetched.Topics {
+		committed[topic] = make(map[int]int64, len(partitions))
+		for _, p := range partitions {
+			committed[topic][p.Partition] = p.CommittedOffset
+		}
+	}
+
+	count := 0
+	commitReq := &kafka.OffsetCommitRequest{
+		GroupID:      groupID,
+		GenerationID: -1,
+		Topics:       make(map[string][]kafka.OffsetCommit),
+	}
+	for topic, partitions := range listed.Topics {
+		for _, p := range partitions {
+			if p.Error != nil {
+				return 0, fmt.Errorf("failed to list offsets of topic %s: %w", topic, p.Error)
+			}
+
+			from := p.FirstOffset
+			if offset, ok := committed[topic][p.Partition]; ok && offset > from {
+				from = offset
+			}
+			if p.LastOffset > from {
+				count += int(p.LastOffset - from)
+			}
+
+			commitReq.Topics[topic] = append(commitReq.Topics[topic], kafka.OffsetCommit{Partition: p.Partition, Offset: p.LastOffset})
+		}
+	}
+
+	res, err := client.OffsetCommit(ctx, commitReq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit offsets: %w", err)
+	}
+	for topic, partitions := range res.Topics {
+		for _, p := range partitions {
+			if p.Error != nil {
+				return 0, fmt.Errorf("failed to commit offset of topic %s: %w", topic, p.Error)
+			}
+		}
+	}
+
+	return count, nil
+}
+
+// createTopic creates a topic, treating an existing topic as success
+func (k *KafkaProvider) createTopic(ctx context.Context, topic string) error {
+	client, err := k.getClient()
+	if err != nil {
+		return err
+	}
+
+	res, err := client.CreateTopics(ctx, &kafka.CreateTopicsRequest{
+		Topics: []kafka.TopicConfig{{
+			Topic:             topic,
+			NumPartitions:     k.partitions,
+			ReplicationFactor: k.replicationFactor,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create topic %s: %w", topic, err)
+	}
+
+	if err := res.Errors[topic]; err != nil && !errors.Is(err, kafka.TopicAlreadyExists) {
+		return fmt.Errorf("failed to create topic %s: %w", topic, err)
+	}
+	return nil
+}
+
+func (k *KafkaProvider) getClient() (*kafka.Client, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.closed {
+		return nil, fmt.Errorf("provider is closed")
+	}
+	if k.client == nil {
+		return nil, fmt.Errorf("provider is not connected")
+	}
+	return k.client, nil
+}
+
+// directTopic returns the topic holding messages addressed to a queue directly
+func directTopic(queue string) string {
+	return queue + directTopicSuffix
+}
+
+// matchBinding reports whether a binding key matches a routing key for an exchange type
+func matchBinding(exchangeType, bindingKey, routingKey string) bool {
+	switch exchangeType {
+	case "fanout":
+		return true
+	case "direct":
+		return bindingKey == routingKey
+	default:
+		return events.MatchTopic(bindingKey, routingKey)
+	}
+}
+
+// toMessage converts a Kafka message, stripping the internal routing headers
+func toMessage(msg kafka.Message) (events.Message, time.Time) {
+	delivery := events.Message{
+		RoutingKey: string(msg.Key),
+		Headers:    make(map[string]string, len(msg.Headers)),
+		Body:       msg.Value,
+	}
+
+	var deliverAt time.Time
+	for _, h := range msg.Headers {
+		switch h.Key {
+		case headerRoutingKey:
+			delivery.RoutingKey = string(h.Value)
+		case headerDeliverAt:
+			if ms, err := strconv.ParseInt(string(h.Value), 10, 64); err == nil {
+				deliverAt = time.UnixMilli(ms)
+			}
+		default:
+			delivery.Headers[h.Key] = string(h.Value)
+		}
+	}
+
+	return delivery, deliverAt
+}
@@ -0,0 +1,68 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"rbac-service/internal/events"
+	"testing"
+)
+
+func TestRedeliverDeadLettersAfterDeliveryLimit(t *testing.T) {
+	handlerErr := errors.New("handler failed")
+	attempts := 0
+	handler := func(ctx context.Context, msg events.Message) error {
+		attempts++
+		return handlerErr
+	}
+
+	var deadLettered int
+	var cause error
+	deadLetter := func(ctx context.Context, msg events.Message, deliveries int, err error) error {
+		deadLettered = deliveries
+		cause = err
+		return nil
+	}
+
+	err := redeliver("permissions", 2, handler, deadLetter)(context.Background(), events.Message{RoutingKey: "rbac.role.created"})
+	if err != nil {
+		t.Fatalf("redeliver returned %v, want nil so the offset is committed", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("handler ran %d times, want 2", attempts)
+	}
+	if deadLettered != 2 || !errors.Is(cause, handlerErr) {
+		t.Fatalf("dead-lettered after %d deliveries with %v, want 2 and the handler error", deadLettered, cause)
+	}
+}
+
+func TestRedeliverReturnsDeadLetterFailure(t *testing.T) {
+	publishErr := errors.New("broker unavailable")
+	handler := func(ctx context.Context, msg events.Message) error {
+		return errors.New("handler failed")
+	}
+	deadLetter := func(ctx context.Context, msg events.Message, deliveries int, err error) error {
+		return publishErr
+	}
+
+	err := redeliver("permissions", 1, handler, deadLetter)(context.Background(), events.Message{})
+	if !errors.Is(err, publishErr) {
+		t.Fatalf("redeliver returned %v, want the dead-letter error", err)
+	}
+}
+
+func TestRedeliverStopsWithConsumer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := func(ctx context.Context, msg events.Message) error {
+		cancel()
+		return errors.New("handler failed")
+	}
+	deadLetter := func(ctx context.Context, msg events.Message, deliveries int, err error) error {
+		t.Fatal("message dead-lettered while the consumer stopped")
+		return nil
+	}
+
+	err := redeliver("permissions", 5, handler, deadLetter)(ctx, events.Message{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("redeliver returned %v, want context.Canceled", err)
+	}
+}
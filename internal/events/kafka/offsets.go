@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+// offsetTracker finds the offsets that are safe to commit when messages of a
+// partition complete out of order on different workers. An offset is committed
+// only once it and every earlier fetched offset have completed.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[topicPartition]*partitionOffsets
+}
+
+type partitionOffsets struct {
+	pending   []kafka.Message // fetched and not yet committable, in offset order
+	completed map[int64]bool
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		partitions: make(map[topicPartition]*partitionOffsets),
+	}
+}
+
+// track records a fetched message
+func (t *offsetTracker) track(msg kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := topicPartition{topic: msg.Topic, partition: msg.Partition}
+	p, ok := t.partitions[key]
+	if !ok {
+		p = &partitionOffsets{completed: make(map[int64]bool)}
+		t.partitions[key] = p
+	}
+
+	// Fetching from an earlier offset means the group rebalanced and the
+	// partition restarted from its committed offset
+	if n := len(p.pending); n > 0 && msg.Offset <= p.pending[n-1].Offset {
+		p.pending = nil
+		p.completed = make(map[int64]bool)
+	}
+
+	p.pending = append(p.pending, msg)
+}
+
+// complete marks a message as handled and returns the latest message whose
+// offset can now be committed, if any
+func (t *offsetTracker) complete(msg kafka.Message) (kafka.Message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[topicPartition{topic: msg.Topic, partition: msg.Partition}]
+	if !ok {
+		return kafka.Message{}, false
+	}
+
+	// A message fetched before a rebalance reset is not tracked anymore; if it
+	// is fetched again, it completes again
+	if n := len(p.pending); n == 0 || msg.Offset < p.pending[0].Offset || msg.Offset > p.pending[n-1].Offset {
+		return kafka.Message{}, false
+	}
+
+	p.completed[msg.Offset] = true
+
+	var last kafka.Message
+	advanced := false
+	for len(p.pending) > 0 && p.completed[p.pending[0].Offset] {
+		last = p.pending[0]
+		delete(p.completed, last.Offset)
+		p.pending = p.pending[1:]
+		advanced = true
+	}
+
+	return last, advanced
+}
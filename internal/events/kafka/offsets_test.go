@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func message(partition int, offset int64) kafka.Message {
+	return kafka.Message{Topic: "rbac_permissions", Partition: partition, Offset: offset}
+}
+
+func TestOffsetTrackerCommitsInOrder(t *testing.T) {
+	offsets := newOffsetTracker()
+	for offset := int64(10); offset <= 13; offset++ {
+		offsets.track(message(0, offset))
+	}
+
+	// Later offsets completing first are not committable yet
+	if _, ok := offsets.complete(message(0, 12)); ok {
+		t.Fatal("offset 12 committable before offset 10 completed")
+	}
+	if _, ok := offsets.complete(message(0, 11)); ok {
+		t.Fatal("offset 11 committable before offset 10 completed")
+	}
+
+	last, ok := offsets.complete(message(0, 10))
+	if !ok || last.Offset != 12 {
+		t.Fatalf("complete(10) = %d, %v, want 12, true", last.Offset, ok)
+	}
+
+	last, ok = offsets.complete(message(0, 13))
+	if !ok || last.Offset != 13 {
+		t.Fatalf("complete(13) = %d, %v, want 13, true", last.Offset, ok)
+	}
+}
+
+func TestOffsetTrackerSeparatesPartitions(t *testing.T) {
+	offsets := newOffsetTracker()
+	offsets.track(message(0, 5))
+	offsets.track(message(1, 7))
+
+	// A pending message of partition 0 doesn't hold back partition 1
+	last, ok := offsets.complete(message(1, 7))
+	if !ok || last.Partition != 1 || last.Offset != 7 {
+		t.Fatalf("complete(1/7) = %d/%d, %v, want 1/7, true", last.Partition, last.Offset, ok)
+	}
+	if _, ok := offsets.complete(message(2, 1)); ok {
+		t.Fatal("untracked partition committable")
+	}
+}
+
+func TestOffsetTrackerResetsOnRebalance(t *testing.T) {
+	offsets := newOffsetTracker()
+	for offset := int64(20); offset <= 22; offset++ {
+		offsets.track(message(0, offset))
+	}
+	offsets.complete(message(0, 21))
+
+	// The partition restarts from its committed offset after a rebalance; the
+	// messages fetched before are delivered again and tracked afresh
+	offsets.track(message(0, 20))
+	offsets.track(message(0, 21))
+
+	if _, ok := offsets.complete(message(0, 22)); ok {
+		t.Fatal("offset fetched before the rebalance committable")
+	}
+	if _, ok := offsets.complete(message(0, 21)); ok {
+		t.Fatal("offset 21 committable before offset 20 completed")
+	}
+
+	// Offset 22 completed before it was fetched again, which doesn't count
+	offsets.track(message(0, 22))
+
+	last, ok := offsets.complete(message(0, 20))
+	if !ok || last.Offset != 21 {
+		t.Fatalf("complete(20) = %d, %v, want 21, true", last.Offset, ok)
+	}
+
+	last, ok = offsets.complete(message(0, 22))
+	if !ok || last.Offset != 22 {
+		t.Fatalf("complete(22) = %d, %v, want 22, true", last.Offset, ok)
+	}
+}
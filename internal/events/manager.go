@@ -2,16 +2,15 @@ package events
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"rbac-service/internal/logger"
-	"rbac-service/internal/repository"
+	"rbac-service/internal/model"
 	"time"
 )
 
-const (
-	ExchangeName = "rbac_permissions"
-	QueueName    = "permissions"
-)
+// ErrEventSystemDisabled is returned when no queue provider is configured
+var ErrEventSystemDisabled = errors.New("event system disabled")
 
 // RoleAppService interface to avoid circular dependency
 type RoleAppService interface {
@@ -29,8 +28,11 @@ type GroupAppService interface {
 type EventManager struct {
 	provider                QueueProvider
 	publisher               *Publisher
-	consumer                *Consumer
+	consumers               []*Consumer
+	topology                Topology
 	healthChecker           *HealthChecker
+	outboxRelay             *OutboxRelay
+	retention               *EventRetention
 	router                  *EventRouter
 	skipInfrastructureSetup bool
 }
@@ -38,34 +40,78 @@ type EventManager struct {
 // NewEventManager creates a new event manager
 func NewEventManager(
 	provider QueueProvider,
-	auditRepo *repository.EventAuditRepository,
+	auditRepo AuditStore,
+	topology Topology,
+	envelope EnvelopeConfig,
 	skipInfrastructureSetup bool,
+	outboxPollInterval time.Duration,
+	retentionConfig RetentionConfig,
 ) (*EventManager, error) {
 	// If no provider configured, return nil manager
 	if provider == nil {
 		return nil, nil
 	}
 
+	if err := topology.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid event topology: %w", err)
+	}
+
+	if err := envelope.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := retentionConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid event retention: %w", err)
+	}
+
 	// Create publisher
-	publisher := NewPublisher(provider, auditRepo, ExchangeName)
+	publisher := NewPublisher(provider, auditRepo, topology.ExchangeName(), envelope)
+
+	// Create outbox relay
+	outboxRelay := NewOutboxRelay(publisher, auditRepo, outboxPollInterval)
+
+	// Create retention job, unless events are kept forever
+	var retention *EventRetention
+	if retentionConfig.Enabled() {
+		retention = NewEventRetention(auditRepo, retentionConfig)
+	}
 
 	// Create router
 	router := NewEventRouter()
 
-	// Create consumer
-	consumer := NewConsumer(provider, auditRepo, router, QueueName, 3)
+	// Create a consumer per queue, all dispatching through the same router
+	consumers := make([]*Consumer, len(topology.Queues))
+	for i, q := range topology.Queues {
+		consumers[i] = NewConsumer(
+			provider,
+			auditRepo,
+			router,
+			publisher,
+			topology.Name(q.Name),
+			topology.DeadLetterExchangeName(),
+			topology.queueMaxRetries(q),
+			topology.queueWorkers(q),
+		)
+	}
 
-	// Create health checker with reconnect function
+	// Create health checker with reconnect function, leaving providers that
+	// reconnect on their own to do so
 	reconnectFunc := func(ctx context.Context) error {
+		if reconnector, ok := provider.(Reconnector); ok {
+			return reconnector.Reconnect(ctx)
+		}
 		return provider.Connect(ctx)
 	}
-	healthChecker := NewHealthChecker(provider, 30*time.Second, reconnectFunc)
+	healthChecker := NewHealthChecker(provider, topology.HealthCheckInterval, reconnectFunc)
 
 	return &EventManager{
 		provider:                provider,
 		publisher:               publisher,
-		consumer:                consumer,
+		consumers:               consumers,
+		topology:                topology,
 		healthChecker:           healthChecker,
+		outboxRelay:             outboxRelay,
+		retention:               retention,
 		router:                  router,
 		skipInfrastructureSetup: skipInfrastructureSetup,
 	}, nil
@@ -98,58 +144,105 @@ func (m *EventManager) Start(ctx context.Context) error {
 	if !m.skipInfrastructureSetup {
 		logger.Info(ctx, "Setting up queue infrastructure (exchanges, queues, bindings)", nil)
 
+		exchange := m.topology.ExchangeName()
+
 		// Declare exchange
-		err = m.provider.DeclareExchange(ctx, ExchangeName, "topic")
+		err = m.provider.DeclareExchange(ctx, exchange, "topic")
 		if err != nil {
 			return fmt.Errorf("failed to declare exchange: %w", err)
 		}
 
-		// Declare queue
-		_, err = m.provider.DeclareQueue(ctx, QueueName)
+		for _, q := range m.topology.Queues {
+			queue := m.topology.Name(q.Name)
+
+			// Declare queue
+			_, err = m.provider.DeclareQueue(ctx, queue)
+			if err != nil {
+				return fmt.Errorf("failed to declare queue %s: %w", queue, err)
+			}
+
+			// Bind queue to exchange with its routing key patterns
+			for _, binding := range q.Bindings {
+				err = m.provider.BindQueue(ctx, queue, exchange, binding)
+				if err != nil {
+					return fmt.Errorf("failed to bind queue %s: %w", queue, err)
+				}
+			}
+		}
+
+		// Declare dead-letter exchange and queue for messages that can't be processed
+		err = m.provider.DeclareExchange(ctx, m.topology.DeadLetterExchangeName(), "topic")
 		if err != nil {
-			return fmt.Errorf("failed to declare queue: %w", err)
+			return fmt.Errorf("failed to declare dead-letter exchange: %w", err)
 		}
 
-		// Bind queue to exchange with routing key pattern
-		err = m.provider.BindQueue(ctx, QueueName, ExchangeName, "rbac.*.*.request")
+		_, err = m.provider.DeclareQueue(ctx, m.topology.DeadLetterQueueName())
 		if err != nil {
-			return fmt.Errorf("failed to bind queue: %w", err)
+			return fmt.Errorf("failed to declare dead-letter queue: %w", err)
+		}
+
+		err = m.provider.BindQueue(ctx, m.topology.DeadLetterQueueName(), m.topology.DeadLetterExchangeName(), "#")
+		if err != nil {
+			return fmt.Errorf("failed to bind dead-letter queue: %w", err)
 		}
 	} else {
 		logger.Info(ctx, "Skipping queue infrastructure setup (external queue manager enabled)", nil)
 	}
 
-	// Start consumer
-	err = m.consumer.Start(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to start consumer: %w", err)
+	// Start consumers
+	for _, consumer := range m.consumers {
+		err = consumer.Start(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to start consumer: %w", err)
+		}
 	}
 
 	// Start health checker
 	go m.healthChecker.Start(ctx)
 
+	// Start outbox relay
+	go m.outboxRelay.Start(ctx)
+
+	// Start retention job
+	if m.retention != nil {
+		go m.retention.Start(ctx)
+	}
+
 	logger.Info(ctx, "Event system started successfully", nil)
 	return nil
 }
 
-// Stop gracefully stops the event system
-func (m *EventManager) Stop() error {
+// Stop gracefully stops the event system, waiting until ctx expires for
+// in-flight messages to be handled
+func (m *EventManager) Stop(ctx context.Context) error {
 	if m == nil {
 		return nil
 	}
 
-	logger.Info(context.Background(), "Stopping event system", nil)
+	logger.Info(ctx, "Stopping event system", nil)
 
-	// Stop health checker
+	// Stop health checker so it doesn't reconnect while draining
 	m.healthChecker.Stop()
 
+	// Stop consuming and drain in-flight handlers
+	if err := m.provider.StopConsuming(ctx); err != nil {
+		logger.Error(ctx, "Failed to drain consumers", err)
+	}
+
+	// Stop outbox relay before the provider goes away
+	m.outboxRelay.Stop()
+
+	if m.retention != nil {
+		m.retention.Stop()
+	}
+
 	// Close provider (this will also cancel consumers)
 	err := m.provider.Close()
 	if err != nil {
 		return fmt.Errorf("failed to close provider: %w", err)
 	}
 
-	logger.Info(context.Background(), "Event system stopped", nil)
+	logger.Info(ctx, "Event system stopped", nil)
 	return nil
 }
 
@@ -160,3 +253,49 @@ func (m *EventManager) GetPublisher() *Publisher {
 	}
 	return m.publisher
 }
+
+// ReprocessEvent republishes a consumed event to the main exchange so it is
+// consumed again
+func (m *EventManager) ReprocessEvent(ctx context.Context, event *model.ConsumedEvent) error {
+	if m == nil {
+		return ErrEventSystemDisabled
+	}
+
+	err := m.provider.Publish(ctx, m.topology.ExchangeName(), event.EventType, event.Payload, nil)
+	if err != nil {
+		return fmt.Errorf("failed to reprocess event: %w", err)
+	}
+
+	logger.Info(ctx, "Consumed event republished for reprocessing", nil, "event_id", event.ID, "event_type", event.EventType)
+	return nil
+}
+
+// NotifyOutbox wakes the outbox relay. Without an event system, requeued
+// events wait for the next relay to run.
+func (m *EventManager) NotifyOutbox() {
+	if m == nil {
+		return
+	}
+	m.publisher.Notify()
+}
+
+// RetentionStats returns the rows pruned from the event audit tables since the
+// service started
+func (m *EventManager) RetentionStats() (model.EventRetentionStats, error) {
+	if m == nil {
+		return model.EventRetentionStats{}, ErrEventSystemDisabled
+	}
+	if m.retention == nil {
+		return model.EventRetentionStats{}, nil
+	}
+	return m.retention.Stats(), nil
+}
+
+// PurgeDeadLetters removes all messages from the dead-letter queue
+func (m *EventManager) PurgeDeadLetters(ctx context.Context) (int, error) {
+	if m == nil {
+		return 0, ErrEventSystemDisabled
+	}
+
+	return m.provider.PurgeQueue(ctx, m.topology.DeadLetterQueueName())
+}
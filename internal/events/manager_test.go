@@ -0,0 +1,232 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"rbac-service/internal/events"
+	"rbac-service/internal/events/memory"
+	"rbac-service/internal/model"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testThingType = "rbac.thing.create.request"
+
+// managerHarness runs an EventManager on the in-memory provider
+type managerHarness struct {
+	provider *memory.MemoryProvider
+	store    *memoryAuditStore
+	topology events.Topology
+	manager  *events.EventManager
+	stopped  bool
+}
+
+func newManagerHarness(t *testing.T, maxRetries int, handler events.EventHandler) *managerHarness {
+	t.Helper()
+
+	topology := events.DefaultTopology()
+	topology.MaxRetries = maxRetries
+
+	h := &managerHarness{
+		provider: memory.NewMemoryProvider(),
+		store:    newMemoryAuditStore(),
+		topology: topology,
+	}
+
+	manager, err := events.NewEventManager(h.provider, h.store, topology, events.EnvelopeConfig{}, false, time.Second, events.RetentionConfig{Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.manager = manager
+
+	manager.GetRouter().Register(testThingType, handler)
+
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := h.stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return h
+}
+
+// stop stops the manager unless the test already did
+func (h *managerHarness) stop() error {
+	if h.stopped {
+		return nil
+	}
+	h.stopped = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return h.manager.Stop(ctx)
+}
+
+// publish enqueues a request event and returns its ID
+func (h *managerHarness) publish(t *testing.T) string {
+	t.Helper()
+
+	event := model.Event{
+		ID:      "event-1",
+		Type:    testThingType,
+		Payload: map[string]string{"thing_id": "thing-1"},
+	}
+	if err := h.manager.GetPublisher().Enqueue(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	return event.ID
+}
+
+// countingHandler counts its calls and fails the first failFor of them
+type countingHandler struct {
+	mu      sync.Mutex
+	calls   int
+	failFor int
+}
+
+func (c *countingHandler) handle(ctx context.Context, event model.Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls++
+	if c.calls <= c.failFor {
+		return errors.New("temporary failure")
+	}
+	return nil
+}
+
+func (c *countingHandler) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+func TestEventManagerPublishesAndConsumes(t *testing.T) {
+	handler := &countingHandler{}
+	h := newManagerHarness(t, 3, handler.handle)
+
+	id := h.publish(t)
+	waitFor(t, "event to be consumed", func() bool { return h.store.consumedStatus(id) == model.StatusCompleted })
+
+	published, _ := h.store.publishedEvent(id)
+	if published.Status != model.StatusPublished {
+		t.Fatalf("published status = %q, want %q", published.Status, model.StatusPublished)
+	}
+	if calls := handler.count(); calls != 1 {
+		t.Fatalf("handler called %d times, want 1", calls)
+	}
+}
+
+func TestEventManagerRetriesAndDeadLetters(t *testing.T) {
+	handler := &countingHandler{failFor: 2}
+	h := newManagerHarness(t, 1, handler.handle)
+
+	id := h.publish(t)
+	waitFor(t, "event to be dead-lettered", func() bool { return h.store.consumedStatus(id) == model.StatusDeadLettered })
+
+	if calls := handler.count(); calls != 2 {
+		t.Fatalf("handler called %d times, want 2", calls)
+	}
+	if n := h.provider.QueueLength(h.topology.DeadLetterQueueName()); n != 1 {
+		t.Fatalf("dead-letter queue has %d messages, want 1", n)
+	}
+}
+
+func TestEventManagerReprocessesDeadLetter(t *testing.T) {
+	handler := &countingHandler{failFor: 1}
+	h := newManagerHarness(t, 0, handler.handle)
+
+	id := h.publish(t)
+	waitFor(t, "event to be dead-lettered", func() bool { return h.store.consumedStatus(id) == model.StatusDeadLettered })
+
+	// The admin API marks the event replayed before republishing it
+	event, _ := h.store.consumedEvent(id)
+	h.store.UpdateConsumedEvent(context.Background(), id, model.StatusReplayed, event.ErrorMessage, event.RetryCount)
+
+	if err := h.manager.ReprocessEvent(context.Background(), &event); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "replayed event to complete", func() bool { return h.store.consumedStatus(id) == model.StatusCompleted })
+
+	if calls := handler.count(); calls != 2 {
+		t.Fatalf("handler called %d times, want 2", calls)
+	}
+}
+
+func TestEventManagerStopDrainsInFlightEvents(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := newManagerHarness(t, 3, func(ctx context.Context, event model.Event) error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	id := h.publish(t)
+	<-started
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- h.stop()
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned while an event was being handled")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+
+	if status := h.store.consumedStatus(id); status != model.StatusCompleted {
+		t.Fatalf("status = %q, want %q", status, model.StatusCompleted)
+	}
+}
+
+func TestEventManagerMarksUnboundEventUnroutable(t *testing.T) {
+	h := newManagerHarness(t, 3, (&countingHandler{}).handle)
+
+	// Only request events are bound to a queue
+	event := model.Event{ID: "event-1", Type: "rbac.thing.created", Payload: map[string]string{"thing_id": "thing-1"}}
+	if err := h.manager.GetPublisher().Enqueue(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "event to be marked unroutable", func() bool {
+		published, _ := h.store.publishedEvent(event.ID)
+		return published.Status == model.StatusUnroutable
+	})
+}
+
+func TestPublisherPublishDirectBypassesOutbox(t *testing.T) {
+	handler := &countingHandler{}
+	h := newManagerHarness(t, 3, handler.handle)
+
+	if err := h.manager.GetPublisher().PublishDirect(context.Background(), testThingType, map[string]string{"thing_id": "thing-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "direct event to be handled", func() bool { return handler.count() == 1 })
+
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	if len(h.store.published) != 0 {
+		t.Fatalf("outbox has %d events, want none", len(h.store.published))
+	}
+}
+
+func TestPublisherPublishDirectReportsUnroutable(t *testing.T) {
+	h := newManagerHarness(t, 3, (&countingHandler{}).handle)
+
+	err := h.manager.GetPublisher().PublishDirect(context.Background(), "rbac.thing.created", map[string]string{"thing_id": "thing-1"})
+	if !errors.Is(err, events.ErrUnroutable) {
+		t.Fatalf("PublishDirect returned %v, want ErrUnroutable", err)
+	}
+}
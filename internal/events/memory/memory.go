@@ -0,0 +1,385 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"rbac-service/internal/events"
+	"rbac-service/internal/logger"
+	"sync"
+	"time"
+)
+
+// MemoryProvider implements the QueueProvider interface in-process. Exchanges,
+// queues and bindings behave like their RabbitMQ counterparts, but messages
+// are lost when the process exits. Intended for tests and single-node
+// deployments without a broker.
+type MemoryProvider struct {
+	mu        sync.RWMutex
+	connected bool
+	closed    bool
+	exchanges map[string]*exchange
+	queues    map[string]*queue
+	consumers []*consumer
+	timers    map[*time.Timer]struct{}
+}
+
+type exchange struct {
+	kind     string // "topic", "direct" or "fanout"
+	bindings []binding
+}
+
+type binding struct {
+	queue      string
+	routingKey string
+}
+
+type consumer struct {
+	queue  *queue
+	pool   *events.WorkerPool
+	stop   chan struct{}
+	done   chan struct{}
+	cancel context.CancelFunc
+}
+
+// NewMemoryProvider creates a new in-memory provider
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{
+		exchanges: make(map[string]*exchange),
+		queues:    make(map[string]*queue),
+		timers:    make(map[*time.Timer]struct{}),
+	}
+}
+
+// Connect marks the provider as connected
+func (m *MemoryProvider) Connect(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return fmt.Errorf("provider is closed")
+	}
+
+	m.connected = true
+	logger.Info(ctx, "In-memory queue provider connected", nil)
+	return nil
+}
+
+// Close stops all consumers and pending delayed messages
+func (m *MemoryProvider) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	m.connected = false
+
+	for _, c := range m.consumers {
+		c.cancel()
+	}
+	m.consumers = nil
+
+	for t := range m.timers {
+		t.Stop()
+	}
+	m.timers = make(map[*time.Timer]struct{})
+
+	return nil
+}
+
+// Publish routes a message to every queue bound to the exchange with a matching routing key.
+// The default exchange ("") routes to the queue named by the routing key. A
+// message that reaches no queue returns events.ErrUnroutable.
+func (m *MemoryProvider) Publish(ctx context.Context, exchangeName, routingKey string, body []byte, headers map[string]string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if err := m.checkConnectedUnsafe(); err != nil {
+		return err
+	}
+
+	msg := events.Message{
+		RoutingKey: routingKey,
+		Headers:    copyHeaders(headers),
+		Body:       append([]byte(nil), body...),
+	}
+
+	if exchangeName == "" {
+		q, ok := m.queues[routingKey]
+		if !ok {
+			return fmt.Errorf("%w (exchange %q, routing key %q)", events.ErrUnroutable, exchangeName, routingKey)
+		}
+		q.push(msg)
+		return nil
+	}
+
+	ex, ok := m.exchanges[exchangeName]
+	if !ok {
+		return fmt.Errorf("failed to publish message: exchange %s not found", exchangeName)
+	}
+
+	// A message is delivered to each matching queue once, even if several bindings match
+	delivered := make(map[string]bool)
+	for _, b := range ex.bindings {
+		if delivered[b.queue] || !ex.matches(b.routingKey, routingKey) {
+			continue
+		}
+		if q, ok := m.queues[b.queue]; ok {
+			q.push(msg)
+			delivered[b.queue] = true
+		}
+	}
+
+	if len(delivered) == 0 {
+		return fmt.Errorf("%w (exchange %q, routing key %q)", events.ErrUnroutable, exchangeName, routingKey)
+	}
+	return nil
+}
+
+// PublishDelayed delivers a message to a queue after the delay
+func (m *MemoryProvider) PublishDelayed(ctx context.Context, queueName string, body []byte, headers map[string]string, delay time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkConnectedUnsafe(); err != nil {
+		return err
+	}
+
+	q, ok := m.queues[queueName]
+	if !ok {
+		return fmt.Errorf("queue %s not found", queueName)
+	}
+
+	msg := events.Message{
+		RoutingKey: queueName,
+		Headers:    copyHeaders(headers),
+		Body:       append([]byte(nil), body...),
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		m.mu.Lock()
+		delete(m.timers, timer)
+		m.mu.Unlock()
+		q.push(msg)
+	})
+	m.timers[timer] = struct{}{}
+
+	return nil
+}
+
+// Consume starts delivering messages from a queue to the handler. Messages are
+// acknowledged when the handler succeeds and requeued at the front of the
+// queue when it fails.
+func (m *MemoryProvider) Consume(ctx context.Context, queueName string, handler events.MessageHandler, opts events.ConsumeOptions) error {
+	opts = opts.Normalize()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkConnectedUnsafe(); err != nil {
+		return err
+	}
+
+	q, ok := m.queues[queueName]
+	if !ok {
+		return fmt.Errorf("failed to start consuming: queue %s not found", queueName)
+	}
+
+	consumerCtx, cancel := context.WithCancel(ctx)
+	c := &consumer{
+		queue:  q,
+		pool:   events.NewWorkerPool(consumerCtx, opts, handler),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+	m.consumers = append(m.consumers, c)
+
+	go func() {
+		defer close(c.done)
+		logger.Info(consumerCtx, "Started consuming from queue", nil, "queue", queueName, "workers", fmt.Sprintf("%d", opts.Workers))
+
+		for {
+			msg, ok := q.next(consumerCtx, c.stop, opts.Prefetch)
+			if !ok {
+				logger.Info(consumerCtx, "Consumer stopped", nil, "queue", queueName)
+				return
+			}
+
+			c.pool.Submit(msg, func(err error) {
+				if err != nil {
+					logger.Error(consumerCtx, "Failed to process message", err, "queue", queueName)
+					q.requeue(msg)
+				} else {
+					q.ack()
+				}
+			})
+		}
+	}()
+
+	return nil
+}
+
+// StopConsuming stops all consumers and waits for in-flight messages to be handled
+func (m *MemoryProvider) StopConsuming(ctx context.Context) error {
+	m.mu.Lock()
+	consumers := m.consumers
+	m.consumers = nil
+	m.mu.Unlock()
+
+	for _, c := range consumers {
+		close(c.stop)
+	}
+
+	for _, c := range consumers {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for consumer to stop: %w", ctx.Err())
+		}
+
+		if err := c.pool.Drain(ctx); err != nil {
+			return fmt.Errorf("timed out waiting for in-flight messages: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// HealthCheck verifies the provider is connected
+func (m *MemoryProvider) HealthCheck(ctx context.Context) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.checkConnectedUnsafe()
+}
+
+// DeclareExchange declares an exchange of type topic, direct or fanout
+func (m *MemoryProvider) DeclareExchange(ctx context.Context, name, exchangeType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkConnectedUnsafe(); err != nil {
+		return err
+	}
+
+	switch exchangeType {
+	case "topic", "direct", "fanout":
+	default:
+		return fmt.Errorf("failed to declare exchange: unsupported type %s", exchangeType)
+	}
+
+	if ex, ok := m.exchanges[name]; ok {
+		if ex.kind != exchangeType {
+			return fmt.Errorf("failed to declare exchange: %s already declared as %s", name, ex.kind)
+		}
+		return nil
+	}
+
+	m.exchanges[name] = &exchange{kind: exchangeType}
+	logger.Info(ctx, "Exchange declared", nil, "exchange", name, "type", exchangeType)
+	return nil
+}
+
+// DeclareQueue declares a queue
+func (m *MemoryProvider) DeclareQueue(ctx context.Context, name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkConnectedUnsafe(); err != nil {
+		return "", err
+	}
+
+	if _, ok := m.queues[name]; !ok {
+		m.queues[name] = newQueue()
+		logger.Info(ctx, "Queue declared", nil, "queue", name)
+	}
+
+	return name, nil
+}
+
+// BindQueue binds a queue to an exchange
+func (m *MemoryProvider) BindQueue(ctx context.Context, queueName, exchangeName, routingKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkConnectedUnsafe(); err != nil {
+		return err
+	}
+
+	ex, ok := m.exchanges[exchangeName]
+	if !ok {
+		return fmt.Errorf("failed to bind queue: exchange %s not found", exchangeName)
+	}
+	if _, ok := m.queues[queueName]; !ok {
+		return fmt.Errorf("failed to bind queue: queue %s not found", queueName)
+	}
+
+	for _, b := range ex.bindings {
+		if b.queue == queueName && b.routingKey == routingKey {
+			return nil
+		}
+	}
+
+	ex.bindings = append(ex.bindings, binding{queue: queueName, routingKey: routingKey})
+	logger.Info(ctx, "Queue bound to exchange", nil, "queue", queueName, "exchange", exchangeName, "routing_key", routingKey)
+	return nil
+}
+
+// PurgeQueue removes all ready messages from a queue
+func (m *MemoryProvider) PurgeQueue(ctx context.Context, queueName string) (int, error) {
+	m.mu.RLock()
+	q, ok := m.queues[queueName]
+	m.mu.RUnlock()
+
+	if !ok {
+		return 0, fmt.Errorf("failed to purge queue: queue %s not found", queueName)
+	}
+
+	return q.purge(), nil
+}
+
+// QueueLength returns the number of ready messages in a queue
+func (m *MemoryProvider) QueueLength(queueName string) int {
+	m.mu.RLock()
+	q, ok := m.queues[queueName]
+	m.mu.RUnlock()
+
+	if !ok {
+		return 0
+	}
+	return q.len()
+}
+
+func (m *MemoryProvider) checkConnectedUnsafe() error {
+	if m.closed {
+		return fmt.Errorf("provider is closed")
+	}
+	if !m.connected {
+		return fmt.Errorf("provider is not connected")
+	}
+	return nil
+}
+
+// matches reports whether a binding key matches a routing key for this exchange type
+func (e *exchange) matches(bindingKey, routingKey string) bool {
+	switch e.kind {
+	case "fanout":
+		return true
+	case "direct":
+		return bindingKey == routingKey
+	default:
+		return events.MatchTopic(bindingKey, routingKey)
+	}
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+
+	copied := make(map[string]string, len(headers))
+	for k, v := range headers {
+		copied[k] = v
+	}
+	return copied
+}
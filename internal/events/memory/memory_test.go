@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"rbac-service/internal/events"
+	"testing"
+)
+
+func TestPublishRoutesOrFailsUnroutable(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryProvider()
+	t.Cleanup(func() { m.Close() })
+
+	if err := m.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DeclareExchange(ctx, "events", "topic"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.DeclareQueue(ctx, "requests"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.BindQueue(ctx, "requests", "events", "rbac.*.request"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		exchange   string
+		routingKey string
+		unroutable bool
+	}{
+		{name: "matching binding", exchange: "events", routingKey: "rbac.thing.request"},
+		{name: "no matching binding", exchange: "events", routingKey: "rbac.thing.created", unroutable: true},
+		{name: "default exchange to existing queue", exchange: "", routingKey: "requests"},
+		{name: "default exchange to missing queue", exchange: "", routingKey: "missing", unroutable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := m.QueueLength("requests")
+			err := m.Publish(ctx, tt.exchange, tt.routingKey, []byte(`{}`), nil)
+
+			if tt.unroutable {
+				if !errors.Is(err, events.ErrUnroutable) {
+					t.Fatalf("err = %v, want ErrUnroutable", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := m.QueueLength("requests"); n != before+1 {
+				t.Fatalf("queue length = %d, want %d", n, before+1)
+			}
+		})
+	}
+}
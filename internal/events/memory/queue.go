@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"rbac-service/internal/events"
+	"sync"
+)
+
+// queue is a FIFO of ready messages with a count of unacknowledged deliveries
+type queue struct {
+	mu       sync.Mutex
+	messages []events.Message
+	unacked  int
+	signal   chan struct{}
+}
+
+func newQueue() *queue {
+	return &queue{
+		signal: make(chan struct{}, 1),
+	}
+}
+
+// push appends a message
+func (q *queue) push(msg events.Message) {
+	q.mu.Lock()
+	q.messages = append(q.messages, msg)
+	q.mu.Unlock()
+	q.notify()
+}
+
+// requeue returns an unacknowledged message to the front of the queue
+func (q *queue) requeue(msg events.Message) {
+	q.mu.Lock()
+	q.messages = append([]events.Message{msg}, q.messages...)
+	q.unacked--
+	q.mu.Unlock()
+	q.notify()
+}
+
+// ack settles an unacknowledged message
+func (q *queue) ack() {
+	q.mu.Lock()
+	q.unacked--
+	q.mu.Unlock()
+	q.notify()
+}
+
+// next blocks until a message is ready and fewer than prefetch deliveries are
+// unacknowledged, then delivers it. It returns false when stop is closed or
+// ctx is done.
+func (q *queue) next(ctx context.Context, stop <-chan struct{}, prefetch int) (events.Message, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.messages) > 0 && q.unacked < prefetch {
+			msg := q.messages[0]
+			q.messages = q.messages[1:]
+			q.unacked++
+			q.mu.Unlock()
+			return msg, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.signal:
+		case <-stop:
+			return events.Message{}, false
+		case <-ctx.Done():
+			return events.Message{}, false
+		}
+	}
+}
+
+// purge drops all ready messages and returns how many were dropped
+func (q *queue) purge() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := len(q.messages)
+	q.messages = nil
+	return n
+}
+
+func (q *queue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.messages)
+}
+
+func (q *queue) notify() {
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,609 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rbac-service/internal/events"
+	"rbac-service/internal/logger"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// headerDeliverAt holds the time a delayed message becomes deliverable, in Unix milliseconds
+const headerDeliverAt = "x-deliver-at"
+
+// Stream and subject prefixes. Exchange and queue names are reduced to a single
+// subject token so that e.g. "rbac_permissions" and "rbac_permissions.dlx" get
+// streams with non-overlapping subjects.
+const (
+	exchangePrefix = "exchange"
+	queuePrefix    = "queue"
+)
+
+// NATSProvider implements the QueueProvider interface for NATS JetStream. Each
+// exchange is a stream with subjects "exchange.<name>.<routing key>" and each
+// queue is a durable pull consumer named after the queue on every stream it is
+// bound to, plus a stream of its own for direct and delayed messages. Topic
+// binding keys map to subject wildcards ("*" to "*", a trailing "#" to ">").
+type NATSProvider struct {
+	url        string
+	maxDeliver int
+	mu         sync.RWMutex
+	closed     bool
+	conn       *nats.Conn
+	js         jetstream.JetStream
+	consumers  []*consumer
+}
+
+// consumer tracks a running consumer so it can be drained
+type consumer struct {
+	subscriptions []jetstream.ConsumeContext
+	pool          *events.WorkerPool
+	submitMu      sync.Mutex // WorkerPool.Submit must not be called concurrently
+	cancel        context.CancelFunc
+}
+
+// NewNATSProvider creates a new NATS JetStream provider. maxDeliver limits how
+// often a message is delivered before it is dropped.
+func NewNATSProvider(url string, maxDeliver int) (*NATSProvider, error) {
+	if url == "" {
+		return nil, fmt.Errorf("NATS URL is required")
+	}
+
+	return &NATSProvider{
+		url:        url,
+		maxDeliver: maxDeliver,
+	}, nil
+}
+
+// Connect connects to NATS and checks that JetStream is enabled
+func (n *NATSProvider) Connect(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return fmt.Errorf("provider is closed")
+	}
+
+	if n.conn != nil {
+		n.conn.Close()
+	}
+
+	conn, err := nats.Connect(n.url, nats.MaxReconnects(-1))
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	if _, err := js.AccountInfo(ctx); err != nil {
+		conn.Close()
+		return fmt.Errorf("JetStream is not available: %w", err)
+	}
+
+	n.conn = conn
+	n.js = js
+
+	logger.Info(ctx, "NATS provider connected", nil, "url", conn.ConnectedUrlRedacted())
+	return nil
+}
+
+// Reconnect connects again for the health checker if the connection is gone.
+// The NATS client reconnects a connection that is still open on its own, and
+// replacing it would leave the consumers subscribed on the old one.
+func (n *NATSProvider) Reconnect(ctx context.Context) error {
+	n.mu.RLock()
+	conn := n.conn
+	n.mu.RUnlock()
+
+	if conn != nil && !conn.IsClosed() {
+		logger.Info(ctx, "NATS client is reconnecting on its own", nil)
+		return nil
+	}
+	return n.Connect(ctx)
+}
+
+// Close stops all consumers and closes the connection
+func (n *NATSProvider) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.closed = true
+
+	for _, c := range n.consumers {
+		for _, sub := range c.subscriptions {
+			sub.Stop()
+		}
+		c.cancel()
+	}
+	n.consumers = nil
+
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+	return nil
+}
+
+// Publish publishes a message to an exchange's stream.
+// The default exchange ("") publishes to the stream of the queue named by the routing key.
+func (n *NATSProvider) Publish(ctx context.Context, exchange, routingKey string, body []byte, headers map[string]string) error {
+	subject := queueSubject(routingKey)
+	if exchange != "" {
+		subject = exchangeSubject(exchange) + "." + routingKey
+	}
+
+	return n.publish(ctx, subject, body, headers)
+}
+
+// PublishDelayed publishes a message to a queue's stream with the time it
+// becomes deliverable. The consumer naks it with the remaining delay; the
+// queue's durable consumer allows one delivery more than maxDeliver for it.
+func (n *NATSProvider) PublishDelayed(ctx context.Context, queue string, body []byte, headers map[string]string, delay time.Duration) error {
+	withDeadline := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		withDeadline[k] = v
+	}
+	withDeadline[headerDeliverAt] = strconv.FormatInt(time.Now().Add(delay).UnixMilli(), 10)
+
+	return n.publish(ctx, queueSubject(queue), body, withDeadline)
+}
+
+func (n *NATSProvider) publish(ctx context.Context, subject string, body []byte, headers map[string]string) error {
+	js, err := n.getJetStream()
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = body
+	for k, v := range headers {
+		msg.Header.Set(k, v)
+	}
+
+	if _, err := js.PublishMsg(ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	return nil
+}
+
+// Consume starts consuming messages from a queue. Every durable consumer named
+// after the queue is consumed, so queues set up by an external manager work as
+// long as they follow the same naming.
+func (n *NATSProvider) Consume(ctx context.Context, queue string, handler events.MessageHandler, opts events.ConsumeOptions) error {
+	opts = opts.Normalize()
+
+	durables, err := n.findConsumers(ctx, queue)
+	if err != nil {
+		return fmt.Errorf("failed to start consuming: %w", err)
+	}
+	if len(durables) == 0 {
+		return fmt.Errorf("failed to start consuming: queue %s not found", queue)
+	}
+
+	consumerCtx, cancel := context.WithCancel(ctx)
+	c := &consumer{
+		pool:   events.NewWorkerPool(consumerCtx, opts, handler),
+		cancel: cancel,
+	}
+
+	for _, durable := range durables {
+		sub, err := durable.Consume(func(msg jetstream.Msg) {
+			c.deliver(consumerCtx, queue, msg, n.maxDeliver)
+		}, jetstream.PullMaxMessages(opts.Prefetch), jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+			logger.Warn(consumerCtx, "Consumer error", nil, "queue", queue, "error", err.Error())
+		}))
+		if err != nil {
+			for _, started := range c.subscriptions {
+				started.Stop()
+			}
+			cancel()
+			return fmt.Errorf("failed to start consuming: %w", err)
+		}
+		c.subscriptions = append(c.subscriptions, sub)
+	}
+
+	n.mu.Lock()
+	n.consumers = append(n.consumers, c)
+	n.mu.Unlock()
+
+	logger.Info(consumerCtx, "Started consuming from queue", nil, "queue", queue, "workers", fmt.Sprintf("%d", opts.Workers))
+	return nil
+}
+
+// deliver hands a message to the worker pool, acking it when the handler
+// succeeds and naking it with backoff when it fails
+func (c *consumer) deliver(ctx context.Context, queue string, msg jetstream.Msg, maxDeliver int) {
+	delivery, deliverAt := toMessage(msg, queue)
+
+	if wait := time.Until(deliverAt); wait > 0 {
+		// Not due yet; have the server redeliver it when it is
+		if err := msg.NakWithDelay(wait); err != nil {
+			logger.Warn(ctx, "Failed to delay message", nil, "queue", queue, "error", err.Error())
+		}
+		return
+	}
+
+	c.submitMu.Lock()
+	defer c.submitMu.Unlock()
+
+	c.pool.Submit(delivery, func(err error) {
+		if err == nil {
+			msg.Ack()
+			return
+		}
+
+		logger.Error(ctx, "Failed to process message", err, "queue", queue)
+
+		delivered := 1
+		if meta, metaErr := msg.Metadata(); metaErr == nil {
+			delivered = handlerDeliveries(int(meta.NumDelivered), !deliverAt.IsZero())
+		}
+		if maxDeliver > 0 && delivered >= maxDeliver {
+			logger.Warn(ctx, "Message reached redelivery limit, dropping", nil, "queue", queue, "deliveries", fmt.Sprintf("%d", delivered))
+			msg.Term()
+			return
+		}
+
+		// Backoff: 1s, 2s, 4s, ... by delivery count
+		msg.NakWithDelay(time.Duration(1<<uint(delivered-1)) * time.Second)
+	})
+}
+
+// handlerDeliveries returns how often a message was delivered to the handler.
+// JetStream counts the delivery a delayed message was naked on until it was
+// due as well; it is left out, so delaying a message doesn't use up one of its
+// attempts. A delay nak that has to be repeated, because it failed or the
+// consumer restarted before the message was due, still uses one.
+func handlerDeliveries(numDelivered int, delayed bool) int {
+	if delayed && numDelivered > 1 {
+		return numDelivered - 1
+	}
+	return numDelivered
+}
+
+// StopConsuming stops all consumers and waits for in-flight messages to be handled
+func (n *NATSProvider) StopConsuming(ctx context.Context) error {
+	n.mu.Lock()
+	consumers := n.consumers
+	n.consumers = nil
+	n.mu.Unlock()
+
+	for _, c := range consumers {
+		for _, sub := range c.subscriptions {
+			sub.Stop()
+		}
+	}
+
+	for _, c := range consumers {
+		for _, sub := range c.subscriptions {
+			select {
+			case <-sub.Closed():
+			case <-ctx.Done():
+				return fmt.Errorf("timed out waiting for consumer to stop: %w", ctx.Err())
+			}
+		}
+
+		if err := c.pool.Drain(ctx); err != nil {
+			return fmt.Errorf("timed out waiting for in-flight messages: %w", err)
+		}
+		c.cancel()
+	}
+
+	return nil
+}
+
+// HealthCheck verifies the connection is up and JetStream responds
+func (n *NATSProvider) HealthCheck(ctx context.Context) error {
+	n.mu.RLock()
+	conn := n.conn
+	n.mu.RUnlock()
+
+	if conn == nil || !conn.IsConnected() {
+		return fmt.Errorf("not connected to NATS")
+	}
+
+	js, err := n.getJetStream()
+	if err != nil {
+		return err
+	}
+
+	if _, err := js.AccountInfo(ctx); err != nil {
+		return fmt.Errorf("JetStream is not available: %w", err)
+	}
+	return nil
+}
+
+// DeclareExchange declares the stream backing an exchange. Messages are kept
+// until every consumer bound at publish time has acknowledged them.
+func (n *NATSProvider) DeclareExchange(ctx context.Context, exchange, exchangeType string) error {
+	switch exchangeType {
+	case "topic", "direct", "fanout":
+	default:
+		return fmt.Errorf("failed to declare exchange: unsupported type %s", exchangeType)
+	}
+
+	err := n.declareStream(ctx, exchangeStream(exchange), exchangeSubject(exchange)+".>")
+	if err != nil {
+		return fmt.Errorf("failed to declare exchange: %w", err)
+	}
+
+	logger.Info(ctx, "Exchange declared", nil, "exchange", exchange, "type", exchangeType)
+	return nil
+}
+
+// DeclareQueue declares the queue's stream and its durable consumer for direct and delayed messages
+func (n *NATSProvider) DeclareQueue(ctx context.Context, queue string) (string, error) {
+	if err := n.declareStream(ctx, queueStream(queue), queueSubject(queue)); err != nil {
+		return "", fmt.Errorf("failed to declare queue: %w", err)
+	}
+
+	// Delayed messages are naked once until due, which JetStream counts as a delivery
+	maxDeliver := n.maxDeliver
+	if maxDeliver > 0 {
+		maxDeliver++
+	}
+	if err := n.declareConsumer(ctx, queueStream(queue), queue, queueSubject(queue), maxDeliver); err != nil {
+		return "", fmt.Errorf("failed to declare queue: %w", err)
+	}
+
+	logger.Info(ctx, "Queue declared", nil, "queue", queue)
+	return queue, nil
+}
+
+// BindQueue adds the binding key's subject to the queue's durable consumer on the exchange's stream
+func (n *NATSProvider) BindQueue(ctx context.Context, queue, exchange, routingKey string) error {
+	filter, err := bindingSubject(exchange, routingKey)
+	if err != nil {
+		return fmt.Errorf("failed to bind queue: %w", err)
+	}
+
+	if err := n.declareConsumer(ctx, exchangeStream(exchange), queue, filter, n.maxDeliver); err != nil {
+		return fmt.Errorf("failed to bind queue: %w", err)
+	}
+
+	logger.Info(ctx, "Queue bound to exchange", nil, "queue", queue, "exchange", exchange, "routing_key", routingKey)
+	return nil
+}
+
+// PurgeQueue drops all pending messages of a queue. The queue's own stream is
+// purged; consumers on exchange streams are recreated to start at new messages.
+func (n *NATSProvider) PurgeQueue(ctx context.Context, queue string) (int, error) {
+	js, err := n.getJetStream()
+	if err != nil {
+		return 0, err
+	}
+
+	durables, err := n.findConsumers(ctx, queue)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge queue: %w", err)
+	}
+
+	count := 0
+	for _, durable := range durables {
+		info, err := durable.Info(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge queue: %w", err)
+		}
+		count += int(info.NumPending) + info.NumAckPending
+
+		if info.Stream == queueStream(queue) {
+			stream, err := js.Stream(ctx, info.Stream)
+			if err != nil {
+				return 0, fmt.Errorf("failed to purge queue: %w", err)
+			}
+			if err := stream.Purge(ctx); err != nil {
+				return 0, fmt.Errorf("failed to purge queue: %w", err)
+			}
+			continue
+		}
+
+		if err := js.DeleteConsumer(ctx, info.Stream, info.Name); err != nil {
+			return 0, fmt.Errorf("failed to purge queue: %w", err)
+		}
+		config := info.Config
+		config.DeliverPolicy = jetstream.DeliverNewPolicy
+		if _, err := js.CreateOrUpdateConsumer(ctx, info.Stream, config); err != nil {
+			return 0, fmt.Errorf("failed to purge queue: %w", err)
+		}
+	}
+
+	logger.Info(ctx, "Queue purged", nil, "queue", queue, "messages", fmt.Sprintf("%d", count))
+	return count, nil
+}
+
+// declareStream creates a stream or updates its subjects
+func (n *NATSProvider) declareStream(ctx context.Context, name, subject string) error {
+	js, err := n.getJetStream()
+	if err != nil {
+		return err
+	}
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:      name,
+		Subjects:  []string{subject},
+		Retention: jetstream.InterestPolicy,
+		Storage:   jetstream.FileStorage,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to declare stream %s: %w", name, err)
+	}
+	return nil
+}
+
+// declareConsumer creates the queue's durable consumer on a stream, or adds the
+// filter subject to the existing one
+func (n *NATSProvider) declareConsumer(ctx context.Context, stream, queue, filter string, maxDeliver int) error {
+	js, err := n.getJetStream()
+	if err != nil {
+		return err
+	}
+
+	config := jetstream.ConsumerConfig{
+		Durable:        durableName(queue),
+		AckPolicy:      jetstream.AckExplicitPolicy,
+		MaxDeliver:     maxDeliver,
+		FilterSubjects: []string{filter},
+	}
+
+	existing, err := js.Consumer(ctx, stream, durableName(queue))
+	switch {
+	case err == nil:
+		config = existing.CachedInfo().Config
+		for _, subject := range config.FilterSubjects {
+			if subject == filter {
+				return nil
+			}
+		}
+		if config.FilterSubject != "" {
+			config.FilterSubjects = []string{config.FilterSubject}
+			config.FilterSubject = ""
+		}
+		config.FilterSubjects = append(config.FilterSubjects, filter)
+	case !errors.Is(err, jetstream.ErrConsumerNotFound):
+		return fmt.Errorf("failed to look up consumer: %w", err)
+	}
+
+	if _, err := js.CreateOrUpdateConsumer(ctx, stream, config); err != nil {
+		return fmt.Errorf("failed to declare consumer: %w", err)
+	}
+	return nil
+}
+
+// findConsumers returns the queue's durable consumers on its own stream and on exchange streams
+func (n *NATSProvider) findConsumers(ctx context.Context, queue string) ([]jetstream.Consumer, error) {
+	js, err := n.getJetStream()
+	if err != nil {
+		return nil, err
+	}
+
+	var consumers []jetstream.Consumer
+
+	names := js.StreamNames(ctx)
+	for stream := range names.Name() {
+		if stream != queueStream(queue) && !strings.HasPrefix(stream, exchangePrefix+"_") {
+			continue
+		}
+
+		c, err := js.Consumer(ctx, stream, durableName(queue))
+		if errors.Is(err, jetstream.ErrConsumerNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up consumer on stream %s: %w", stream, err)
+		}
+		consumers = append(consumers, c)
+	}
+	if err := names.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list streams: %w", err)
+	}
+
+	return consumers, nil
+}
+
+func (n *NATSProvider) getJetStream() (jetstream.JetStream, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.closed {
+		return nil, fmt.Errorf("provider is closed")
+	}
+	if n.js == nil {
+		return nil, fmt.Errorf("provider is not connected")
+	}
+	return n.js, nil
+}
+
+// bindingSubject converts a topic binding key to a subject filter on the exchange's stream
+func bindingSubject(exchange, bindingKey string) (string, error) {
+	words := strings.Split(bindingKey, ".")
+	for i, word := range words {
+		if word == "#" {
+			if i != len(words)-1 {
+				return "", fmt.Errorf("binding key %s: \"#\" is only supported as the last word", bindingKey)
+			}
+			words[i] = ">"
+		}
+	}
+	return exchangeSubject(exchange) + "." + strings.Join(words, "."), nil
+}
+
+// toMessage converts a JetStream message, stripping the delivery time header
+func toMessage(msg jetstream.Msg, queue string) (events.Message, time.Time) {
+	delivery := events.Message{
+		RoutingKey: queue,
+		Headers:    make(map[string]string, len(msg.Headers())),
+		Body:       msg.Data(),
+	}
+
+	// Strip "exchange.<name>." from exchange subjects
+	if parts := strings.SplitN(msg.Subject(), ".", 3); len(parts) == 3 && parts[0] == exchangePrefix {
+		delivery.RoutingKey = parts[2]
+	}
+
+	var deliverAt time.Time
+	for k, values := range msg.Headers() {
+		if len(values) == 0 {
+			continue
+		}
+		if k == headerDeliverAt {
+			if ms, err := strconv.ParseInt(values[0], 10, 64); err == nil {
+				deliverAt = time.UnixMilli(ms)
+			}
+			continue
+		}
+		delivery.Headers[k] = values[0]
+	}
+
+	return delivery, deliverAt
+}
+
+func exchangeStream(exchange string) string {
+	return exchangePrefix + "_" + token(exchange)
+}
+
+func exchangeSubject(exchange string) string {
+	return exchangePrefix + "." + token(exchange)
+}
+
+func queueStream(queue string) string {
+	return queuePrefix + "_" + token(queue)
+}
+
+func queueSubject(queue string) string {
+	return queuePrefix + "." + token(queue)
+}
+
+func durableName(queue string) string {
+	return token(queue)
+}
+
+// token makes a name usable as a single subject token and as a stream or
+// consumer name. Characters that are not allowed are escaped as "_" and their
+// hex code, and "_" itself as "__", so different names never share a token:
+// "a.b" becomes "a_2eb" and "a_b" becomes "a__b".
+func token(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch r {
+		case '_':
+			b.WriteString("__")
+		case '.', '*', '>', ' ', '\t', '/', '\\':
+			fmt.Fprintf(&b, "_%02x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
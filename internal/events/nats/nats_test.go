@@ -0,0 +1,216 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"rbac-service/internal/events"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestBindingSubject(t *testing.T) {
+	tests := []struct {
+		bindingKey string
+		want       string
+		wantErr    bool
+	}{
+		{bindingKey: "rbac.role.created", want: "exchange.rbac__permissions.rbac.role.created"},
+		{bindingKey: "rbac.*.created", want: "exchange.rbac__permissions.rbac.*.created"},
+		{bindingKey: "rbac.#", want: "exchange.rbac__permissions.rbac.>"},
+		{bindingKey: "#", want: "exchange.rbac__permissions.>"},
+		{bindingKey: "rbac.#.created", wantErr: true},
+		{bindingKey: "#.created", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.bindingKey, func(t *testing.T) {
+			got, err := bindingSubject("rbac_permissions", tt.bindingKey)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("bindingSubject(%q) = %q, want an error", tt.bindingKey, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("bindingSubject(%q) failed: %v", tt.bindingKey, err)
+			}
+			if got != tt.want {
+				t.Fatalf("bindingSubject(%q) = %q, want %q", tt.bindingKey, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenKeepsNamesApart(t *testing.T) {
+	names := []string{"a.b", "a_b", "a__b", "a_2eb", "a*b", "a>b", "a b", "a/b", "rbac_permissions", "rbac_permissions.dlx"}
+
+	seen := make(map[string]string, len(names))
+	for _, name := range names {
+		tok := token(name)
+		if other, ok := seen[tok]; ok {
+			t.Fatalf("%q and %q both map to %q", other, name, tok)
+		}
+		seen[tok] = name
+
+		for _, r := range tok {
+			switch r {
+			case '.', '*', '>', ' ', '\t', '/', '\\':
+				t.Fatalf("token(%q) = %q contains %q", name, tok, r)
+			}
+		}
+	}
+}
+
+// fakeMsg records how a JetStream message was settled
+type fakeMsg struct {
+	subject   string
+	headers   nats.Header
+	delivered uint64
+
+	mu        sync.Mutex
+	acked     bool
+	termed    bool
+	nakDelays []time.Duration
+	settled   chan struct{}
+}
+
+func newFakeMsg(subject string, delivered uint64, headers nats.Header) *fakeMsg {
+	if headers == nil {
+		headers = nats.Header{}
+	}
+	return &fakeMsg{subject: subject, headers: headers, delivered: delivered, settled: make(chan struct{}, 1)}
+}
+
+func (m *fakeMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{NumDelivered: m.delivered}, nil
+}
+func (m *fakeMsg) Data() []byte         { return []byte("{}") }
+func (m *fakeMsg) Headers() nats.Header { return m.headers }
+func (m *fakeMsg) Subject() string      { return m.subject }
+func (m *fakeMsg) Reply() string        { return "" }
+func (m *fakeMsg) Ack() error {
+	m.settle(func() { m.acked = true })
+	return nil
+}
+func (m *fakeMsg) DoubleAck(context.Context) error { return m.Ack() }
+func (m *fakeMsg) Nak() error                      { return m.NakWithDelay(0) }
+func (m *fakeMsg) NakWithDelay(delay time.Duration) error {
+	m.settle(func() { m.nakDelays = append(m.nakDelays, delay) })
+	return nil
+}
+func (m *fakeMsg) InProgress() error { return nil }
+func (m *fakeMsg) Term() error {
+	m.settle(func() { m.termed = true })
+	return nil
+}
+func (m *fakeMsg) TermWithReason(string) error { return m.Term() }
+
+func (m *fakeMsg) settle(record func()) {
+	m.mu.Lock()
+	record()
+	m.mu.Unlock()
+	m.settled <- struct{}{}
+}
+
+func (m *fakeMsg) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-m.settled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the message to be settled")
+	}
+}
+
+func newTestConsumer(t *testing.T, handler events.MessageHandler) (*consumer, context.Context) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &consumer{pool: events.NewWorkerPool(ctx, events.ConsumeOptions{}, handler), cancel: cancel}, ctx
+}
+
+func deliverAt(at time.Time) nats.Header {
+	return nats.Header{headerDeliverAt: []string{strconv.FormatInt(at.UnixMilli(), 10)}}
+}
+
+func TestDeliverDelaysMessageUntilDue(t *testing.T) {
+	c, ctx := newTestConsumer(t, func(ctx context.Context, msg events.Message) error {
+		t.Error("handler called before the message was due")
+		return nil
+	})
+
+	msg := newFakeMsg("queue.permissions", 1, deliverAt(time.Now().Add(time.Minute)))
+	c.deliver(ctx, "permissions", msg, 5)
+	msg.wait(t)
+
+	if len(msg.nakDelays) != 1 || msg.nakDelays[0] <= 50*time.Second || msg.nakDelays[0] > time.Minute {
+		t.Fatalf("nak delays = %v, want one of about a minute", msg.nakDelays)
+	}
+}
+
+func TestDeliverDoesNotCountDelayAgainstLimit(t *testing.T) {
+	c, ctx := newTestConsumer(t, func(ctx context.Context, msg events.Message) error {
+		return errors.New("handler failed")
+	})
+
+	// The second delivery is the first attempt at a delayed message, so with
+	// a limit of 2 it is retried rather than dropped
+	msg := newFakeMsg("queue.permissions", 2, deliverAt(time.Now().Add(-time.Second)))
+	c.deliver(ctx, "permissions", msg, 2)
+	msg.wait(t)
+
+	if msg.termed || len(msg.nakDelays) != 1 || msg.nakDelays[0] != time.Second {
+		t.Fatalf("termed = %v, nak delays = %v, want a single 1s nak", msg.termed, msg.nakDelays)
+	}
+
+	msg = newFakeMsg("queue.permissions", 3, deliverAt(time.Now().Add(-time.Second)))
+	c.deliver(ctx, "permissions", msg, 2)
+	msg.wait(t)
+
+	if !msg.termed {
+		t.Fatalf("nak delays = %v, want the message dropped at the limit", msg.nakDelays)
+	}
+}
+
+func TestDeliverAcksHandledMessage(t *testing.T) {
+	var got events.Message
+	c, ctx := newTestConsumer(t, func(ctx context.Context, msg events.Message) error {
+		got = msg
+		return nil
+	})
+
+	msg := newFakeMsg("exchange.rbac__permissions.rbac.role.created", 1, nil)
+	c.deliver(ctx, "permissions", msg, 5)
+	msg.wait(t)
+
+	if !msg.acked {
+		t.Fatal("handled message not acked")
+	}
+	if got.RoutingKey != "rbac.role.created" {
+		t.Fatalf("routing key = %q, want rbac.role.created", got.RoutingKey)
+	}
+}
+
+func TestDeliverDropsMessageAtLimit(t *testing.T) {
+	c, ctx := newTestConsumer(t, func(ctx context.Context, msg events.Message) error {
+		return errors.New("handler failed")
+	})
+
+	msg := newFakeMsg("exchange.rbac__permissions.rbac.role.created", 2, nil)
+	c.deliver(ctx, "permissions", msg, 5)
+	msg.wait(t)
+	if msg.termed || len(msg.nakDelays) != 1 || msg.nakDelays[0] != 2*time.Second {
+		t.Fatalf("termed = %v, nak delays = %v, want a single 2s nak", msg.termed, msg.nakDelays)
+	}
+
+	msg = newFakeMsg("exchange.rbac__permissions.rbac.role.created", 5, nil)
+	c.deliver(ctx, "permissions", msg, 5)
+	msg.wait(t)
+	if !msg.termed {
+		t.Fatalf("nak delays = %v, want the message dropped at the limit", msg.nakDelays)
+	}
+}
@@ -0,0 +1,161 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/model"
+	"rbac-service/internal/reqctx"
+	"time"
+)
+
+const (
+	outboxBatchSize  = 100
+	outboxLease      = 30 * time.Second
+	outboxMaxBackoff = 5 * time.Minute
+)
+
+// OutboxRelay publishes pending events from the outbox. Events that fail to
+// publish stay pending and are retried with exponential backoff, including
+// after a restart.
+type OutboxRelay struct {
+	publisher    *Publisher
+	auditRepo    AuditStore
+	pollInterval time.Duration
+	stopChan     chan struct{}
+	doneChan     chan struct{}
+}
+
+// NewOutboxRelay creates a new outbox relay
+func NewOutboxRelay(publisher *Publisher, auditRepo AuditStore, pollInterval time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		publisher:    publisher,
+		auditRepo:    auditRepo,
+		pollInterval: pollInterval,
+		stopChan:     make(chan struct{}),
+		doneChan:     make(chan struct{}),
+	}
+}
+
+// Start runs the relay until Stop is called
+func (r *OutboxRelay) Start(ctx context.Context) {
+	defer close(r.doneChan)
+
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	logger.Info(ctx, "Outbox relay started", nil, "poll_interval", r.pollInterval.String())
+
+	for {
+		r.drain(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-r.publisher.wake:
+		case <-r.stopChan:
+			logger.Info(ctx, "Outbox relay stopped", nil)
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Stop stops the relay and waits for the current batch to finish
+func (r *OutboxRelay) Stop() {
+	close(r.stopChan)
+	<-r.doneChan
+}
+
+// drain publishes due events until the outbox has no more
+func (r *OutboxRelay) drain(ctx context.Context) {
+	for {
+		events, err := r.auditRepo.ClaimPendingEvents(ctx, outboxBatchSize, outboxLease)
+		if err != nil {
+			logger.Error(ctx, "Failed to claim pending events", err)
+			return
+		}
+
+		for _, event := range events {
+			r.relay(ctx, event)
+		}
+
+		if len(events) < outboxBatchSize {
+			return
+		}
+
+		select {
+		case <-r.stopChan:
+			return
+		default:
+		}
+	}
+}
+
+func (r *OutboxRelay) relay(ctx context.Context, pending model.PublishedEvent) {
+	event := model.Event{
+		ID:        pending.ID,
+		Type:      pending.EventType,
+		Payload:   json.RawMessage(pending.Payload),
+		Timestamp: pending.CreatedAt,
+	}
+	if pending.CorrelationID != nil {
+		event.CorrelationID = *pending.CorrelationID
+		ctx = reqctx.WithCorrelationID(ctx, event.CorrelationID)
+	}
+	if pending.CausationID != nil {
+		event.CausationID = *pending.CausationID
+		ctx = reqctx.WithCausationID(ctx, event.CausationID)
+	}
+
+	err := r.publisher.send(ctx, event)
+	if errors.Is(err, ErrUnroutable) {
+		// Retrying won't help until a queue is bound for the event type
+		logger.Warn(ctx, "Event is unroutable, no queue is bound for it", nil, "event_id", event.ID, "event_type", event.Type)
+
+		errMsg := err.Error()
+		if err := r.auditRepo.UpdatePublishedEvent(ctx, event.ID, model.StatusUnroutable, &errMsg); err != nil {
+			logger.Error(ctx, "Failed to mark event as unroutable", err, "event_id", event.ID)
+		}
+		return
+	}
+	if err != nil {
+		attempts := pending.Attempts + 1
+		backoff := outboxBackoff(attempts)
+
+		logger.Warn(ctx, "Failed to publish event, will retry", nil,
+			"event_id", event.ID,
+			"event_type", event.Type,
+			"attempt", fmt.Sprintf("%d", attempts),
+			"retry_in", backoff.String(),
+			"error", err.Error(),
+		)
+
+		if err := r.auditRepo.ReschedulePublishedEvent(ctx, event.ID, err.Error(), attempts, time.Now().Add(backoff)); err != nil {
+			logger.Error(ctx, "Failed to reschedule event", err, "event_id", event.ID)
+		}
+		return
+	}
+
+	if err := r.auditRepo.UpdatePublishedEvent(ctx, event.ID, model.StatusPublished, nil); err != nil {
+		// The lease expires and the event is published again; consumers must be idempotent
+		logger.Error(ctx, "Failed to mark event as published", err, "event_id", event.ID)
+		return
+	}
+
+	logger.Info(ctx, "Event published successfully", nil, "event_id", event.ID, "event_type", event.Type)
+}
+
+// outboxBackoff returns the delay before the given attempt: 1s, 2s, 4s, ... capped at outboxMaxBackoff
+func outboxBackoff(attempts int) time.Duration {
+	if attempts > 9 {
+		return outboxMaxBackoff
+	}
+	backoff := time.Duration(1<<uint(attempts-1)) * time.Second
+	if backoff > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return backoff
+}
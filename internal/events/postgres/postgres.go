@@ -0,0 +1,504 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rbac-service/internal/events"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/model"
+	"rbac-service/internal/repository"
+	"sync"
+	"time"
+)
+
+const (
+	// pollInterval is how often consumers look for messages without a notification,
+	// which picks up delayed messages and messages whose visibility timeout expired
+	pollInterval = time.Second
+	// maxReleaseBackoff caps the delay before a failed message is delivered again
+	maxReleaseBackoff = time.Minute
+)
+
+// QueueStore stores exchanges, queues, bindings and messages. It is
+// implemented by repository.QueueRepository.
+type QueueStore interface {
+	// Ping checks the database connection
+	Ping(ctx context.Context) error
+
+	DeclareExchange(ctx context.Context, name, exchangeType string) error
+	DeclareQueue(ctx context.Context, name string) error
+	BindQueue(ctx context.Context, binding model.QueueBinding) error
+	// GetExchangeBindings returns an exchange's type and the bindings of its queues
+	GetExchangeBindings(ctx context.Context, exchange string) (string, []model.QueueBinding, error)
+
+	// EnqueueMessage stores a message in the queues that exist, returning how many
+	EnqueueMessage(ctx context.Context, queues []string, routingKey string, headers map[string]string, body []byte, availableAt time.Time) (int, error)
+	// ClaimMessages hides visible messages for the visibility timeout and counts the delivery
+	ClaimMessages(ctx context.Context, queue string, limit int, visibilityTimeout time.Duration) ([]model.QueueMessage, error)
+	// DeleteMessage, ReleaseMessage and DeadLetterMessage settle a message
+	// claimed with deliveryCount, failing with repository.ErrQueueLeaseLost if
+	// it was claimed again since
+	DeleteMessage(ctx context.Context, id int64, deliveryCount int) error
+	ReleaseMessage(ctx context.Context, id int64, deliveryCount int, availableAt time.Time) error
+	DeadLetterMessage(ctx context.Context, id int64, deliveryCount int, queues []string, routingKey string, headers map[string]string) (int, error)
+	PurgeQueue(ctx context.Context, queue string) (int, error)
+
+	// Listen receives the names of queues that got new messages
+	Listen(ctx context.Context) (*repository.QueueListener, error)
+}
+
+// PostgresProvider implements the QueueProvider interface on the service's own
+// database, for environments without a message broker. Exchanges, queues and
+// bindings are rows; publishing copies a message to every queue with a matching
+// binding. Consumers claim messages with FOR UPDATE SKIP LOCKED, which hides
+// them for the visibility timeout, and are woken by LISTEN/NOTIFY.
+type PostgresProvider struct {
+	repo              QueueStore
+	visibilityTimeout time.Duration
+	maxDeliveries     int
+	mu                sync.Mutex
+	closed            bool
+	consumers         []*consumer
+}
+
+// consumer tracks a running consumer so it can be drained
+type consumer struct {
+	pool   *events.WorkerPool
+	stop   chan struct{}
+	done   chan struct{} // closed when the claim loop exits
+	cancel context.CancelFunc
+}
+
+// NewPostgresProvider creates a new Postgres provider. A message that fails
+// maxDeliveries times is moved to the consumer's dead-letter exchange.
+func NewPostgresProvider(repo QueueStore, visibilityTimeout time.Duration, maxDeliveries int) *PostgresProvider {
+	return &PostgresProvider{
+		repo:              repo,
+		visibilityTimeout: visibilityTimeout,
+		maxDeliveries:     maxDeliveries,
+	}
+}
+
+// Connect verifies the database is reachable
+func (p *PostgresProvider) Connect(ctx context.Context) error {
+	if err := p.checkOpen(); err != nil {
+		return err
+	}
+
+	if err := p.repo.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to connect to Postgres: %w", err)
+	}
+
+	logger.Info(ctx, "Postgres queue provider connected", nil)
+	return nil
+}
+
+// Close stops all consumers. The database pool is owned by the repository.
+func (p *PostgresProvider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+
+	for _, c := range p.consumers {
+		c.cancel()
+	}
+	p.consumers = nil
+
+	return nil
+}
+
+// Publish stores a message in every queue bound to the exchange with a matching
+// routing key. The default exchange ("") stores it in the queue named by the
+// routing key. A message that reaches no queue returns events.ErrUnroutable.
+func (p *PostgresProvider) Publish(ctx context.Context, exchange, routingKey string, body []byte, headers map[string]string) error {
+	if err := p.checkOpen(); err != nil {
+		return err
+	}
+
+	queues, err := p.route(ctx, exchange, routingKey)
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	return p.enqueue(ctx, exchange, queues, routingKey, headers, body, time.Now())
+}
+
+// PublishDelayed stores a message in a queue, invisible until the delay has passed
+func (p *PostgresProvider) PublishDelayed(ctx context.Context, queue string, body []byte, headers map[string]string, delay time.Duration) error {
+	if err := p.checkOpen(); err != nil {
+		return err
+	}
+
+	return p.enqueue(ctx, "", []string{queue}, queue, headers, body, time.Now().Add(delay))
+}
+
+// route returns the queues bound to an exchange with a matching routing key
+func (p *PostgresProvider) route(ctx context.Context, exchange, routingKey string) ([]string, error) {
+	if exchange == "" {
+		return []string{routingKey}, nil
+	}
+
+	exchangeType, bindings, err := p.repo.GetExchangeBindings(ctx, exchange)
+	if err != nil {
+		if errors.Is(err, repository.ErrQueueExchangeNotFound) {
+			return nil, fmt.Errorf("exchange %s not found", exchange)
+		}
+		return nil, err
+	}
+
+	// A message is stored in each matching queue once, even if several bindings match
+	var queues []string
+	matched := make(map[string]bool)
+	for _, b := range bindings {
+		if !matched[b.Queue] && matches(exchangeType, b.RoutingKey, routingKey) {
+			queues = append(queues, b.Queue)
+			matched[b.Queue] = true
+		}
+	}
+	return queues, nil
+}
+
+// enqueue stores a message in the queues, failing with events.ErrUnroutable if
+// none of them exist
+func (p *PostgresProvider) enqueue(ctx context.Context, exchange string, queues []string, routingKey string, headers map[string]string, body []byte, availableAt time.Time) error {
+	stored, err := p.repo.EnqueueMessage(ctx, queues, routingKey, headers, body, availableAt)
+	if err != nil {
+		return err
+	}
+	if stored == 0 {
+		return fmt.Errorf("%w (exchange %q, routing key %q)", events.ErrUnroutable, exchange, routingKey)
+	}
+	return nil
+}
+
+// Consume starts claiming messages from a queue. Messages are deleted when the
+// handler succeeds and released with backoff when it fails.
+func (p *PostgresProvider) Consume(ctx context.Context, queue string, handler events.MessageHandler, opts events.ConsumeOptions) error {
+	opts = opts.Normalize()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return fmt.Errorf("provider is closed")
+	}
+
+	consumerCtx, cancel := context.WithCancel(ctx)
+	c := &consumer{
+		pool:   events.NewWorkerPool(consumerCtx, opts, handler),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+	p.consumers = append(p.consumers, c)
+
+	// Signalled by notifications for this queue and whenever a message is settled
+	wake := make(chan struct{}, 1)
+	signal := func() {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+
+	go p.listen(consumerCtx, c.stop, queue, signal)
+
+	go func() {
+		defer close(c.done)
+		logger.Info(consumerCtx, "Started consuming from queue", nil, "queue", queue, "workers", fmt.Sprintf("%d", opts.Workers))
+
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		// Limits claimed but unsettled messages, like a RabbitMQ prefetch count
+		inFlight := make(chan struct{}, opts.Prefetch)
+
+		for {
+			claimed := 0
+			if free := cap(inFlight) - len(inFlight); free > 0 {
+				messages, err := p.repo.ClaimMessages(consumerCtx, queue, free, p.visibilityTimeout)
+				if err != nil && consumerCtx.Err() == nil {
+					logger.Error(consumerCtx, "Failed to claim messages", err, "queue", queue)
+				}
+
+				for _, msg := range messages {
+					inFlight <- struct{}{}
+					c.pool.Submit(events.Message{
+						RoutingKey: msg.RoutingKey,
+						Headers:    msg.Headers,
+						Body:       msg.Body,
+					}, p.settle(consumerCtx, queue, opts.DeadLetterExchange, msg, func() {
+						<-inFlight
+						signal()
+					}))
+				}
+				claimed = len(messages)
+			}
+
+			// A full batch means more may be waiting
+			if claimed > 0 && claimed == cap(inFlight) {
+				select {
+				case <-c.stop:
+					logger.Info(consumerCtx, "Consumer stopped", nil, "queue", queue)
+					return
+				default:
+					continue
+				}
+			}
+
+			select {
+			case <-wake:
+			case <-ticker.C:
+			case <-c.stop:
+				logger.Info(consumerCtx, "Consumer stopped", nil, "queue", queue)
+				return
+			case <-consumerCtx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// settle returns the completion callback of a message
+func (p *PostgresProvider) settle(ctx context.Context, queue, deadLetterExchange string, msg model.QueueMessage, release func()) func(err error) {
+	return func(err error) {
+		defer release()
+
+		if err == nil {
+			if delErr := p.repo.DeleteMessage(ctx, msg.ID, msg.DeliveryCount); delErr != nil {
+				if errors.Is(delErr, repository.ErrQueueLeaseLost) {
+					// Another delivery owns the message now and settles it
+					logger.Warn(ctx, "Message was claimed again before it was acknowledged", nil, "queue", queue, "visibility_timeout", p.visibilityTimeout.String())
+					return
+				}
+				// It becomes visible again after the visibility timeout
+				logger.Error(ctx, "Failed to acknowledge message", delErr, "queue", queue)
+			}
+			return
+		}
+
+		logger.Error(ctx, "Failed to process message", err, "queue", queue)
+
+		if p.maxDeliveries > 0 && msg.DeliveryCount >= p.maxDeliveries {
+			if p.deadLetter(ctx, queue, deadLetterExchange, msg, err) {
+				return
+			}
+		}
+
+		// Backoff: 1s, 2s, 4s, ... by delivery count
+		backoff := time.Duration(1<<uint(msg.DeliveryCount-1)) * time.Second
+		if backoff > maxReleaseBackoff {
+			backoff = maxReleaseBackoff
+		}
+		if relErr := p.repo.ReleaseMessage(ctx, msg.ID, msg.DeliveryCount, time.Now().Add(backoff)); relErr != nil {
+			if errors.Is(relErr, repository.ErrQueueLeaseLost) {
+				logger.Warn(ctx, "Message was claimed again before it was released", nil, "queue", queue, "visibility_timeout", p.visibilityTimeout.String())
+				return
+			}
+			logger.Error(ctx, "Failed to release message", relErr, "queue", queue)
+		}
+	}
+}
+
+// deadLetter moves a message that reached the delivery limit to the
+// dead-letter exchange, or drops it if there is none. It returns false if the
+// message could not be dead-lettered and should be released instead.
+func (p *PostgresProvider) deadLetter(ctx context.Context, queue, deadLetterExchange string, msg model.QueueMessage, cause error) bool {
+	deliveries := fmt.Sprintf("%d", msg.DeliveryCount)
+
+	if deadLetterExchange == "" {
+		logger.Warn(ctx, "Message reached delivery limit, dropping", nil, "queue", queue, "deliveries", deliveries)
+		if delErr := p.repo.DeleteMessage(ctx, msg.ID, msg.DeliveryCount); delErr != nil {
+			if errors.Is(delErr, repository.ErrQueueLeaseLost) {
+				logger.Warn(ctx, "Message was claimed again before it was dropped", nil, "queue", queue)
+				return true
+			}
+			logger.Error(ctx, "Failed to drop message", delErr, "queue", queue)
+		}
+		return true
+	}
+
+	headers := make(map[string]string, len(msg.Headers)+5)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[events.HeaderDeadLetterReason] = events.DeadLetterReasonDeliveryLimit
+	headers[events.HeaderDeadLetterError] = cause.Error()
+	headers[events.HeaderOriginalQueue] = queue
+	headers[events.HeaderRetryCount] = deliveries
+	headers[events.HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339)
+
+	queues, err := p.route(ctx, deadLetterExchange, msg.RoutingKey)
+	if err != nil {
+		logger.Error(ctx, "Failed to dead-letter message", err, "queue", queue)
+		return false
+	}
+
+	moved, err := p.repo.DeadLetterMessage(ctx, msg.ID, msg.DeliveryCount, queues, msg.RoutingKey, headers)
+	if errors.Is(err, repository.ErrQueueLeaseLost) {
+		logger.Warn(ctx, "Message was claimed again before it was dead-lettered", nil, "queue", queue)
+		return true
+	}
+	if err != nil {
+		logger.Error(ctx, "Failed to dead-letter message", err, "queue", queue)
+		return false
+	}
+	if moved == 0 {
+		logger.Error(ctx, "Failed to dead-letter message", events.ErrUnroutable, "queue", queue, "exchange", deadLetterExchange)
+		return false
+	}
+
+	logger.Warn(ctx, "Message reached delivery limit, dead-lettered", nil, "queue", queue, "deliveries", deliveries, "exchange", deadLetterExchange)
+	return true
+}
+
+// listen wakes the consumer when a message is stored in its queue, reconnecting on errors
+func (p *PostgresProvider) listen(ctx context.Context, stop <-chan struct{}, queue string, wake func()) {
+	listenCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-listenCtx.Done():
+		}
+	}()
+
+	for listenCtx.Err() == nil {
+		listener, err := p.repo.Listen(listenCtx)
+		if err != nil {
+			if listenCtx.Err() == nil {
+				logger.Warn(ctx, "Failed to listen for queue notifications, polling only", nil, "queue", queue, "error", err.Error())
+			}
+		} else {
+			for {
+				notified, err := listener.Wait(listenCtx)
+				if err != nil {
+					if listenCtx.Err() == nil {
+						logger.Warn(ctx, "Lost queue notification connection", nil, "queue", queue, "error", err.Error())
+					}
+					break
+				}
+				if notified == queue {
+					wake()
+				}
+			}
+			listener.Close()
+		}
+
+		select {
+		case <-time.After(pollInterval):
+		case <-listenCtx.Done():
+		}
+	}
+}
+
+// StopConsuming stops all consumers and waits for in-flight messages to be handled
+func (p *PostgresProvider) StopConsuming(ctx context.Context) error {
+	p.mu.Lock()
+	consumers := p.consumers
+	p.consumers = nil
+	p.mu.Unlock()
+
+	for _, c := range consumers {
+		close(c.stop)
+	}
+
+	for _, c := range consumers {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for consumer to stop: %w", ctx.Err())
+		}
+
+		if err := c.pool.Drain(ctx); err != nil {
+			return fmt.Errorf("timed out waiting for in-flight messages: %w", err)
+		}
+		c.cancel()
+	}
+
+	return nil
+}
+
+// HealthCheck verifies the database is reachable
+func (p *PostgresProvider) HealthCheck(ctx context.Context) error {
+	if err := p.checkOpen(); err != nil {
+		return err
+	}
+	return p.repo.Ping(ctx)
+}
+
+// DeclareExchange declares an exchange of type topic, direct or fanout
+func (p *PostgresProvider) DeclareExchange(ctx context.Context, exchange, exchangeType string) error {
+	switch exchangeType {
+	case "topic", "direct", "fanout":
+	default:
+		return fmt.Errorf("failed to declare exchange: unsupported type %s", exchangeType)
+	}
+
+	if err := p.repo.DeclareExchange(ctx, exchange, exchangeType); err != nil {
+		return fmt.Errorf("failed to declare exchange: %w", err)
+	}
+
+	logger.Info(ctx, "Exchange declared", nil, "exchange", exchange, "type", exchangeType)
+	return nil
+}
+
+// DeclareQueue declares a queue
+func (p *PostgresProvider) DeclareQueue(ctx context.Context, queue string) (string, error) {
+	if err := p.repo.DeclareQueue(ctx, queue); err != nil {
+		return "", err
+	}
+
+	logger.Info(ctx, "Queue declared", nil, "queue", queue)
+	return queue, nil
+}
+
+// BindQueue binds a queue to an exchange
+func (p *PostgresProvider) BindQueue(ctx context.Context, queue, exchange, routingKey string) error {
+	err := p.repo.BindQueue(ctx, model.QueueBinding{Queue: queue, Exchange: exchange, RoutingKey: routingKey})
+	if err != nil {
+		return err
+	}
+
+	logger.Info(ctx, "Queue bound to exchange", nil, "queue", queue, "exchange", exchange, "routing_key", routingKey)
+	return nil
+}
+
+// PurgeQueue removes all visible messages from a queue
+func (p *PostgresProvider) PurgeQueue(ctx context.Context, queue string) (int, error) {
+	count, err := p.repo.PurgeQueue(ctx, queue)
+	if err != nil {
+		return 0, err
+	}
+
+	logger.Info(ctx, "Queue purged", nil, "queue", queue, "messages", fmt.Sprintf("%d", count))
+	return count, nil
+}
+
+func (p *PostgresProvider) checkOpen() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return fmt.Errorf("provider is closed")
+	}
+	return nil
+}
+
+// matches reports whether a binding key matches a routing key for an exchange type
+func matches(exchangeType, bindingKey, routingKey string) bool {
+	switch exchangeType {
+	case "fanout":
+		return true
+	case "direct":
+		return bindingKey == routingKey
+	default:
+		return events.MatchTopic(bindingKey, routingKey)
+	}
+}
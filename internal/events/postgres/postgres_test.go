@@ -0,0 +1,444 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"rbac-service/internal/events"
+	"rbac-service/internal/model"
+	"rbac-service/internal/repository"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryQueueStore is an in-memory QueueStore with the semantics of
+// repository.QueueRepository
+type memoryQueueStore struct {
+	mu        sync.Mutex
+	exchanges map[string]string
+	queues    map[string]bool
+	bindings  []model.QueueBinding
+	messages  map[int64]*model.QueueMessage
+	nextID    int64
+}
+
+func newMemoryQueueStore() *memoryQueueStore {
+	return &memoryQueueStore{
+		exchanges: make(map[string]string),
+		queues:    make(map[string]bool),
+		messages:  make(map[int64]*model.QueueMessage),
+	}
+}
+
+func (s *memoryQueueStore) Ping(ctx context.Context) error { return nil }
+
+func (s *memoryQueueStore) DeclareExchange(ctx context.Context, name, exchangeType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exchanges[name] = exchangeType
+	return nil
+}
+
+func (s *memoryQueueStore) DeclareQueue(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queues[name] = true
+	return nil
+}
+
+func (s *memoryQueueStore) BindQueue(ctx context.Context, binding model.QueueBinding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bindings = append(s.bindings, binding)
+	return nil
+}
+
+func (s *memoryQueueStore) GetExchangeBindings(ctx context.Context, exchange string) (string, []model.QueueBinding, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exchangeType, ok := s.exchanges[exchange]
+	if !ok {
+		return "", nil, repository.ErrQueueExchangeNotFound
+	}
+
+	var bindings []model.QueueBinding
+	for _, b := range s.bindings {
+		if b.Exchange == exchange {
+			bindings = append(bindings, b)
+		}
+	}
+	return exchangeType, bindings, nil
+}
+
+func (s *memoryQueueStore) EnqueueMessage(ctx context.Context, queues []string, routingKey string, headers map[string]string, body []byte, availableAt time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := 0
+	for _, queue := range queues {
+		if !s.queues[queue] {
+			continue
+		}
+		s.nextID++
+		s.messages[s.nextID] = &model.QueueMessage{
+			ID:          s.nextID,
+			Queue:       queue,
+			RoutingKey:  routingKey,
+			Headers:     headers,
+			Body:        body,
+			AvailableAt: availableAt,
+			CreatedAt:   time.Now(),
+		}
+		stored++
+	}
+	return stored, nil
+}
+
+func (s *memoryQueueStore) ClaimMessages(ctx context.Context, queue string, limit int, visibilityTimeout time.Duration) ([]model.QueueMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var claimed []model.QueueMessage
+	for _, msg := range s.sortedUnsafe() {
+		if len(claimed) == limit {
+			break
+		}
+		if msg.Queue != queue || msg.AvailableAt.After(now) {
+			continue
+		}
+		msg.AvailableAt = now.Add(visibilityTimeout)
+		msg.DeliveryCount++
+		claimed = append(claimed, *msg)
+	}
+	return claimed, nil
+}
+
+func (s *memoryQueueStore) DeleteMessage(ctx context.Context, id int64, deliveryCount int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if msg, ok := s.messages[id]; !ok || msg.DeliveryCount != deliveryCount {
+		return repository.ErrQueueLeaseLost
+	}
+	delete(s.messages, id)
+	return nil
+}
+
+func (s *memoryQueueStore) ReleaseMessage(ctx context.Context, id int64, deliveryCount int, availableAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.messages[id]
+	if !ok || msg.DeliveryCount != deliveryCount {
+		return repository.ErrQueueLeaseLost
+	}
+	msg.AvailableAt = availableAt
+	return nil
+}
+
+func (s *memoryQueueStore) DeadLetterMessage(ctx context.Context, id int64, deliveryCount int, queues []string, routingKey string, headers map[string]string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.messages[id]
+	if !ok || msg.DeliveryCount != deliveryCount {
+		return 0, repository.ErrQueueLeaseLost
+	}
+
+	stored := 0
+	for _, queue := range queues {
+		if !s.queues[queue] {
+			continue
+		}
+		s.nextID++
+		s.messages[s.nextID] = &model.QueueMessage{
+			ID:          s.nextID,
+			Queue:       queue,
+			RoutingKey:  routingKey,
+			Headers:     headers,
+			Body:        msg.Body,
+			AvailableAt: time.Now(),
+			CreatedAt:   time.Now(),
+		}
+		stored++
+	}
+	if stored > 0 {
+		delete(s.messages, id)
+	}
+	return stored, nil
+}
+
+func (s *memoryQueueStore) PurgeQueue(ctx context.Context, queue string) (int, error) {
+	return 0, nil
+}
+
+// Listen blocks until the consumer stops, leaving it to poll
+func (s *memoryQueueStore) Listen(ctx context.Context) (*repository.QueueListener, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (s *memoryQueueStore) sortedUnsafe() []*model.QueueMessage {
+	messages := make([]*model.QueueMessage, 0, len(s.messages))
+	for _, msg := range s.messages {
+		messages = append(messages, msg)
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages
+}
+
+// queued returns copies of the messages stored in a queue
+func (s *memoryQueueStore) queued(queue string) []model.QueueMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var messages []model.QueueMessage
+	for _, msg := range s.sortedUnsafe() {
+		if msg.Queue == queue {
+			messages = append(messages, *msg)
+		}
+	}
+	return messages
+}
+
+// reclaim simulates another consumer claiming a message after its visibility timeout
+func (s *memoryQueueStore) reclaim(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[id].DeliveryCount++
+}
+
+// newTestProvider declares a topic exchange "events" with queue "requests"
+// bound to "rbac.*.request", and a dead-letter exchange "events.dlx" with queue
+// "dead_letter" bound to "#"
+func newTestProvider(t *testing.T, maxDeliveries int) (*PostgresProvider, *memoryQueueStore) {
+	t.Helper()
+
+	ctx := context.Background()
+	store := newMemoryQueueStore()
+	p := NewPostgresProvider(store, time.Minute, maxDeliveries)
+	t.Cleanup(func() { p.Close() })
+
+	if err := p.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, ex := range []string{"events", "events.dlx"} {
+		if err := p.DeclareExchange(ctx, ex, "topic"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, q := range []string{"requests", "dead_letter"} {
+		if _, err := p.DeclareQueue(ctx, q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.BindQueue(ctx, "requests", "events", "rbac.*.request"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.BindQueue(ctx, "dead_letter", "events.dlx", "#"); err != nil {
+		t.Fatal(err)
+	}
+
+	return p, store
+}
+
+// consume starts a consumer on "requests" that dead-letters to "events.dlx"
+func consume(t *testing.T, p *PostgresProvider, handler events.MessageHandler) {
+	t.Helper()
+
+	opts := events.ConsumeOptions{DeadLetterExchange: "events.dlx"}
+	if err := p.Consume(context.Background(), "requests", handler, opts); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := p.StopConsuming(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPublishRoutesByTopic(t *testing.T) {
+	p, store := newTestProvider(t, 0)
+	ctx := context.Background()
+
+	if err := p.BindQueue(ctx, "dead_letter", "events", "rbac.#"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		routingKey string
+		wantQueues []string
+	}{
+		{routingKey: "rbac.thing.request", wantQueues: []string{"requests", "dead_letter"}},
+		{routingKey: "rbac.thing.created", wantQueues: []string{"dead_letter"}},
+		{routingKey: "rbac.thing.sub.request", wantQueues: []string{"dead_letter"}},
+		{routingKey: "other.thing.request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.routingKey, func(t *testing.T) {
+			before := map[string]int{"requests": len(store.queued("requests")), "dead_letter": len(store.queued("dead_letter"))}
+
+			err := p.Publish(ctx, "events", tt.routingKey, []byte(`{}`), nil)
+			if len(tt.wantQueues) == 0 {
+				if !errors.Is(err, events.ErrUnroutable) {
+					t.Fatalf("err = %v, want ErrUnroutable", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := map[string]bool{}
+			for _, q := range tt.wantQueues {
+				want[q] = true
+			}
+			for queue, n := range before {
+				got := len(store.queued(queue)) - n
+				if want[queue] && got != 1 || !want[queue] && got != 0 {
+					t.Fatalf("queue %s got %d messages, want routed = %v", queue, got, want[queue])
+				}
+			}
+		})
+	}
+}
+
+func TestConsumeDeletesHandledMessage(t *testing.T) {
+	p, store := newTestProvider(t, 3)
+
+	handled := make(chan events.Message, 1)
+	consume(t, p, func(ctx context.Context, msg events.Message) error {
+		handled <- msg
+		return nil
+	})
+
+	if err := p.Publish(context.Background(), "events", "rbac.thing.request", []byte(`{"id":"1"}`), map[string]string{"k": "v"}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := <-handled
+	if string(msg.Body) != `{"id":"1"}` || msg.Headers["k"] != "v" {
+		t.Fatalf("handled %+v", msg)
+	}
+	waitFor(t, "message to be deleted", func() bool { return len(store.queued("requests")) == 0 })
+}
+
+func TestConsumeReleasesFailedMessage(t *testing.T) {
+	p, store := newTestProvider(t, 3)
+
+	var mu sync.Mutex
+	calls := 0
+	consume(t, p, func(ctx context.Context, msg events.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+
+	if err := p.Publish(context.Background(), "events", "rbac.thing.request", []byte(`{}`), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "message to be handled again and deleted", func() bool { return len(store.queued("requests")) == 0 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Fatalf("handler called %d times, want 2", calls)
+	}
+	if n := len(store.queued("dead_letter")); n != 0 {
+		t.Fatalf("%d messages dead-lettered, want 0", n)
+	}
+}
+
+func TestConsumeDeadLettersAfterDeliveryLimit(t *testing.T) {
+	p, store := newTestProvider(t, 1)
+
+	consume(t, p, func(ctx context.Context, msg events.Message) error {
+		return errors.New("permanent failure")
+	})
+
+	if err := p.Publish(context.Background(), "events", "rbac.thing.request", []byte(`{}`), map[string]string{"k": "v"}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "message to be dead-lettered", func() bool { return len(store.queued("dead_letter")) == 1 })
+
+	if n := len(store.queued("requests")); n != 0 {
+		t.Fatalf("%d messages left in the queue, want 0", n)
+	}
+	dead := store.queued("dead_letter")[0]
+	if dead.RoutingKey != "rbac.thing.request" {
+		t.Fatalf("routing key = %q, want %q", dead.RoutingKey, "rbac.thing.request")
+	}
+	if dead.Headers[events.HeaderDeadLetterReason] != events.DeadLetterReasonDeliveryLimit {
+		t.Fatalf("reason = %q, want %q", dead.Headers[events.HeaderDeadLetterReason], events.DeadLetterReasonDeliveryLimit)
+	}
+	if dead.Headers[events.HeaderDeadLetterError] != "permanent failure" || dead.Headers["k"] != "v" {
+		t.Fatalf("headers = %v", dead.Headers)
+	}
+}
+
+func TestConsumeLeavesReclaimedMessage(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		err  error
+	}{
+		{name: "acknowledged", err: nil},
+		{name: "failed", err: errors.New("temporary failure")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p, store := newTestProvider(t, 3)
+
+			started := make(chan struct{})
+			release := make(chan struct{})
+			consume(t, p, func(ctx context.Context, msg events.Message) error {
+				close(started)
+				<-release
+				return tt.err
+			})
+
+			if err := p.Publish(context.Background(), "events", "rbac.thing.request", []byte(`{}`), nil); err != nil {
+				t.Fatal(err)
+			}
+			<-started
+
+			// The visibility timeout ran out and another consumer claimed the message
+			claimed := store.queued("requests")[0]
+			store.reclaim(claimed.ID)
+			close(release)
+
+			// The first delivery neither deletes nor releases the other's claim
+			time.Sleep(100 * time.Millisecond)
+			messages := store.queued("requests")
+			if len(messages) != 1 {
+				t.Fatalf("%d messages in the queue, want the reclaimed one", len(messages))
+			}
+			if !messages[0].AvailableAt.Equal(claimed.AvailableAt) {
+				t.Fatalf("available at changed from %v to %v", claimed.AvailableAt, messages[0].AvailableAt)
+			}
+		})
+	}
+}
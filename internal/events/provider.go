@@ -1,6 +1,20 @@
 package events
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Publish outcomes reported by providers that confirm delivery
+var (
+	// ErrUnroutable means the broker accepted the message but no queue was bound for it
+	ErrUnroutable = errors.New("message is unroutable")
+	// ErrPublishNacked means the broker refused the message
+	ErrPublishNacked = errors.New("message was rejected by the broker")
+	// ErrPublishTimeout means the broker did not confirm the message in time
+	ErrPublishTimeout = errors.New("timed out waiting for publish confirmation")
+)
 
 // QueueProvider defines the interface for message queue providers
 type QueueProvider interface {
@@ -10,11 +24,20 @@ type QueueProvider interface {
 	// Close closes the connection to the queue provider
 	Close() error
 
-	// Publish publishes an event to an exchange with a routing key
-	Publish(ctx context.Context, exchange, routingKey string, body []byte) error
+	// Publish publishes an event to an exchange with a routing key and optional
+	// headers. Providers that confirm delivery return nil only once the broker
+	// has accepted the message.
+	Publish(ctx context.Context, exchange, routingKey string, body []byte, headers map[string]string) error
+
+	// PublishDelayed delivers a message directly to a queue once the delay has elapsed
+	PublishDelayed(ctx context.Context, queue string, body []byte, headers map[string]string, delay time.Duration) error
 
 	// Consume starts consuming messages from a queue
-	Consume(ctx context.Context, queue string, handler MessageHandler) error
+	Consume(ctx context.Context, queue string, handler MessageHandler, opts ConsumeOptions) error
+
+	// StopConsuming stops delivering new messages and waits for in-flight
+	// handlers to finish or ctx to expire
+	StopConsuming(ctx context.Context) error
 
 	// HealthCheck verifies the connection is healthy
 	HealthCheck(ctx context.Context) error
@@ -27,7 +50,25 @@ type QueueProvider interface {
 
 	// BindQueue binds a queue to an exchange with a routing key
 	BindQueue(ctx context.Context, queue, exchange, routingKey string) error
+
+	// PurgeQueue removes all ready messages from a queue and returns how many were removed
+	PurgeQueue(ctx context.Context, queue string) (int, error)
+}
+
+// Reconnector is implemented by providers that reconnect on their own when the
+// connection is lost. The health checker asks them to reconnect instead of
+// calling Connect, so it doesn't race their own reconnection.
+type Reconnector interface {
+	// Reconnect connects again unless the provider is already reconnecting
+	Reconnect(ctx context.Context) error
+}
+
+// Message is a message delivered by a queue provider
+type Message struct {
+	RoutingKey string
+	Headers    map[string]string
+	Body       []byte
 }
 
 // MessageHandler is a function that processes incoming messages
-type MessageHandler func(ctx context.Context, body []byte) error
+type MessageHandler func(ctx context.Context, msg Message) error
@@ -2,34 +2,37 @@ package events
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"rbac-service/internal/logger"
 	"rbac-service/internal/model"
-	"rbac-service/internal/repository"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// Publisher handles publishing events with audit trail
+// Publisher writes events to the outbox and sends them to the queue provider
 type Publisher struct {
 	provider  QueueProvider
-	auditRepo *repository.EventAuditRepository
+	auditRepo AuditStore
 	exchange  string
+	envelope  EnvelopeConfig
+	wake      chan struct{}
 }
 
 // NewPublisher creates a new publisher
-func NewPublisher(provider QueueProvider, auditRepo *repository.EventAuditRepository, exchange string) *Publisher {
+func NewPublisher(provider QueueProvider, auditRepo AuditStore, exchange string, envelope EnvelopeConfig) *Publisher {
 	return &Publisher{
 		provider:  provider,
 		auditRepo: auditRepo,
 		exchange:  exchange,
+		envelope:  envelope,
+		wake:      make(chan struct{}, 1),
 	}
 }
 
-// PublishRaw publishes an event with audit trail (internal use)
-func (p *Publisher) PublishRaw(ctx context.Context, event model.Event) error {
+// Enqueue writes an event to the outbox. The outbox relay publishes it,
+// retrying until the queue provider accepts it.
+func (p *Publisher) Enqueue(ctx context.Context, event model.Event) error {
 	// Generate ID if not provided
 	if event.ID == "" {
 		event.ID = uuid.New().String()
@@ -40,92 +43,59 @@ func (p *Publisher) PublishRaw(ctx context.Context, event model.Event) error {
 		event.Timestamp = time.Now()
 	}
 
-	// Serialize payload
-	payloadBytes, err := json.Marshal(event.Payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event payload: %w", err)
+	if err := p.auditRepo.EnqueuePublishedEvent(ctx, event); err != nil {
+		logger.Error(ctx, "Failed to enqueue event", err, "event_id", event.ID, "event_type", event.Type)
+		return err
 	}
 
-	// Create audit entry with pending status
-	auditEvent := &model.PublishedEvent{
-		ID:        event.ID,
-		EventType: event.Type,
-		Payload:   payloadBytes,
-		Status:    model.StatusPending,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
+	p.Notify()
+	return nil
+}
 
-	err = p.auditRepo.CreatePublishedEvent(ctx, auditEvent)
-	if err != nil {
-		logger.Error(ctx, "Failed to create published event audit entry", err, "event_id", event.ID, "event_type", event.Type)
-		return fmt.Errorf("failed to create audit entry: %w", err)
+// Publish implements the app.EventPublisher interface
+func (p *Publisher) Publish(ctx context.Context, eventType string, payload interface{}) error {
+	event := model.Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Payload:   payload,
+		Timestamp: time.Now(),
 	}
+	return p.Enqueue(ctx, event)
+}
 
-	// Serialize entire event
-	eventBytes, err := json.Marshal(event)
-	if err != nil {
-		errMsg := err.Error()
-		p.auditRepo.UpdatePublishedEvent(ctx, event.ID, model.StatusFailed, &errMsg)
-		return fmt.Errorf("failed to marshal event: %w", err)
+// PublishDirect sends an event straight to the queue provider, without writing
+// it to the outbox. It is meant for high-volume telemetry that may be lost,
+// such as permission decisions, where an outbox row per event would cost more
+// than the event is worth. An event that cannot be published is not retried.
+func (p *Publisher) PublishDirect(ctx context.Context, eventType string, payload interface{}) error {
+	return p.send(ctx, model.Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Payload:   payload,
+		Timestamp: time.Now(),
+	})
+}
+
+// Notify wakes the outbox relay so new events are published without waiting
+// for the next poll
+func (p *Publisher) Notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
 	}
+}
 
-	// Publish to exchange
-	err = p.provider.Publish(ctx, p.exchange, event.Type, eventBytes)
+// send publishes a single event to the exchange in the configured format
+func (p *Publisher) send(ctx context.Context, event model.Event) error {
+	body, headers, err := p.envelope.Encode(event)
 	if err != nil {
-		errMsg := err.Error()
-		p.auditRepo.UpdatePublishedEvent(ctx, event.ID, model.StatusFailed, &errMsg)
-		logger.Error(ctx, "Failed to publish event", err, "event_id", event.ID, "event_type", event.Type)
-		return fmt.Errorf("failed to publish event: %w", err)
+		return err
 	}
 
-	// Update audit entry to published
-	err = p.auditRepo.UpdatePublishedEvent(ctx, event.ID, model.StatusPublished, nil)
+	err = p.provider.Publish(ctx, p.exchange, event.Type, body, headers)
 	if err != nil {
-		logger.Error(ctx, "Failed to update published event audit entry", err, "event_id", event.ID)
-		// Don't return error as the event was published successfully
+		return fmt.Errorf("failed to publish event: %w", err)
 	}
 
-	logger.Info(ctx, "Event published successfully", nil, "event_id", event.ID, "event_type", event.Type)
 	return nil
 }
-
-// PublishWithRetry publishes an event with retry logic
-func (p *Publisher) PublishWithRetry(ctx context.Context, event model.Event, maxRetries int) error {
-	var lastErr error
-
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		err := p.PublishRaw(ctx, event)
-		if err == nil {
-			return nil
-		}
-
-		lastErr = err
-		logger.Warn(ctx, "Failed to publish event, retrying", nil,
-			"event_id", event.ID,
-			"event_type", event.Type,
-			"attempt", fmt.Sprintf("%d", attempt+1),
-			"max_retries", fmt.Sprintf("%d", maxRetries),
-			"error", err.Error(),
-		)
-
-		if attempt < maxRetries {
-			// Exponential backoff
-			backoff := time.Duration(1<<uint(attempt)) * time.Second
-			time.Sleep(backoff)
-		}
-	}
-
-	return fmt.Errorf("failed to publish event after %d retries: %w", maxRetries, lastErr)
-}
-
-// Publish implements the app.EventPublisher interface
-func (p *Publisher) Publish(ctx context.Context, eventType string, payload interface{}) error {
-	event := model.Event{
-		ID:        uuid.New().String(),
-		Type:      eventType,
-		Payload:   payload,
-		Timestamp: time.Now(),
-	}
-	return p.PublishWithRetry(ctx, event, 3)
-}
@@ -0,0 +1,469 @@
+package rabbitmq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+)
+
+// AMQP 0-9-1 frame types
+const (
+	frameMethod    = 1
+	frameHeader    = 2
+	frameBody      = 3
+	frameHeartbeat = 8
+	frameEnd       = 0xCE
+)
+
+// unroutableKey is a routing key the fake broker returns as unroutable
+const unroutableKey = "unroutable"
+
+// fakeBroker speaks enough AMQP 0-9-1 for the provider's connections, pooled
+// channels, publisher confirms, returns and consumers. It records which
+// channel every publish and consume arrived on, but stores no messages.
+type fakeBroker struct {
+	listener net.Listener
+
+	mu        sync.Mutex
+	conns     []*brokerConn
+	publishes []brokerPublish
+	consumers []brokerChannel
+}
+
+// brokerChannel identifies a channel on a connection
+type brokerChannel struct {
+	conn    int
+	channel uint16
+}
+
+type brokerPublish struct {
+	brokerChannel
+	routingKey string
+}
+
+type brokerConn struct {
+	broker *fakeBroker
+	index  int
+	conn   net.Conn
+
+	writeMu sync.Mutex
+	w       *bufio.Writer
+
+	mu       sync.Mutex
+	channels map[uint16]*brokerChannelState
+}
+
+type brokerChannelState struct {
+	nextTag uint64
+	closing bool // closed by the broker, waiting for close-ok
+
+	// Publish whose content is being received
+	publishing bool
+	exchange   string
+	routingKey string
+	bodySize   uint64
+	body       []byte
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on loopback: %v", err)
+	}
+
+	b := &fakeBroker{listener: listener}
+	go b.accept()
+	t.Cleanup(func() { b.close() })
+	return b
+}
+
+func (b *fakeBroker) url() string {
+	return "amqp://guest:guest@" + b.listener.Addr().String() + "/"
+}
+
+func (b *fakeBroker) accept() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		b.mu.Lock()
+		c := &brokerConn{
+			broker:   b,
+			index:    len(b.conns),
+			conn:     conn,
+			w:        bufio.NewWriter(conn),
+			channels: make(map[uint16]*brokerChannelState),
+		}
+		b.conns = append(b.conns, c)
+		b.mu.Unlock()
+
+		go c.serve()
+	}
+}
+
+func (b *fakeBroker) close() {
+	b.listener.Close()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range b.conns {
+		c.conn.Close()
+	}
+}
+
+// recordedPublishes returns the publishes received so far
+func (b *fakeBroker) recordedPublishes() []brokerPublish {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]brokerPublish(nil), b.publishes...)
+}
+
+// recordedConsumers returns the channels consumers subscribed on
+func (b *fakeBroker) recordedConsumers() []brokerChannel {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]brokerChannel(nil), b.consumers...)
+}
+
+// openChannels returns the channels currently open on a connection
+func (b *fakeBroker) openChannels(conn int) int {
+	b.mu.Lock()
+	c := b.conns[conn]
+	b.mu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.channels)
+}
+
+// closeChannel closes a channel from the broker side, as RabbitMQ does after a channel error
+func (b *fakeBroker) closeChannel(ch brokerChannel) error {
+	b.mu.Lock()
+	c := b.conns[ch.conn]
+	b.mu.Unlock()
+
+	c.mu.Lock()
+	state, ok := c.channels[ch.channel]
+	if ok {
+		state.closing = true
+	}
+	c.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("channel %d is not open", ch.channel)
+	}
+
+	var a args
+	a.short(406)
+	a.shortstr("PRECONDITION_FAILED - closed by test")
+	a.short(0)
+	a.short(0)
+	return c.writeMethod(ch.channel, 20, 40, a.Bytes())
+}
+
+func (c *brokerConn) serve() {
+	defer c.conn.Close()
+	r := bufio.NewReader(c.conn)
+
+	// Protocol header: "AMQP" 0 0 9 1
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return
+	}
+
+	var start args
+	start.octet(0)
+	start.octet(9)
+	start.long(0) // server properties
+	start.longstr("PLAIN")
+	start.longstr("en_US")
+	if err := c.writeMethod(0, 10, 10, start.Bytes()); err != nil {
+		return
+	}
+
+	for {
+		typ, channel, payload, err := readFrame(r)
+		if err != nil {
+			return
+		}
+
+		switch typ {
+		case frameMethod:
+			if !c.handleMethod(channel, payload) {
+				return
+			}
+		case frameHeader:
+			c.handleHeader(channel, payload)
+		case frameBody:
+			c.handleBody(channel, payload)
+		case frameHeartbeat:
+		}
+	}
+}
+
+// handleMethod answers a method frame, returning false once the connection is closed
+func (c *brokerConn) handleMethod(channel uint16, payload []byte) bool {
+	class := binary.BigEndian.Uint16(payload[0:2])
+	method := binary.BigEndian.Uint16(payload[2:4])
+	p := payload[4:]
+
+	switch {
+	// connection.start-ok
+	case class == 10 && method == 11:
+		var tune args
+		tune.short(0)
+		tune.long(131072)
+		tune.short(0)
+		c.writeMethod(0, 10, 30, tune.Bytes())
+
+	// connection.tune-ok
+	case class == 10 && method == 31:
+
+	// connection.open
+	case class == 10 && method == 40:
+		var ok args
+		ok.shortstr("")
+		c.writeMethod(0, 10, 41, ok.Bytes())
+
+	// connection.close
+	case class == 10 && method == 50:
+		c.writeMethod(0, 10, 51, nil)
+		return false
+
+	// channel.open
+	case class == 20 && method == 10:
+		c.mu.Lock()
+		c.channels[channel] = &brokerChannelState{}
+		c.mu.Unlock()
+
+		var ok args
+		ok.longstr("")
+		c.writeMethod(channel, 20, 11, ok.Bytes())
+
+	// channel.close
+	case class == 20 && method == 40:
+		c.mu.Lock()
+		delete(c.channels, channel)
+		c.mu.Unlock()
+		c.writeMethod(channel, 20, 41, nil)
+
+	// channel.close-ok, after the broker closed the channel
+	case class == 20 && method == 41:
+		c.mu.Lock()
+		delete(c.channels, channel)
+		c.mu.Unlock()
+
+	// confirm.select
+	case class == 85 && method == 10:
+		if p[0]&1 == 0 {
+			c.writeMethod(channel, 85, 11, nil)
+		}
+
+	// basic.qos
+	case class == 60 && method == 10:
+		c.writeMethod(channel, 60, 11, nil)
+
+	// basic.consume
+	case class == 60 && method == 20:
+		rest := p[2:] // reserved
+		_, rest = shortstr(rest)
+		tag, rest := shortstr(rest)
+
+		c.broker.mu.Lock()
+		c.broker.consumers = append(c.broker.consumers, brokerChannel{conn: c.index, channel: channel})
+		c.broker.mu.Unlock()
+
+		if rest[0]&(1<<3) == 0 {
+			var ok args
+			ok.shortstr(tag)
+			c.writeMethod(channel, 60, 21, ok.Bytes())
+		}
+
+	// basic.cancel
+	case class == 60 && method == 30:
+		tag, rest := shortstr(p)
+		if rest[0]&1 == 0 {
+			var ok args
+			ok.shortstr(tag)
+			c.writeMethod(channel, 60, 31, ok.Bytes())
+		}
+
+	// basic.publish
+	case class == 60 && method == 40:
+		rest := p[2:] // reserved
+		exchange, rest := shortstr(rest)
+		routingKey, _ := shortstr(rest)
+
+		c.mu.Lock()
+		if state, ok := c.channels[channel]; ok && !state.closing {
+			state.publishing = true
+			state.exchange = exchange
+			state.routingKey = routingKey
+			state.body = nil
+		}
+		c.mu.Unlock()
+	}
+
+	return true
+}
+
+func (c *brokerConn) handleHeader(channel uint16, payload []byte) {
+	c.mu.Lock()
+	state, ok := c.channels[channel]
+	if !ok || !state.publishing {
+		c.mu.Unlock()
+		return
+	}
+	state.bodySize = binary.BigEndian.Uint64(payload[4:12])
+	done := state.bodySize == 0
+	c.mu.Unlock()
+
+	if done {
+		c.completePublish(channel)
+	}
+}
+
+func (c *brokerConn) handleBody(channel uint16, payload []byte) {
+	c.mu.Lock()
+	state, ok := c.channels[channel]
+	if !ok || !state.publishing {
+		c.mu.Unlock()
+		return
+	}
+	state.body = append(state.body, payload...)
+	done := uint64(len(state.body)) >= state.bodySize
+	c.mu.Unlock()
+
+	if done {
+		c.completePublish(channel)
+	}
+}
+
+// completePublish records a publish, returns it if it is unroutable and confirms it
+func (c *brokerConn) completePublish(channel uint16) {
+	c.mu.Lock()
+	state := c.channels[channel]
+	state.publishing = false
+	state.nextTag++
+	tag := state.nextTag
+	exchange, routingKey, body := state.exchange, state.routingKey, state.body
+	c.mu.Unlock()
+
+	c.broker.mu.Lock()
+	c.broker.publishes = append(c.broker.publishes, brokerPublish{
+		brokerChannel: brokerChannel{conn: c.index, channel: channel},
+		routingKey:    routingKey,
+	})
+	c.broker.mu.Unlock()
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if routingKey == unroutableKey {
+		var ret args
+		ret.short(312)
+		ret.shortstr("NO_ROUTE")
+		ret.shortstr(exchange)
+		ret.shortstr(routingKey)
+		c.writeMethodLocked(channel, 60, 50, ret.Bytes())
+
+		var header args
+		header.short(60)
+		header.short(0)
+		header.longlong(uint64(len(body)))
+		header.short(0)
+		writeFrame(c.w, frameHeader, channel, header.Bytes())
+		if len(body) > 0 {
+			writeFrame(c.w, frameBody, channel, body)
+		}
+	}
+
+	var ack args
+	ack.longlong(tag)
+	ack.octet(0)
+	c.writeMethodLocked(channel, 60, 80, ack.Bytes())
+	c.w.Flush()
+}
+
+func (c *brokerConn) writeMethod(channel, class, method uint16, arguments []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.writeMethodLocked(channel, class, method, arguments); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+func (c *brokerConn) writeMethodLocked(channel, class, method uint16, arguments []byte) error {
+	var payload args
+	payload.short(class)
+	payload.short(method)
+	payload.Write(arguments)
+	return writeFrame(c.w, frameMethod, channel, payload.Bytes())
+}
+
+func readFrame(r *bufio.Reader) (byte, uint16, []byte, error) {
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, 0, nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(header[3:7])+1)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, 0, nil, err
+	}
+	if payload[len(payload)-1] != frameEnd {
+		return 0, 0, nil, fmt.Errorf("malformed frame")
+	}
+
+	return header[0], binary.BigEndian.Uint16(header[1:3]), payload[:len(payload)-1], nil
+}
+
+func writeFrame(w io.Writer, typ byte, channel uint16, payload []byte) error {
+	var frame args
+	frame.octet(typ)
+	frame.short(channel)
+	frame.long(uint32(len(payload)))
+	frame.Write(payload)
+	frame.octet(frameEnd)
+	_, err := w.Write(frame.Bytes())
+	return err
+}
+
+// args encodes AMQP method arguments
+type args struct {
+	bytes.Buffer
+}
+
+func (a *args) octet(v byte) { a.WriteByte(v) }
+
+func (a *args) short(v uint16) { binary.Write(a, binary.BigEndian, v) }
+
+func (a *args) long(v uint32) { binary.Write(a, binary.BigEndian, v) }
+
+func (a *args) longlong(v uint64) { binary.Write(a, binary.BigEndian, v) }
+
+func (a *args) shortstr(s string) {
+	a.octet(byte(len(s)))
+	a.WriteString(s)
+}
+
+func (a *args) longstr(s string) {
+	a.long(uint32(len(s)))
+	a.WriteString(s)
+}
+
+// shortstr decodes a short string and returns the rest of the arguments
+func shortstr(p []byte) (string, []byte) {
+	n := int(p[0])
+	return string(p[1 : 1+n]), p[1+n:]
+}
@@ -3,6 +3,7 @@ package rabbitmq
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"rbac-service/internal/events"
 	"rbac-service/internal/logger"
 	"sync"
@@ -17,36 +18,94 @@ type RabbitMQProvider struct {
 	maxConnections      int
 	maxChannelsPerConn  int
 	connections         []*amqp.Connection
-	channelPools        [][]*amqp.Channel
 	currentConnIndex    int
-	currentChannelIndex []int
 	mu                  sync.RWMutex
 	closed              bool
 	consumerCancelFuncs []context.CancelFunc
+	delayQueues         sync.Map // delay queue name -> struct{}, declared on first use
+	consumers           []*consumer
+	idleChannels        chan *pooledChannel // pooled channels not checked out
+	channelSlots        chan struct{}       // one token per open pooled channel
+	confirmTimeout      time.Duration
+	topology            topology
+	reconnecting        bool
+	shutdown            chan struct{} // closed by Close
 }
 
-// NewRabbitMQProvider creates a new RabbitMQ provider
-func NewRabbitMQProvider(url string, maxConnections, maxChannelsPerConn int) (*RabbitMQProvider, error) {
+// topology records declared exchanges, queues and bindings so they can be
+// declared again after a reconnect
+type topology struct {
+	exchanges []exchangeDeclaration
+	queues    []string
+	bindings  []bindingDeclaration
+}
+
+type exchangeDeclaration struct {
+	name string
+	kind string
+}
+
+type bindingDeclaration struct {
+	queue      string
+	exchange   string
+	routingKey string
+}
+
+// maxReconnectBackoff caps the delay between reconnection attempts
+const maxReconnectBackoff = 30 * time.Second
+
+// pooledChannel is a channel in confirm mode used for publishing and
+// declarations. amqp091 channels are not safe for concurrent publishing, so a
+// pooled channel is checked out by one goroutine at a time; this also means a
+// returned message always belongs to the publish waiting on it.
+type pooledChannel struct {
+	channel *amqp.Channel
+	returns chan amqp.Return
+}
+
+// consumer tracks a registered consumer so it can be re-subscribed after a
+// channel or connection failure and drained on shutdown. Each consumer owns a
+// dedicated channel outside the pool.
+type consumer struct {
+	ctx     context.Context
+	queue   string
+	opts    events.ConsumeOptions
+	pool    *events.WorkerPool
+	mu      sync.Mutex
+	channel *amqp.Channel
+	tag     string
+	stopped bool
+	done    chan struct{} // closed when the current delivery loop exits
+}
+
+// NewRabbitMQProvider creates a new RabbitMQ provider. At most
+// maxConnections*maxChannelsPerConn pooled channels are open at once, and
+// publishes wait up to confirmTimeout for the broker to confirm them.
+func NewRabbitMQProvider(url string, maxConnections, maxChannelsPerConn int, confirmTimeout time.Duration) (*RabbitMQProvider, error) {
 	if url == "" {
 		return nil, fmt.Errorf("RabbitMQ URL is required")
 	}
 
 	return &RabbitMQProvider{
-		url:                 url,
-		maxConnections:      maxConnections,
-		maxChannelsPerConn:  maxChannelsPerConn,
-		connections:         make([]*amqp.Connection, 0, maxConnections),
-		channelPools:        make([][]*amqp.Channel, 0, maxConnections),
-		currentChannelIndex: make([]int, maxConnections),
+		url:                url,
+		maxConnections:     maxConnections,
+		maxChannelsPerConn: maxChannelsPerConn,
+		connections:        make([]*amqp.Connection, 0, maxConnections),
+		idleChannels:       make(chan *pooledChannel, maxConnections*maxChannelsPerConn),
+		channelSlots:       make(chan struct{}, maxConnections*maxChannelsPerConn),
+		confirmTimeout:     confirmTimeout,
+		shutdown:           make(chan struct{}),
 	}, nil
 }
 
-// Connect establishes connections to RabbitMQ
+// Connect establishes connections to RabbitMQ and declares the recorded
+// topology again, so it can also be used to reconnect. Consumers re-subscribe
+// on their own once their channel is replaced.
 func (r *RabbitMQProvider) Connect(ctx context.Context) error {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	if r.closed {
+		r.mu.Unlock()
 		return fmt.Errorf("provider is closed")
 	}
 
@@ -58,24 +117,145 @@ func (r *RabbitMQProvider) Connect(ctx context.Context) error {
 		conn, err := amqp.Dial(r.url)
 		if err != nil {
 			r.closeConnectionsUnsafe()
+			r.mu.Unlock()
 			return fmt.Errorf("failed to connect to RabbitMQ (connection %d): %w", i, err)
 		}
 
 		r.connections = append(r.connections, conn)
-		r.channelPools = append(r.channelPools, make([]*amqp.Channel, 0, r.maxChannelsPerConn))
+
+		// Detect broker failures immediately instead of waiting for the health check
+		go r.watchConnection(conn.NotifyClose(make(chan *amqp.Error, 1)), i)
 
 		logger.Info(ctx, "RabbitMQ connection established", nil, "connection_index", fmt.Sprintf("%d", i))
 	}
 
+	topology := r.topology
+	r.mu.Unlock()
+
+	// Delay queues are declared again on first use
+	r.delayQueues.Range(func(key, _ any) bool {
+		r.delayQueues.Delete(key)
+		return true
+	})
+
+	if err := r.restoreTopology(ctx, topology); err != nil {
+		return fmt.Errorf("failed to restore topology: %w", err)
+	}
+
 	logger.Info(ctx, "RabbitMQ provider connected", nil, "connections", fmt.Sprintf("%d", r.maxConnections))
 	return nil
 }
 
+// restoreTopology declares the recorded exchanges, queues and bindings
+func (r *RabbitMQProvider) restoreTopology(ctx context.Context, t topology) error {
+	for _, e := range t.exchanges {
+		if err := r.declareExchange(ctx, e.name, e.kind); err != nil {
+			return err
+		}
+	}
+	for _, q := range t.queues {
+		if _, err := r.declareQueue(ctx, q); err != nil {
+			return err
+		}
+	}
+	for _, b := range t.bindings {
+		if err := r.bindQueue(ctx, b.queue, b.exchange, b.routingKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchConnection reconnects when a connection closes unexpectedly. A nil
+// error means the connection was closed on purpose.
+func (r *RabbitMQProvider) watchConnection(closed <-chan *amqp.Error, index int) {
+	amqpErr, ok := <-closed
+	if !ok || amqpErr == nil {
+		return
+	}
+
+	logger.Error(context.Background(), "RabbitMQ connection lost", amqpErr, "connection_index", fmt.Sprintf("%d", index))
+	r.reconnect()
+}
+
+// reconnect rebuilds the connections with jittered exponential backoff until
+// it succeeds or the provider is closed. Concurrent calls are coalesced.
+func (r *RabbitMQProvider) reconnect() {
+	r.mu.Lock()
+	if r.reconnecting || r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.reconnecting = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.reconnecting = false
+		r.mu.Unlock()
+	}()
+
+	ctx := context.Background()
+	for attempt := 1; ; attempt++ {
+		delay := reconnectBackoff(attempt)
+
+		select {
+		case <-time.After(delay):
+		case <-r.shutdown:
+			return
+		}
+
+		err := r.Connect(ctx)
+		if err == nil {
+			logger.Info(ctx, "Reconnected to RabbitMQ", nil, "attempt", fmt.Sprintf("%d", attempt))
+			return
+		}
+
+		logger.Warn(ctx, "Reconnection to RabbitMQ failed", nil, "attempt", fmt.Sprintf("%d", attempt), "error", err.Error())
+	}
+}
+
+// Reconnect connects again for the health checker. If a lost connection is
+// already being reconnected, it leaves it to that reconnect.
+func (r *RabbitMQProvider) Reconnect(ctx context.Context) error {
+	r.mu.Lock()
+	if r.reconnecting {
+		r.mu.Unlock()
+		logger.Info(ctx, "RabbitMQ reconnection already in progress", nil)
+		return nil
+	}
+	r.reconnecting = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.reconnecting = false
+		r.mu.Unlock()
+	}()
+
+	return r.Connect(ctx)
+}
+
+// reconnectBackoff returns the delay before the given attempt: exponential
+// from 1s, capped at 30s, with jitter so instances don't reconnect in lockstep
+func reconnectBackoff(attempt int) time.Duration {
+	backoff := maxReconnectBackoff
+	if attempt <= 5 {
+		backoff = time.Duration(1<<uint(attempt-1)) * time.Second
+	}
+
+	// Between half and the full backoff
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
 // Close closes all connections and channels
 func (r *RabbitMQProvider) Close() error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if !r.closed {
+		close(r.shutdown)
+	}
 	r.closed = true
 
 	// Cancel all consumers
@@ -88,14 +268,17 @@ func (r *RabbitMQProvider) Close() error {
 	return nil
 }
 
-// closeConnectionsUnsafe closes all connections without locking (must be called with lock held)
+// closeConnectionsUnsafe closes all connections without locking (must be called with lock held).
+// Checked-out channels are discarded when they are returned.
 func (r *RabbitMQProvider) closeConnectionsUnsafe() {
-	// Close all channels
-	for _, channelPool := range r.channelPools {
-		for _, ch := range channelPool {
-			if ch != nil && !ch.IsClosed() {
-				ch.Close()
-			}
+	// Close idle pooled channels
+drain:
+	for {
+		select {
+		case pc := <-r.idleChannels:
+			r.discardChannel(pc)
+		default:
+			break drain
 		}
 	}
 
@@ -107,155 +290,415 @@ func (r *RabbitMQProvider) closeConnectionsUnsafe() {
 	}
 
 	r.connections = make([]*amqp.Connection, 0, r.maxConnections)
-	r.channelPools = make([][]*amqp.Channel, 0, r.maxConnections)
-	r.currentChannelIndex = make([]int, r.maxConnections)
 	r.currentConnIndex = 0
 }
 
-// getChannel gets or creates a channel from the pool (round-robin)
-func (r *RabbitMQProvider) getChannel(ctx context.Context) (*amqp.Channel, error) {
+// openChannel opens a channel on the next connection (round-robin)
+func (r *RabbitMQProvider) openChannel() (*amqp.Channel, error) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-
 	if r.closed {
+		r.mu.Unlock()
 		return nil, fmt.Errorf("provider is closed")
 	}
-
 	if len(r.connections) == 0 {
+		r.mu.Unlock()
 		return nil, fmt.Errorf("no connections available")
 	}
 
-	// Round-robin connection selection
 	connIndex := r.currentConnIndex
 	r.currentConnIndex = (r.currentConnIndex + 1) % len(r.connections)
-
 	conn := r.connections[connIndex]
+	r.mu.Unlock()
+
 	if conn.IsClosed() {
 		return nil, fmt.Errorf("connection %d is closed", connIndex)
 	}
 
-	// Try to reuse existing channel
-	channelPool := r.channelPools[connIndex]
-	if len(channelPool) < r.maxChannelsPerConn {
-		// Create new channel
-		ch, err := conn.Channel()
-		if err != nil {
-			return nil, fmt.Errorf("failed to create channel: %w", err)
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create channel: %w", err)
+	}
+	return ch, nil
+}
+
+// acquireChannel checks out an idle pooled channel, opens a new one if the
+// pool has room, or waits for one to be returned. Closed channels are
+// replaced on checkout.
+func (r *RabbitMQProvider) acquireChannel(ctx context.Context) (*pooledChannel, error) {
+	for {
+		// Prefer an idle channel over opening a new one
+		select {
+		case pc := <-r.idleChannels:
+			if pc.channel.IsClosed() {
+				r.discardChannel(pc)
+				continue
+			}
+			return pc, nil
+		default:
 		}
 
-		r.channelPools[connIndex] = append(r.channelPools[connIndex], ch)
-		return ch, nil
-	}
+		select {
+		case pc := <-r.idleChannels:
+			if pc.channel.IsClosed() {
+				r.discardChannel(pc)
+				continue
+			}
+			return pc, nil
 
-	// Reuse existing channel (round-robin)
-	channelIndex := r.currentChannelIndex[connIndex]
-	r.currentChannelIndex[connIndex] = (r.currentChannelIndex[connIndex] + 1) % len(channelPool)
+		case r.channelSlots <- struct{}{}:
+			pc, err := r.openPooledChannel()
+			if err != nil {
+				<-r.channelSlots
+				return nil, err
+			}
+			return pc, nil
 
-	ch := channelPool[channelIndex]
-	if ch.IsClosed() {
-		// Recreate channel
-		newCh, err := conn.Channel()
-		if err != nil {
-			return nil, fmt.Errorf("failed to recreate channel: %w", err)
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for a channel: %w", ctx.Err())
 		}
-		r.channelPools[connIndex][channelIndex] = newCh
-		return newCh, nil
 	}
+}
 
-	return ch, nil
+// openPooledChannel opens a channel in confirm mode
+func (r *RabbitMQProvider) openPooledChannel() (*pooledChannel, error) {
+	ch, err := r.openChannel()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	return &pooledChannel{
+		channel: ch,
+		returns: ch.NotifyReturn(make(chan amqp.Return, 1)),
+	}, nil
 }
 
-// Publish publishes a message to an exchange
-func (r *RabbitMQProvider) Publish(ctx context.Context, exchange, routingKey string, body []byte) error {
-	ch, err := r.getChannel(ctx)
+// releaseChannel returns a checked-out channel to the pool. Channels closed by
+// an error while checked out are discarded.
+func (r *RabbitMQProvider) releaseChannel(pc *pooledChannel) {
+	if pc.channel.IsClosed() {
+		r.discardChannel(pc)
+		return
+	}
+
+	// Never blocks: there are at most as many open channels as slots
+	r.idleChannels <- pc
+}
+
+// discardChannel closes a pooled channel and frees its slot
+func (r *RabbitMQProvider) discardChannel(pc *pooledChannel) {
+	if !pc.channel.IsClosed() {
+		pc.channel.Close()
+	}
+	<-r.channelSlots
+}
+
+// withChannel runs fn on a checked-out channel
+func (r *RabbitMQProvider) withChannel(ctx context.Context, fn func(ch *amqp.Channel) error) error {
+	pc, err := r.acquireChannel(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get channel: %w", err)
+	}
+	defer r.releaseChannel(pc)
+
+	return fn(pc.channel)
+}
+
+// Publish publishes a message to an exchange and waits for the broker to
+// confirm it. Messages are published as mandatory, so a message no queue is
+// bound for fails with events.ErrUnroutable instead of being dropped.
+func (r *RabbitMQProvider) Publish(ctx context.Context, exchange, routingKey string, body []byte, headers map[string]string) error {
+	pc, err := r.acquireChannel(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get channel: %w", err)
 	}
 
-	err = ch.PublishWithContext(
+	confirmation, err := pc.channel.PublishWithDeferredConfirmWithContext(
 		ctx,
 		exchange,   // exchange
 		routingKey, // routing key
-		false,      // mandatory
+		true,       // mandatory
 		false,      // immediate
 		amqp.Publishing{
-			ContentType:  "application/json",
+			ContentType:  contentType(headers),
+			Headers:      toTable(headers),
 			Body:         body,
 			DeliveryMode: amqp.Persistent,
 			Timestamp:    time.Now(),
 		},
 	)
-
 	if err != nil {
+		r.discardChannel(pc)
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
 
+	waitCtx, cancel := context.WithTimeout(ctx, r.confirmTimeout)
+	defer cancel()
+
+	acked, err := confirmation.WaitContext(waitCtx)
+	if err != nil {
+		// The confirmation may still arrive, so the channel can't be reused
+		r.discardChannel(pc)
+		return fmt.Errorf("%w: %v", events.ErrPublishTimeout, err)
+	}
+
+	// The broker returns an unroutable message before confirming it
+	select {
+	case returned := <-pc.returns:
+		r.releaseChannel(pc)
+		return fmt.Errorf("%w: %s (exchange %q, routing key %q)", events.ErrUnroutable, returned.ReplyText, exchange, routingKey)
+	default:
+	}
+
+	r.releaseChannel(pc)
+
+	if !acked {
+		return fmt.Errorf("%w (exchange %q, routing key %q)", events.ErrPublishNacked, exchange, routingKey)
+	}
+
+	return nil
+}
+
+// PublishDelayed publishes a message to a per-delay queue whose messages
+// expire after the delay and are dead-lettered to the target queue
+func (r *RabbitMQProvider) PublishDelayed(ctx context.Context, queue string, body []byte, headers map[string]string, delay time.Duration) error {
+	delayQueue, err := r.declareDelayQueue(ctx, queue, delay)
+	if err != nil {
+		return err
+	}
+
+	// The default exchange routes directly to the queue named by the routing key
+	return r.Publish(ctx, "", delayQueue, body, headers)
+}
+
+// declareDelayQueue declares the delay queue for a target queue and delay
+func (r *RabbitMQProvider) declareDelayQueue(ctx context.Context, queue string, delay time.Duration) (string, error) {
+	name := fmt.Sprintf("%s.delay.%d", queue, delay.Milliseconds())
+	if _, ok := r.delayQueues.Load(name); ok {
+		return name, nil
+	}
+
+	err := r.withChannel(ctx, func(ch *amqp.Channel) error {
+		_, err := ch.QueueDeclare(
+			name,  // name
+			true,  // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queue,
+			},
+		)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to declare delay queue: %w", err)
+	}
+
+	r.delayQueues.Store(name, struct{}{})
+	logger.Info(ctx, "Delay queue declared", nil, "queue", name, "target_queue", queue, "delay", delay.String())
+	return name, nil
+}
+
+// Consume starts consuming messages from a queue. If the channel or
+// connection fails, the consumer re-subscribes once the provider is connected again.
+func (r *RabbitMQProvider) Consume(ctx context.Context, queue string, handler events.MessageHandler, opts events.ConsumeOptions) error {
+	opts = opts.Normalize()
+
+	// Create cancellable context for this consumer
+	consumerCtx, cancel := context.WithCancel(ctx)
+	c := &consumer{
+		ctx:   consumerCtx,
+		queue: queue,
+		opts:  opts,
+		pool:  events.NewWorkerPool(consumerCtx, opts, handler),
+		done:  make(chan struct{}),
+	}
+
+	if err := r.subscribe(c); err != nil {
+		cancel()
+		return err
+	}
+
+	r.mu.Lock()
+	r.consumerCancelFuncs = append(r.consumerCancelFuncs, cancel)
+	r.consumers = append(r.consumers, c)
+	r.mu.Unlock()
+
+	logger.Info(consumerCtx, "Started consuming from queue", nil, "queue", queue, "workers", fmt.Sprintf("%d", opts.Workers))
 	return nil
 }
 
-// Consume starts consuming messages from a queue
-func (r *RabbitMQProvider) Consume(ctx context.Context, queue string, handler events.MessageHandler) error {
-	ch, err := r.getChannel(ctx)
+// subscribe opens a dedicated channel for the consumer and starts its delivery loop
+func (r *RabbitMQProvider) subscribe(c *consumer) error {
+	ch, err := r.openChannel()
 	if err != nil {
 		return fmt.Errorf("failed to get channel for consumer: %w", err)
 	}
 
 	// Set QoS
 	err = ch.Qos(
-		10,    // prefetch count
-		0,     // prefetch size
-		false, // global
+		c.opts.Prefetch, // prefetch count
+		0,               // prefetch size
+		false,           // global
 	)
 	if err != nil {
+		ch.Close()
 		return fmt.Errorf("failed to set QoS: %w", err)
 	}
 
+	tag := fmt.Sprintf("%s-%d", c.queue, time.Now().UnixNano())
 	msgs, err := ch.Consume(
-		queue, // queue
-		"",    // consumer tag
-		false, // auto-ack
-		false, // exclusive
-		false, // no-local
-		false, // no-wait
-		nil,   // args
+		c.queue, // queue
+		tag,     // consumer tag
+		false,   // auto-ack
+		false,   // exclusive
+		false,   // no-local
+		false,   // no-wait
+		nil,     // args
 	)
 	if err != nil {
+		ch.Close()
 		return fmt.Errorf("failed to start consuming: %w", err)
 	}
 
-	// Create cancellable context for this consumer
-	consumerCtx, cancel := context.WithCancel(ctx)
-	r.mu.Lock()
-	r.consumerCancelFuncs = append(r.consumerCancelFuncs, cancel)
-	r.mu.Unlock()
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		ch.Close()
+		return nil
+	}
+	done := make(chan struct{})
+	if c.channel != nil && !c.channel.IsClosed() {
+		c.channel.Close()
+	}
+	c.channel = ch
+	c.tag = tag
+	c.done = done
+	c.mu.Unlock()
 
-	// Start consumer goroutine
-	go func() {
-		logger.Info(consumerCtx, "Started consuming from queue", nil, "queue", queue)
+	go r.deliver(c, msgs, done)
+	return nil
+}
 
-		for {
-			select {
-			case <-consumerCtx.Done():
-				logger.Info(consumerCtx, "Consumer stopped", nil, "queue", queue)
-				return
+// deliver hands messages to the worker pool until the delivery channel closes
+func (r *RabbitMQProvider) deliver(c *consumer, msgs <-chan amqp.Delivery, done chan struct{}) {
+	defer close(done)
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			logger.Info(c.ctx, "Consumer stopped", nil, "queue", c.queue)
+			return
 
-			case msg, ok := <-msgs:
-				if !ok {
-					logger.Warn(consumerCtx, "Message channel closed", nil, "queue", queue)
+		case msg, ok := <-msgs:
+			if !ok {
+				if c.isStopped() {
+					logger.Info(c.ctx, "Consumer stopped", nil, "queue", c.queue)
 					return
 				}
 
-				// Process message
-				err := handler(consumerCtx, msg.Body)
+				logger.Warn(c.ctx, "Message channel closed, re-subscribing", nil, "queue", c.queue)
+				go r.resubscribe(c)
+				return
+			}
+
+			// Process message on a worker
+			c.pool.Submit(events.Message{
+				RoutingKey: msg.RoutingKey,
+				Headers:    deliveryHeaders(msg),
+				Body:       msg.Body,
+			}, func(err error) {
+				// Acks for deliveries from a channel that has since closed fail;
+				// the broker redelivers those messages
 				if err != nil {
-					logger.Error(consumerCtx, "Failed to process message", err, "queue", queue)
+					logger.Error(c.ctx, "Failed to process message", err, "queue", c.queue)
 					msg.Nack(false, true) // Requeue on error
 				} else {
 					msg.Ack(false)
 				}
+			})
+		}
+	}
+}
+
+// resubscribe retries subscribing with jittered backoff until it succeeds or
+// the consumer is stopped
+func (r *RabbitMQProvider) resubscribe(c *consumer) {
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-time.After(reconnectBackoff(attempt)):
+		case <-c.ctx.Done():
+			return
+		}
+
+		if c.isStopped() {
+			return
+		}
+
+		if err := r.subscribe(c); err != nil {
+			logger.Warn(c.ctx, "Failed to re-subscribe consumer", nil, "queue", c.queue, "attempt", fmt.Sprintf("%d", attempt), "error", err.Error())
+			continue
+		}
+
+		logger.Info(c.ctx, "Consumer re-subscribed", nil, "queue", c.queue)
+		return
+	}
+}
+
+func (c *consumer) isStopped() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopped
+}
+
+// StopConsuming cancels all consumers and waits for in-flight messages to be handled
+func (r *RabbitMQProvider) StopConsuming(ctx context.Context) error {
+	r.mu.Lock()
+	consumers := r.consumers
+	r.consumers = nil
+	r.mu.Unlock()
+
+	dones := make([]chan struct{}, len(consumers))
+	for i, c := range consumers {
+		c.mu.Lock()
+		c.stopped = true
+		ch, tag := c.channel, c.tag
+		dones[i] = c.done
+		c.mu.Unlock()
+
+		// Stop the broker from sending more messages; the delivery channel closes once drained
+		if ch != nil && !ch.IsClosed() {
+			if err := ch.Cancel(tag, false); err != nil {
+				logger.Warn(ctx, "Failed to cancel consumer", nil, "consumer_tag", tag, "error", err.Error())
 			}
 		}
-	}()
+	}
+
+	for i, c := range consumers {
+		select {
+		case <-dones[i]:
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for consumer to stop: %w", ctx.Err())
+		}
+
+		if err := c.pool.Drain(ctx); err != nil {
+			return fmt.Errorf("timed out waiting for in-flight messages: %w", err)
+		}
+
+		// Acks are sent on the consumer's channel, so it is closed only once they are done
+		c.mu.Lock()
+		if c.channel != nil && !c.channel.IsClosed() {
+			c.channel.Close()
+		}
+		c.mu.Unlock()
+	}
 
 	return nil
 }
@@ -284,23 +727,36 @@ func (r *RabbitMQProvider) HealthCheck(ctx context.Context) error {
 	return fmt.Errorf("all connections are closed")
 }
 
-// DeclareExchange declares an exchange
+// DeclareExchange declares an exchange and records it for reconnects
 func (r *RabbitMQProvider) DeclareExchange(ctx context.Context, exchange, exchangeType string) error {
-	ch, err := r.getChannel(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get channel: %w", err)
+	if err := r.declareExchange(ctx, exchange, exchangeType); err != nil {
+		return err
 	}
 
-	err = ch.ExchangeDeclare(
-		exchange,     // name
-		exchangeType, // type
-		true,         // durable
-		false,        // auto-deleted
-		false,        // internal
-		false,        // no-wait
-		nil,          // arguments
-	)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range r.topology.exchanges {
+		if e.name == exchange {
+			return nil
+		}
+	}
+	r.topology.exchanges = append(r.topology.exchanges, exchangeDeclaration{name: exchange, kind: exchangeType})
+	return nil
+}
 
+func (r *RabbitMQProvider) declareExchange(ctx context.Context, exchange, exchangeType string) error {
+	err := r.withChannel(ctx, func(ch *amqp.Channel) error {
+		return ch.ExchangeDeclare(
+			exchange,     // name
+			exchangeType, // type
+			true,         // durable
+			false,        // auto-deleted
+			false,        // internal
+			false,        // no-wait
+			nil,          // arguments
+		)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to declare exchange: %w", err)
 	}
@@ -309,22 +765,39 @@ func (r *RabbitMQProvider) DeclareExchange(ctx context.Context, exchange, exchan
 	return nil
 }
 
-// DeclareQueue declares a queue
+// DeclareQueue declares a queue and records it for reconnects
 func (r *RabbitMQProvider) DeclareQueue(ctx context.Context, queue string) (string, error) {
-	ch, err := r.getChannel(ctx)
+	name, err := r.declareQueue(ctx, queue)
 	if err != nil {
-		return "", fmt.Errorf("failed to get channel: %w", err)
+		return "", err
 	}
 
-	q, err := ch.QueueDeclare(
-		queue, // name
-		true,  // durable
-		false, // delete when unused
-		false, // exclusive
-		false, // no-wait
-		nil,   // arguments
-	)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, q := range r.topology.queues {
+		if q == name {
+			return name, nil
+		}
+	}
+	r.topology.queues = append(r.topology.queues, name)
+	return name, nil
+}
 
+func (r *RabbitMQProvider) declareQueue(ctx context.Context, queue string) (string, error) {
+	var q amqp.Queue
+	err := r.withChannel(ctx, func(ch *amqp.Channel) error {
+		var err error
+		q, err = ch.QueueDeclare(
+			queue, // name
+			true,  // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			nil,   // arguments
+		)
+		return err
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to declare queue: %w", err)
 	}
@@ -333,21 +806,36 @@ func (r *RabbitMQProvider) DeclareQueue(ctx context.Context, queue string) (stri
 	return q.Name, nil
 }
 
-// BindQueue binds a queue to an exchange
+// BindQueue binds a queue to an exchange and records the binding for reconnects
 func (r *RabbitMQProvider) BindQueue(ctx context.Context, queue, exchange, routingKey string) error {
-	ch, err := r.getChannel(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get channel: %w", err)
+	if err := r.bindQueue(ctx, queue, exchange, routingKey); err != nil {
+		return err
 	}
 
-	err = ch.QueueBind(
-		queue,      // queue name
-		routingKey, // routing key
-		exchange,   // exchange
-		false,      // no-wait
-		nil,        // arguments
-	)
+	binding := bindingDeclaration{queue: queue, exchange: exchange, routingKey: routingKey}
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, b := range r.topology.bindings {
+		if b == binding {
+			return nil
+		}
+	}
+	r.topology.bindings = append(r.topology.bindings, binding)
+	return nil
+}
+
+func (r *RabbitMQProvider) bindQueue(ctx context.Context, queue, exchange, routingKey string) error {
+	err := r.withChannel(ctx, func(ch *amqp.Channel) error {
+		return ch.QueueBind(
+			queue,      // queue name
+			routingKey, // routing key
+			exchange,   // exchange
+			false,      // no-wait
+			nil,        // arguments
+		)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to bind queue: %w", err)
 	}
@@ -355,3 +843,71 @@ func (r *RabbitMQProvider) BindQueue(ctx context.Context, queue, exchange, routi
 	logger.Info(ctx, "Queue bound to exchange", nil, "queue", queue, "exchange", exchange, "routing_key", routingKey)
 	return nil
 }
+
+// PurgeQueue removes all ready messages from a queue
+func (r *RabbitMQProvider) PurgeQueue(ctx context.Context, queue string) (int, error) {
+	var count int
+	err := r.withChannel(ctx, func(ch *amqp.Channel) error {
+		var err error
+		count, err = ch.QueuePurge(queue, false)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge queue: %w", err)
+	}
+
+	logger.Info(ctx, "Queue purged", nil, "queue", queue, "messages", fmt.Sprintf("%d", count))
+	return count, nil
+}
+
+// contentType returns the content-type header, which is sent as the AMQP
+// content type property rather than a header
+func contentType(headers map[string]string) string {
+	if ct := headers[events.HeaderContentType]; ct != "" {
+		return ct
+	}
+	return events.ContentTypeJSON
+}
+
+// toTable converts string headers to an AMQP table
+func toTable(headers map[string]string) amqp.Table {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	table := make(amqp.Table, len(headers))
+	for k, v := range headers {
+		if k == events.HeaderContentType {
+			continue
+		}
+		table[k] = v
+	}
+	return table
+}
+
+// deliveryHeaders returns the headers of a delivery, including its content type
+func deliveryHeaders(msg amqp.Delivery) map[string]string {
+	headers := fromTable(msg.Headers)
+	if msg.ContentType != "" {
+		headers[events.HeaderContentType] = msg.ContentType
+	}
+	return headers
+}
+
+// fromTable converts AMQP headers to strings, skipping nested values
+func fromTable(table amqp.Table) map[string]string {
+	headers := make(map[string]string, len(table))
+	for k, v := range table {
+		switch v := v.(type) {
+		case string:
+			headers[k] = v
+		case []byte:
+			headers[k] = string(v)
+		case amqp.Table, []interface{}:
+			// x-death and similar broker metadata
+		default:
+			headers[k] = fmt.Sprint(v)
+		}
+	}
+	return headers
+}
@@ -0,0 +1,167 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rbac-service/internal/events"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestProvider connects a provider to a fake broker
+func newTestProvider(t *testing.T, maxConnections, maxChannelsPerConn int) (*RabbitMQProvider, *fakeBroker) {
+	t.Helper()
+
+	broker := newFakeBroker(t)
+	provider, err := NewRabbitMQProvider(broker.url(), maxConnections, maxChannelsPerConn, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { provider.Close() })
+
+	return provider, broker
+}
+
+// publishChannels returns the distinct channels publishes arrived on
+func publishChannels(publishes []brokerPublish) map[brokerChannel]int {
+	channels := make(map[brokerChannel]int)
+	for _, p := range publishes {
+		channels[p.brokerChannel]++
+	}
+	return channels
+}
+
+func TestPublishConcurrentlySharesBoundedPool(t *testing.T) {
+	provider, broker := newTestProvider(t, 2, 2)
+
+	const publishers = 50
+	errs := make([]error, publishers)
+	var wg sync.WaitGroup
+	for i := 0; i < publishers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// Every fifth message is returned, which must fail only that publish
+			routingKey := fmt.Sprintf("thing.%d", i)
+			if i%5 == 0 {
+				routingKey = unroutableKey
+			}
+			errs[i] = provider.Publish(context.Background(), "events", routingKey, []byte(`{}`), nil)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if i%5 == 0 {
+			if !errors.Is(err, events.ErrUnroutable) {
+				t.Errorf("publish %d: err = %v, want ErrUnroutable", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("publish %d: %v", i, err)
+		}
+	}
+
+	publishes := broker.recordedPublishes()
+	if len(publishes) != publishers {
+		t.Fatalf("broker received %d publishes, want %d", len(publishes), publishers)
+	}
+	if n := len(publishChannels(publishes)); n > 4 {
+		t.Fatalf("publishes used %d channels, want at most 4", n)
+	}
+	if n := len(provider.idleChannels); n != len(provider.channelSlots) {
+		t.Fatalf("%d idle channels for %d open, want all returned", n, len(provider.channelSlots))
+	}
+}
+
+func TestPublishReplacesClosedChannel(t *testing.T) {
+	provider, broker := newTestProvider(t, 1, 1)
+	ctx := context.Background()
+
+	if err := provider.Publish(ctx, "events", "thing.1", []byte(`{}`), nil); err != nil {
+		t.Fatal(err)
+	}
+	first := broker.recordedPublishes()[0].brokerChannel
+
+	// The broker closes the idle channel, as after a channel error
+	if err := broker.closeChannel(first); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for broker.openChannels(first.conn) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the channel to close")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// With a single slot, publishing only works if the closed channel frees it
+	if err := provider.Publish(ctx, "events", "thing.2", []byte(`{}`), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	second := broker.recordedPublishes()[1].brokerChannel
+	if second == first {
+		t.Fatalf("publish used closed channel %d", first.channel)
+	}
+	if n := len(provider.channelSlots); n != 1 {
+		t.Fatalf("%d channel slots taken, want 1", n)
+	}
+}
+
+func TestConsumerChannelsStayOutOfPublishPool(t *testing.T) {
+	provider, broker := newTestProvider(t, 1, 2)
+	ctx := context.Background()
+
+	handler := func(ctx context.Context, msg events.Message) error { return nil }
+	if err := provider.Consume(ctx, "things", handler, events.ConsumeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.Consume(ctx, "other-things", handler, events.ConsumeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := provider.Publish(ctx, "events", fmt.Sprintf("thing.%d", i), []byte(`{}`), nil); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	consumers := broker.recordedConsumers()
+	if len(consumers) != 2 {
+		t.Fatalf("broker has %d consumers, want 2", len(consumers))
+	}
+	channels := publishChannels(broker.recordedPublishes())
+	for _, c := range consumers {
+		if channels[c] > 0 {
+			t.Fatalf("%d publishes used consumer channel %d", channels[c], c.channel)
+		}
+	}
+
+	// Consumer channels take none of the publish pool's slots
+	if n := len(channels); n > 2 {
+		t.Fatalf("publishes used %d channels, want at most 2", n)
+	}
+	if n := len(provider.channelSlots); n != len(channels) {
+		t.Fatalf("%d channel slots taken for %d publish channels", n, len(channels))
+	}
+
+	stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := provider.StopConsuming(stopCtx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,319 @@
+package events
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/model"
+	"sync"
+	"time"
+)
+
+// Default retention, used for anything the configuration leaves out
+const (
+	DefaultRetentionInterval  = time.Hour
+	DefaultCompletedRetention = 7 * 24 * time.Hour
+	DefaultFailedRetention    = 30 * 24 * time.Hour
+
+	retentionBatchSize = 1000
+)
+
+// Statuses pruned by the retention job. Pending and processing events are
+// never pruned.
+var (
+	publishedCompletedStatuses = []string{model.StatusPublished}
+	publishedFailedStatuses    = []string{model.StatusUnroutable, model.StatusFailed}
+	consumedCompletedStatuses  = []string{model.StatusCompleted}
+	consumedFailedStatuses     = []string{model.StatusFailed, model.StatusDeadLettered, model.StatusReplayed}
+)
+
+// RetentionConfig controls how long finished events are kept in the event
+// audit tables. An age of zero keeps those events forever.
+type RetentionConfig struct {
+	Interval time.Duration
+	// CompletedAge applies to published and completed events
+	CompletedAge time.Duration
+	// FailedAge applies to unroutable, failed, dead-lettered and replayed events
+	FailedAge time.Duration
+	// ArchiveDir, when set, receives pruned rows as gzip-compressed JSONL
+	// files before they are deleted. Archives may hold the same row twice;
+	// see writeArchive.
+	ArchiveDir string
+}
+
+// DefaultRetentionConfig returns the built-in retention: completed events for
+// a week, failed events for 30 days, no archive
+func DefaultRetentionConfig() RetentionConfig {
+	return RetentionConfig{
+		Interval:     DefaultRetentionInterval,
+		CompletedAge: DefaultCompletedRetention,
+		FailedAge:    DefaultFailedRetention,
+	}
+}
+
+// Validate checks the retention can be run
+func (c RetentionConfig) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("retention interval must be positive")
+	}
+	if c.CompletedAge < 0 || c.FailedAge < 0 {
+		return fmt.Errorf("retention ages must not be negative")
+	}
+	return nil
+}
+
+// Enabled reports whether any events are pruned
+func (c RetentionConfig) Enabled() bool {
+	return c.CompletedAge > 0 || c.FailedAge > 0
+}
+
+// EventRetention periodically deletes finished events from the audit tables,
+// archiving them first if an archive directory is configured. Replicas can
+// run it concurrently; rows locked by one are skipped by the others.
+type EventRetention struct {
+	auditRepo AuditStore
+	config    RetentionConfig
+	stopChan  chan struct{}
+	doneChan  chan struct{}
+
+	mu    sync.Mutex
+	stats model.EventRetentionStats
+}
+
+// NewEventRetention creates a new retention job
+func NewEventRetention(auditRepo AuditStore, config RetentionConfig) *EventRetention {
+	return &EventRetention{
+		auditRepo: auditRepo,
+		config:    config,
+		stopChan:  make(chan struct{}),
+		doneChan:  make(chan struct{}),
+		stats:     model.EventRetentionStats{Enabled: true},
+	}
+}
+
+// Start prunes events on every interval until Stop is called
+func (r *EventRetention) Start(ctx context.Context) {
+	defer close(r.doneChan)
+
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	logger.Info(ctx, "Event retention started", nil,
+		"interval", r.config.Interval.String(),
+		"completed_age", r.config.CompletedAge.String(),
+		"failed_age", r.config.FailedAge.String(),
+	)
+
+	for {
+		r.Run(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-r.stopChan:
+			logger.Info(ctx, "Event retention stopped", nil)
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Stop stops the retention job and waits for the current run to finish
+func (r *EventRetention) Stop() {
+	close(r.stopChan)
+	<-r.doneChan
+}
+
+// Stats returns the rows pruned and archived since the service started
+func (r *EventRetention) Stats() model.EventRetentionStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// Run prunes every expired event once
+func (r *EventRetention) Run(ctx context.Context) {
+	started := time.Now()
+	var run model.EventRetentionStats
+	var runErr error
+
+	if r.config.CompletedAge > 0 {
+		cutoff := started.Add(-r.config.CompletedAge)
+		if err := r.prunePublished(ctx, publishedCompletedStatuses, cutoff, &run.Published.Completed, &run.Archived); err != nil {
+			runErr = err
+		}
+		if err := r.pruneConsumed(ctx, consumedCompletedStatuses, cutoff, &run.Consumed.Completed, &run.Archived); err != nil {
+			runErr = err
+		}
+	}
+
+	if r.config.FailedAge > 0 {
+		cutoff := started.Add(-r.config.FailedAge)
+		if err := r.prunePublished(ctx, publishedFailedStatuses, cutoff, &run.Published.Failed, &run.Archived); err != nil {
+			runErr = err
+		}
+		if err := r.pruneConsumed(ctx, consumedFailedStatuses, cutoff, &run.Consumed.Failed, &run.Archived); err != nil {
+			runErr = err
+		}
+	}
+
+	r.mu.Lock()
+	r.stats.Runs++
+	r.stats.LastRunAt = &started
+	r.stats.LastError = ""
+	if runErr != nil {
+		r.stats.LastError = runErr.Error()
+	}
+	r.stats.Published.Completed += run.Published.Completed
+	r.stats.Published.Failed += run.Published.Failed
+	r.stats.Consumed.Completed += run.Consumed.Completed
+	r.stats.Consumed.Failed += run.Consumed.Failed
+	r.stats.Archived += run.Archived
+	r.mu.Unlock()
+
+	if runErr != nil {
+		logger.Error(ctx, "Event retention run failed", runErr)
+	}
+
+	pruned := run.Published.Completed + run.Published.Failed + run.Consumed.Completed + run.Consumed.Failed
+	if pruned > 0 {
+		logger.Info(ctx, "Pruned expired events", nil,
+			"published_completed", fmt.Sprintf("%d", run.Published.Completed),
+			"published_failed", fmt.Sprintf("%d", run.Published.Failed),
+			"consumed_completed", fmt.Sprintf("%d", run.Consumed.Completed),
+			"consumed_failed", fmt.Sprintf("%d", run.Consumed.Failed),
+			"archived", fmt.Sprintf("%d", run.Archived),
+			"duration", time.Since(started).String(),
+		)
+	}
+}
+
+// prunePublished deletes expired outbox entries in batches until none are left
+func (r *EventRetention) prunePublished(ctx context.Context, statuses []string, before time.Time, pruned, archived *int64) error {
+	var archive func([]model.PublishedEvent) error
+	if r.config.ArchiveDir != "" {
+		archive = func(events []model.PublishedEvent) error {
+			records := make([]interface{}, len(events))
+			for i := range events {
+				records[i] = events[i].Record()
+			}
+			return r.writeArchive("published_events", records)
+		}
+	}
+
+	for {
+		n, err := r.auditRepo.PrunePublishedEvents(ctx, statuses, before, retentionBatchSize, archive)
+		if err != nil {
+			return fmt.Errorf("failed to prune published events: %w", err)
+		}
+
+		*pruned += int64(n)
+		if archive != nil {
+			*archived += int64(n)
+		}
+
+		if n < retentionBatchSize {
+			return nil
+		}
+		if r.stopping(ctx) {
+			return nil
+		}
+	}
+}
+
+// pruneConsumed deletes expired consumed events in batches until none are left
+func (r *EventRetention) pruneConsumed(ctx context.Context, statuses []string, before time.Time, pruned, archived *int64) error {
+	var archive func([]model.ConsumedEvent) error
+	if r.config.ArchiveDir != "" {
+		archive = func(events []model.ConsumedEvent) error {
+			records := make([]interface{}, len(events))
+			for i := range events {
+				records[i] = events[i].Record()
+			}
+			return r.writeArchive("consumed_events", records)
+		}
+	}
+
+	for {
+		n, err := r.auditRepo.PruneConsumedEvents(ctx, statuses, before, retentionBatchSize, archive)
+		if err != nil {
+			return fmt.Errorf("failed to prune consumed events: %w", err)
+		}
+
+		*pruned += int64(n)
+		if archive != nil {
+			*archived += int64(n)
+		}
+
+		if n < retentionBatchSize {
+			return nil
+		}
+		if r.stopping(ctx) {
+			return nil
+		}
+	}
+}
+
+// stopping reports whether the job should give up the current run
+func (r *EventRetention) stopping(ctx context.Context) bool {
+	select {
+	case <-r.stopChan:
+		return true
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// writeArchive writes a batch of records to a new gzip-compressed JSONL file,
+// named after the table and the time, in the archive directory. The file is
+// synced before it gets its final name, so a file that exists is complete.
+// It is written before the deletion commits: if the commit fails or the
+// process dies first, the rows stay in the table and the next run archives
+// them again in another file. Readers of the archive should deduplicate by id.
+func (r *EventRetention) writeArchive(table string, records []interface{}) (err error) {
+	if err := os.MkdirAll(r.config.ArchiveDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	name := filepath.Join(r.config.ArchiveDir, fmt.Sprintf("%s-%s.jsonl.gz", table, time.Now().UTC().Format("20060102T150405.000000000Z")))
+	tmp := name + ".tmp"
+
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	gz := gzip.NewWriter(file)
+	encoder := json.NewEncoder(gz)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to write archive file: %w", err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write archive file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync archive file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close archive file: %w", err)
+	}
+
+	if err := os.Rename(tmp, name); err != nil {
+		return fmt.Errorf("failed to rename archive file: %w", err)
+	}
+	return nil
+}
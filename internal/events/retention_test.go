@@ -0,0 +1,246 @@
+package events_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rbac-service/internal/events"
+	"rbac-service/internal/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// addPublished stores count outbox records with the given status, last
+// updated at updatedAt
+func (s *memoryAuditStore) addPublished(prefix, status string, updatedAt time.Time, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < count; i++ {
+		id := fmt.Sprintf("%s-%d", prefix, i)
+		s.published[id] = &model.PublishedEvent{
+			ID:        id,
+			EventType: testThingType,
+			Payload:   json.RawMessage(`{"thing_id":"thing-1"}`),
+			Status:    status,
+			CreatedAt: updatedAt,
+			UpdatedAt: updatedAt,
+		}
+	}
+}
+
+// addConsumed stores count consumed event records with the given status, last
+// updated at updatedAt
+func (s *memoryAuditStore) addConsumed(prefix, status string, updatedAt time.Time, count int) {
+	for i := 0; i < count; i++ {
+		s.setConsumed(fmt.Sprintf("%s-%d", prefix, i), status, updatedAt)
+	}
+}
+
+func (s *memoryAuditStore) counts() (published, consumed int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.published), len(s.consumed)
+}
+
+func testRetentionConfig() events.RetentionConfig {
+	return events.RetentionConfig{
+		Interval:     time.Hour,
+		CompletedAge: 24 * time.Hour,
+		FailedAge:    7 * 24 * time.Hour,
+	}
+}
+
+func TestEventRetentionPrunesExpiredEventsInBatches(t *testing.T) {
+	store := newMemoryAuditStore()
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	ancient := now.Add(-30 * 24 * time.Hour)
+
+	store.addPublished("published-old", model.StatusPublished, old, 2500)
+	store.addPublished("published-recent", model.StatusPublished, now, 10)
+	store.addPublished("unroutable-ancient", model.StatusUnroutable, ancient, 3)
+	store.addPublished("failed-old", model.StatusFailed, old, 4)
+	store.addPublished("pending-ancient", model.StatusPending, ancient, 5)
+	store.addConsumed("completed-old", model.StatusCompleted, old, 1200)
+	store.addConsumed("dead-lettered-ancient", model.StatusDeadLettered, ancient, 6)
+	store.addConsumed("processing-ancient", model.StatusProcessing, ancient, 7)
+
+	retention := events.NewEventRetention(store, testRetentionConfig())
+	retention.Run(context.Background())
+
+	stats := retention.Stats()
+	if stats.Published.Completed != 2500 || stats.Published.Failed != 3 {
+		t.Fatalf("published pruned = %+v, want 2500 completed and 3 failed", stats.Published)
+	}
+	if stats.Consumed.Completed != 1200 || stats.Consumed.Failed != 6 {
+		t.Fatalf("consumed pruned = %+v, want 1200 completed and 6 failed", stats.Consumed)
+	}
+	if stats.Runs != 1 || stats.LastRunAt == nil || stats.LastError != "" || stats.Archived != 0 {
+		t.Fatalf("stats = %+v, want one successful run without archiving", stats)
+	}
+
+	// 3 + 1 + 2 + 1 batches of at most 1000 rows
+	if store.pruneCalls != 7 {
+		t.Fatalf("pruned %d batches, want 7", store.pruneCalls)
+	}
+
+	// Recent, not yet expired failures, pending and processing events are kept
+	published, consumed := store.counts()
+	if published != 10+4+5 || consumed != 7 {
+		t.Fatalf("kept %d published and %d consumed events, want 19 and 7", published, consumed)
+	}
+}
+
+func TestEventRetentionAccumulatesStats(t *testing.T) {
+	store := newMemoryAuditStore()
+	old := time.Now().Add(-48 * time.Hour)
+	retention := events.NewEventRetention(store, testRetentionConfig())
+
+	store.addPublished("first", model.StatusPublished, old, 5)
+	retention.Run(context.Background())
+
+	store.addPublished("second", model.StatusPublished, old, 3)
+	store.addConsumed("second", model.StatusCompleted, old, 2)
+	retention.Run(context.Background())
+
+	stats := retention.Stats()
+	if stats.Runs != 2 || stats.Published.Completed != 8 || stats.Consumed.Completed != 2 {
+		t.Fatalf("stats = %+v, want 2 runs pruning 8 published and 2 consumed events", stats)
+	}
+}
+
+func TestEventRetentionKeepsEventsWithZeroAge(t *testing.T) {
+	store := newMemoryAuditStore()
+	ancient := time.Now().Add(-365 * 24 * time.Hour)
+	store.addPublished("published", model.StatusPublished, ancient, 2)
+	store.addPublished("failed", model.StatusFailed, ancient, 3)
+
+	config := testRetentionConfig()
+	config.CompletedAge = 0
+	retention := events.NewEventRetention(store, config)
+	retention.Run(context.Background())
+
+	stats := retention.Stats()
+	if stats.Published.Completed != 0 || stats.Published.Failed != 3 {
+		t.Fatalf("published pruned = %+v, want only the 3 failed events", stats.Published)
+	}
+}
+
+// readArchives returns the ids in the archive files of a table, failing on
+// leftover temporary files
+func readArchives(t *testing.T, dir, table string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			t.Fatalf("temporary archive file %s left behind", name)
+		}
+		if !strings.HasPrefix(name, table+"-") {
+			continue
+		}
+		if !strings.HasSuffix(name, ".jsonl.gz") {
+			t.Fatalf("archive file %s is not .jsonl.gz", name)
+		}
+
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		scanner := bufio.NewScanner(gz)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var record struct {
+				ID        string          `json:"id"`
+				EventType string          `json:"event_type"`
+				Status    string          `json:"status"`
+				Payload   json.RawMessage `json:"payload"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				t.Fatalf("archive line %q: %v", scanner.Text(), err)
+			}
+			if record.ID == "" || record.EventType == "" || record.Status == "" {
+				t.Fatalf("archive line %q is missing fields", scanner.Text())
+			}
+			ids = append(ids, record.ID)
+		}
+		if err := scanner.Err(); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+	}
+	return ids
+}
+
+func TestEventRetentionArchivesPrunedEvents(t *testing.T) {
+	store := newMemoryAuditStore()
+	old := time.Now().Add(-48 * time.Hour)
+	store.addPublished("published", model.StatusPublished, old, 1500)
+	store.addConsumed("consumed", model.StatusCompleted, old, 20)
+
+	config := testRetentionConfig()
+	config.ArchiveDir = filepath.Join(t.TempDir(), "archive")
+	retention := events.NewEventRetention(store, config)
+	retention.Run(context.Background())
+
+	if stats := retention.Stats(); stats.Archived != 1520 {
+		t.Fatalf("archived %d events, want 1520", stats.Archived)
+	}
+
+	published := readArchives(t, config.ArchiveDir, "published_events")
+	if len(published) != 1500 {
+		t.Fatalf("published archives hold %d events, want 1500", len(published))
+	}
+	seen := make(map[string]bool, len(published))
+	for _, id := range published {
+		if seen[id] {
+			t.Fatalf("event %s archived twice", id)
+		}
+		seen[id] = true
+	}
+
+	if consumed := readArchives(t, config.ArchiveDir, "consumed_events"); len(consumed) != 20 {
+		t.Fatalf("consumed archives hold %d events, want 20", len(consumed))
+	}
+}
+
+func TestEventRetentionKeepsEventsWhenArchiveFails(t *testing.T) {
+	store := newMemoryAuditStore()
+	old := time.Now().Add(-48 * time.Hour)
+	store.addPublished("published", model.StatusPublished, old, 5)
+
+	// A file where the archive directory should be
+	config := testRetentionConfig()
+	config.ArchiveDir = filepath.Join(t.TempDir(), "archive")
+	if err := os.WriteFile(config.ArchiveDir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	retention := events.NewEventRetention(store, config)
+	retention.Run(context.Background())
+
+	stats := retention.Stats()
+	if stats.LastError == "" || stats.Published.Completed != 0 || stats.Archived != 0 {
+		t.Fatalf("stats = %+v, want a failed run that pruned nothing", stats)
+	}
+	if published, _ := store.counts(); published != 5 {
+		t.Fatalf("kept %d events, want 5", published)
+	}
+}
@@ -21,17 +21,36 @@ func NewEventRouter() *EventRouter {
 	}
 }
 
+// TypedEventHandler handles an event whose payload has been decoded into T
+type TypedEventHandler[T any] func(ctx context.Context, event model.Event, payload T) error
+
 // Register registers a handler for an event type
 func (r *EventRouter) Register(eventType string, handler EventHandler) {
 	r.handlers[eventType] = handler
 }
 
-// Dispatch routes an event to its handler
+// Register registers a handler that receives the event payload decoded into T.
+// A payload that can't be decoded is rejected with ErrInvalidPayload.
+func Register[T any](r *EventRouter, eventType string, handler TypedEventHandler[T]) {
+	r.Register(eventType, func(ctx context.Context, event model.Event) error {
+		payload, err := DecodePayload[T](event.Payload)
+		if err != nil {
+			return err
+		}
+		return handler(ctx, event, payload)
+	})
+}
+
+// Dispatch validates an event against the schema of its type and routes it to its handler
 func (r *EventRouter) Dispatch(ctx context.Context, event model.Event) error {
 	handler, exists := r.handlers[event.Type]
 	if !exists {
 		return fmt.Errorf("no handler registered for event type: %s", event.Type)
 	}
 
+	if err := ValidatePayload(event.Type, event.Payload); err != nil {
+		return err
+	}
+
 	return handler(ctx, event)
 }
@@ -0,0 +1,119 @@
+package events
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// schemaFiles holds a JSON Schema per event type, named <event type>.json
+//
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// ErrInvalidPayload is returned when an event payload does not match its
+// schema or handler type. Such events are not retried.
+var ErrInvalidPayload = errors.New("invalid event payload")
+
+// schemas are the compiled event schemas keyed by event type
+var schemas = mustCompileSchemas()
+
+func mustCompileSchemas() map[string]*jsonschema.Schema {
+	files, err := schemaFiles.ReadDir("schemas")
+	if err != nil {
+		panic(fmt.Sprintf("failed to read event schemas: %v", err))
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat = true
+
+	compiled := make(map[string]*jsonschema.Schema, len(files))
+	for _, file := range files {
+		name := path.Join("schemas", file.Name())
+		data, err := schemaFiles.ReadFile(name)
+		if err != nil {
+			panic(fmt.Sprintf("failed to read event schema %s: %v", name, err))
+		}
+
+		url := "file:///" + name
+		if err := compiler.AddResource(url, bytes.NewReader(data)); err != nil {
+			panic(fmt.Sprintf("failed to load event schema %s: %v", name, err))
+		}
+
+		schema, err := compiler.Compile(url)
+		if err != nil {
+			panic(fmt.Sprintf("failed to compile event schema %s: %v", name, err))
+		}
+		compiled[strings.TrimSuffix(file.Name(), ".json")] = schema
+	}
+
+	return compiled
+}
+
+// ValidatePayload checks an event payload against the schema of its event
+// type. Event types without a schema are accepted.
+func ValidatePayload(eventType string, payload interface{}) error {
+	schema, ok := schemas[eventType]
+	if !ok {
+		return nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	if err := schema.Validate(value); err != nil {
+		var validationErr *jsonschema.ValidationError
+		if errors.As(err, &validationErr) {
+			return fmt.Errorf("%w: %s", ErrInvalidPayload, strings.Join(validationMessages(validationErr), "; "))
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	return nil
+}
+
+// validationMessages flattens a validation error into one message per failed field
+func validationMessages(err *jsonschema.ValidationError) []string {
+	if len(err.Causes) == 0 {
+		location := err.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		return []string{location + ": " + err.Message}
+	}
+
+	var messages []string
+	for _, cause := range err.Causes {
+		messages = append(messages, validationMessages(cause)...)
+	}
+	return messages
+}
+
+// DecodePayload decodes an event payload into T
+func DecodePayload[T any](payload interface{}) (T, error) {
+	var decoded T
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return decoded, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return decoded, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	return decoded, nil
+}
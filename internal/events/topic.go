@@ -0,0 +1,36 @@
+package events
+
+import "strings"
+
+// MatchTopic reports whether a routing key matches a topic binding pattern,
+// where "*" matches exactly one word and "#" matches zero or more words
+func MatchTopic(pattern, routingKey string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(routingKey, "."))
+}
+
+func matchWords(pattern, words []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case "#":
+			// Try every possible number of words for "#"
+			for i := 0; i <= len(words); i++ {
+				if matchWords(pattern[1:], words[i:]) {
+					return true
+				}
+			}
+			return false
+		case "*":
+			if len(words) == 0 {
+				return false
+			}
+		default:
+			if len(words) == 0 || pattern[0] != words[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		words = words[1:]
+	}
+
+	return len(words) == 0
+}
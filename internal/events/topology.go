@@ -0,0 +1,207 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Default topology, used for anything the configuration leaves out
+const (
+	DefaultExchangeName           = "rbac_permissions"
+	DefaultQueueName              = "permissions"
+	DefaultQueueBinding           = "rbac.*.*.request"
+	DefaultDeadLetterExchangeName = "rbac_permissions.dlx"
+	DefaultDeadLetterQueueName    = "permissions.dead_letter"
+	DefaultMaxRetries             = 3
+	DefaultHealthCheckInterval    = 30 * time.Second
+)
+
+// Topology describes the exchanges and queues of the event system and how
+// their consumers behave. Names are given without the prefix.
+type Topology struct {
+	// Prefix is prepended to every exchange and queue name, so several
+	// deployments can share one broker
+	Prefix              string
+	Exchange            string
+	DeadLetterExchange  string
+	DeadLetterQueue     string
+	MaxRetries          int
+	Workers             int
+	HealthCheckInterval time.Duration
+	Queues              []QueueTopology
+}
+
+// QueueTopology describes a consumed queue. Workers falls back to the
+// topology's value when zero and MaxRetries when nil.
+type QueueTopology struct {
+	Name       string
+	Bindings   []string
+	Workers    int
+	MaxRetries *int
+}
+
+// topologyFile is the JSON form of a Topology
+type topologyFile struct {
+	Prefix              string              `json:"prefix"`
+	Exchange            string              `json:"exchange"`
+	DeadLetterExchange  string              `json:"dead_letter_exchange"`
+	DeadLetterQueue     string              `json:"dead_letter_queue"`
+	MaxRetries          *int                `json:"max_retries"`
+	Workers             int                 `json:"workers"`
+	HealthCheckInterval string              `json:"health_check_interval"`
+	Queues              []queueTopologyFile `json:"queues"`
+}
+
+type queueTopologyFile struct {
+	Name       string   `json:"name"`
+	Bindings   []string `json:"bindings"`
+	Workers    int      `json:"workers"`
+	MaxRetries *int     `json:"max_retries"`
+}
+
+// DefaultTopology returns the built-in topology: one queue bound to request events
+func DefaultTopology() Topology {
+	return Topology{
+		Exchange:            DefaultExchangeName,
+		DeadLetterExchange:  DefaultDeadLetterExchangeName,
+		DeadLetterQueue:     DefaultDeadLetterQueueName,
+		MaxRetries:          DefaultMaxRetries,
+		Workers:             1,
+		HealthCheckInterval: DefaultHealthCheckInterval,
+		Queues: []QueueTopology{
+			{Name: DefaultQueueName, Bindings: []string{DefaultQueueBinding}},
+		},
+	}
+}
+
+// LoadTopologyFile reads a JSON topology file over the default topology.
+// Fields missing from the file keep their defaults.
+func LoadTopologyFile(path string) (Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Topology{}, fmt.Errorf("failed to read topology file: %w", err)
+	}
+
+	var file topologyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Topology{}, fmt.Errorf("failed to parse topology file: %w", err)
+	}
+
+	topology := DefaultTopology()
+	topology.Prefix = file.Prefix
+	if file.Exchange != "" {
+		topology.Exchange = file.Exchange
+	}
+	if file.DeadLetterExchange != "" {
+		topology.DeadLetterExchange = file.DeadLetterExchange
+	}
+	if file.DeadLetterQueue != "" {
+		topology.DeadLetterQueue = file.DeadLetterQueue
+	}
+	if file.MaxRetries != nil {
+		topology.MaxRetries = *file.MaxRetries
+	}
+	if file.Workers != 0 {
+		topology.Workers = file.Workers
+	}
+	if file.HealthCheckInterval != "" {
+		topology.HealthCheckInterval, err = time.ParseDuration(file.HealthCheckInterval)
+		if err != nil {
+			return Topology{}, fmt.Errorf("invalid health_check_interval in topology file: %w", err)
+		}
+	}
+
+	if len(file.Queues) > 0 {
+		topology.Queues = make([]QueueTopology, len(file.Queues))
+		for i, q := range file.Queues {
+			topology.Queues[i] = QueueTopology{
+				Name:       q.Name,
+				Bindings:   q.Bindings,
+				Workers:    q.Workers,
+				MaxRetries: q.MaxRetries,
+			}
+		}
+	}
+
+	return topology, nil
+}
+
+// Validate checks that the topology can be declared and consumed
+func (t Topology) Validate() error {
+	if t.Exchange == "" {
+		return fmt.Errorf("topology has no exchange")
+	}
+	if t.DeadLetterExchange == "" || t.DeadLetterQueue == "" {
+		return fmt.Errorf("topology has no dead-letter exchange or queue")
+	}
+	if t.MaxRetries < 0 {
+		return fmt.Errorf("topology max retries must not be negative")
+	}
+	if t.HealthCheckInterval <= 0 {
+		return fmt.Errorf("topology health check interval must be positive")
+	}
+	if len(t.Queues) == 0 {
+		return fmt.Errorf("topology has no queues")
+	}
+
+	seen := make(map[string]bool)
+	for _, q := range t.Queues {
+		if q.Name == "" {
+			return fmt.Errorf("topology has a queue without a name")
+		}
+		if seen[q.Name] || q.Name == t.DeadLetterQueue {
+			return fmt.Errorf("topology declares queue %s twice", q.Name)
+		}
+		seen[q.Name] = true
+
+		if len(q.Bindings) == 0 {
+			return fmt.Errorf("queue %s has no bindings", q.Name)
+		}
+		if q.MaxRetries != nil && *q.MaxRetries < 0 {
+			return fmt.Errorf("queue %s max retries must not be negative", q.Name)
+		}
+	}
+
+	return nil
+}
+
+// Name returns the prefixed name of an exchange or queue
+func (t Topology) Name(name string) string {
+	if t.Prefix == "" {
+		return name
+	}
+	return t.Prefix + "." + name
+}
+
+// ExchangeName returns the prefixed main exchange
+func (t Topology) ExchangeName() string {
+	return t.Name(t.Exchange)
+}
+
+// DeadLetterExchangeName returns the prefixed dead-letter exchange
+func (t Topology) DeadLetterExchangeName() string {
+	return t.Name(t.DeadLetterExchange)
+}
+
+// DeadLetterQueueName returns the prefixed dead-letter queue
+func (t Topology) DeadLetterQueueName() string {
+	return t.Name(t.DeadLetterQueue)
+}
+
+// queueWorkers returns the worker count of a queue
+func (t Topology) queueWorkers(q QueueTopology) int {
+	if q.Workers > 0 {
+		return q.Workers
+	}
+	return t.Workers
+}
+
+// queueMaxRetries returns the retry limit of a queue
+func (t Topology) queueMaxRetries(q QueueTopology) int {
+	if q.MaxRetries != nil {
+		return *q.MaxRetries
+	}
+	return t.MaxRetries
+}
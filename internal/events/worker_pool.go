@@ -0,0 +1,127 @@
+package events
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+)
+
+// ConsumeOptions configures how a provider delivers messages to a handler
+type ConsumeOptions struct {
+	// Workers is the number of messages handled concurrently (default 1)
+	Workers int
+	// Prefetch is the number of unacknowledged messages the broker may send (default 10, at least Workers)
+	Prefetch int
+	// KeyFunc returns the ordering key of a message. Messages with the same key
+	// are handled one at a time in delivery order. If nil, any worker may
+	// handle any message.
+	KeyFunc func(msg Message) string
+	// DeadLetterExchange receives messages that providers with a delivery
+	// limit give up on. If empty, they are dropped.
+	DeadLetterExchange string
+}
+
+// Normalize applies defaults to the options
+func (o ConsumeOptions) Normalize() ConsumeOptions {
+	if o.Workers <= 0 {
+		o.Workers = 1
+	}
+	if o.Prefetch <= 0 {
+		o.Prefetch = 10
+	}
+	if o.Prefetch < o.Workers {
+		o.Prefetch = o.Workers
+	}
+	return o
+}
+
+type poolTask struct {
+	msg  Message
+	done func(err error)
+}
+
+// WorkerPool runs a message handler on a fixed number of workers. Messages are
+// assigned to workers by ordering key, so messages sharing a key never run
+// concurrently or out of order. Providers use it to implement ConsumeOptions.
+type WorkerPool struct {
+	handler MessageHandler
+	keyFunc func(msg Message) string
+	queues  []chan poolTask
+	shared  chan poolTask
+	wg      sync.WaitGroup
+}
+
+// NewWorkerPool creates a worker pool and starts its workers
+func NewWorkerPool(ctx context.Context, opts ConsumeOptions, handler MessageHandler) *WorkerPool {
+	opts = opts.Normalize()
+
+	p := &WorkerPool{
+		handler: handler,
+		keyFunc: opts.KeyFunc,
+	}
+
+	if p.keyFunc == nil {
+		p.shared = make(chan poolTask)
+		for i := 0; i < opts.Workers; i++ {
+			p.start(ctx, p.shared)
+		}
+		return p
+	}
+
+	p.queues = make([]chan poolTask, opts.Workers)
+	for i := range p.queues {
+		p.queues[i] = make(chan poolTask, opts.Prefetch)
+		p.start(ctx, p.queues[i])
+	}
+	return p
+}
+
+func (p *WorkerPool) start(ctx context.Context, tasks chan poolTask) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for task := range tasks {
+			task.done(p.handler(ctx, task.msg))
+		}
+	}()
+}
+
+// Submit hands a message to a worker; done is called with the handler's result.
+// It blocks while the selected worker is busy. Submit must not be called
+// concurrently or after Drain.
+func (p *WorkerPool) Submit(msg Message, done func(err error)) {
+	task := poolTask{msg: msg, done: done}
+
+	if p.shared != nil {
+		p.shared <- task
+		return
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(p.keyFunc(msg)))
+	p.queues[h.Sum32()%uint32(len(p.queues))] <- task
+}
+
+// Drain stops accepting messages and waits until queued and in-flight
+// messages are handled or ctx expires
+func (p *WorkerPool) Drain(ctx context.Context) error {
+	if p.shared != nil {
+		close(p.shared)
+	}
+	for _, q := range p.queues {
+		close(q)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
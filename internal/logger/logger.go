@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"rbac-service/internal/reqctx"
 	"runtime"
 	"time"
 )
@@ -77,6 +78,17 @@ func logMsg(ctx context.Context, level Level, msg string, data interface{}, tags
 		slog.Time("timestamp", time.Now()),
 	}
 
+	// Trace IDs of the request or event being handled
+	if requestID := reqctx.RequestID(ctx); requestID != "" {
+		attrs = append(attrs, slog.String("request_id", requestID))
+	}
+	if correlationID := reqctx.CorrelationID(ctx); correlationID != "" {
+		attrs = append(attrs, slog.String("correlation_id", correlationID))
+	}
+	if causationID := reqctx.CausationID(ctx); causationID != "" {
+		attrs = append(attrs, slog.String("causation_id", causationID))
+	}
+
 	defaultLogger.LogAttrs(ctx, slogLevel, msg, attrs...)
 
 	if level == LevelFatal {
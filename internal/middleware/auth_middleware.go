@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"rbac-service/internal/auth"
+	"rbac-service/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthMiddleware struct {
+	authenticator auth.Authenticator
+}
+
+func NewAuthMiddleware(authenticator auth.Authenticator) *AuthMiddleware {
+	return &AuthMiddleware{
+		authenticator: authenticator,
+	}
+}
+
+// Authenticate resolves the caller's principal and stores it in the request context
+func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := m.authenticator.Authenticate(c.Request)
+		if err != nil {
+			if errors.Is(err, auth.ErrNoCredentials) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+				return
+			}
+
+			logger.Warn(c.Request.Context(), "Authentication failed", nil, "error", err.Error())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+
+		logger.Info(c.Request.Context(), "Request authenticated", nil,
+			"principal_type", principal.Type,
+			"principal_id", principal.ID,
+			"method", c.Request.Method,
+			"path", c.FullPath(),
+		)
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
@@ -2,34 +2,44 @@ package middleware
 
 import (
 	"net/http"
+	"rbac-service/internal/auth"
+	"rbac-service/internal/decision"
+	"rbac-service/internal/model"
 	"rbac-service/internal/service"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type PermissionMiddleware struct {
-	permService *service.PermissionService
+	permService    *service.PermissionService
+	decisionLogger *decision.Logger
 }
 
-func NewPermissionMiddleware(permService *service.PermissionService) *PermissionMiddleware {
+func NewPermissionMiddleware(permService *service.PermissionService, decisionLogger *decision.Logger) *PermissionMiddleware {
 	return &PermissionMiddleware{
-		permService: permService,
+		permService:    permService,
+		decisionLogger: decisionLogger,
 	}
 }
 
 func (m *PermissionMiddleware) RequirePermission(permCode string, associatedPermCode string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID := c.GetHeader("X-User-ID")
-		if userID == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "X-User-ID header is required"})
+		principal, ok := auth.PrincipalFromContext(c.Request.Context())
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 			return
 		}
+		userID := principal.ID
 
 		targetTenantID := c.GetHeader("X-Tenant-ID")
 		if targetTenantID == "" {
 			targetTenantID = c.Query("tenant_id")
 		}
+		if targetTenantID == "" {
+			targetTenantID = principal.TenantID
+		}
 
 		// Parse permission codes
 		permParts := strings.Split(permCode, ".")
@@ -50,7 +60,8 @@ func (m *PermissionMiddleware) RequirePermission(permCode string, associatedPerm
 		}
 
 		// Optimized single query check using materialized view
-		allowed, err := m.permService.CheckMiddlewarePermissionsWithMV(
+		start := time.Now()
+		allowed, global, err := m.permService.CheckMiddlewarePermissionsWithMV(
 			c.Request.Context(),
 			userID,
 			tenantIDPtr,
@@ -58,12 +69,31 @@ func (m *PermissionMiddleware) RequirePermission(permCode string, associatedPerm
 			assocPermParts[0], assocPermParts[1],
 		)
 
+		entry := &model.DecisionLog{
+			Source:      model.DecisionSourceMiddleware,
+			PrincipalID: userID,
+			TenantID:    targetTenantID,
+			Permissions: []model.PermissionCode{
+				{ResourceCode: permParts[0], ActionCode: permParts[1]},
+				{ResourceCode: assocPermParts[0], ActionCode: assocPermParts[1]},
+			},
+			Condition: "OR",
+			Allowed:   allowed,
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		m.decisionLogger.Record(c.Request.Context(), entry)
+
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
 			return
 		}
 
 		if allowed {
+			scope := auth.Scope{TenantID: targetTenantID, Global: global}
+			c.Request = c.Request.WithContext(auth.WithScope(c.Request.Context(), scope))
 			c.Next()
 			return
 		}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"rbac-service/internal/reqctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxRequestIDLength bounds client-supplied request IDs, which are stored as
+// correlation IDs and audit log request IDs
+const maxRequestIDLength = 128
+
+// RequestID propagates the X-Request-ID header (or a generated ID) through the
+// request context and echoes it on the response. The request ID is also the
+// correlation and causation ID of events published while handling the request.
+// A header that is too long or contains other characters than letters, digits
+// and ._:- is replaced by a generated ID.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(reqctx.HeaderRequestID)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+
+		c.Header(reqctx.HeaderRequestID, requestID)
+		ctx := reqctx.WithRequestID(c.Request.Context(), requestID)
+		ctx = reqctx.WithCorrelationID(ctx, requestID)
+		ctx = reqctx.WithCausationID(ctx, requestID)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// validRequestID reports whether a client-supplied request ID is safe to store and log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		switch ch := id[i]; {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '.', ch == '_', ch == ':', ch == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
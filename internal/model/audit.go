@@ -0,0 +1,70 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit log entity types
+const (
+	AuditEntityRole   = "role"
+	AuditEntityGroup  = "group"
+	AuditEntityTenant = "tenant"
+	AuditEntityAPIKey = "api_key"
+)
+
+// Audit log operations
+const (
+	AuditRoleCreate            = "role.create"
+	AuditRolePermissionsAssign = "role.permissions.assign"
+	AuditRolePermissionsRemove = "role.permissions.remove"
+	AuditRolePermissionsSync   = "role.permissions.sync"
+	AuditRoleUsersAssign       = "role.users.assign"
+	AuditRoleUsersRemove       = "role.users.remove"
+
+	AuditGroupCreate            = "group.create"
+	AuditGroupPermissionsAssign = "group.permissions.assign"
+	AuditGroupPermissionsRemove = "group.permissions.remove"
+	AuditGroupPermissionsSync   = "group.permissions.sync"
+	AuditGroupUsersAssign       = "group.users.assign"
+	AuditGroupUsersRemove       = "group.users.remove"
+
+	AuditTenantPermissionsAssign = "tenant.permissions.assign"
+	AuditTenantPermissionsRemove = "tenant.permissions.remove"
+	AuditTenantPermissionsSync   = "tenant.permissions.sync"
+
+	AuditAPIKeyCreate      = "api_key.create"
+	AuditAPIKeyRevoke      = "api_key.revoke"
+	AuditAPIKeyRolesAssign = "api_key.roles.assign"
+	AuditAPIKeyRolesRemove = "api_key.roles.remove"
+)
+
+// AuditLogEntry represents a row in the authz_audit_log table
+type AuditLogEntry struct {
+	ID         string          `json:"id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	ActorID    string          `json:"actor_id,omitempty"`
+	ActorType  string          `json:"actor_type,omitempty"`
+	TenantID   string          `json:"tenant_id,omitempty"`
+	Operation  string          `json:"operation"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	Diff       json.RawMessage `json:"diff,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+}
+
+// AuditLogFilter holds the query filters for listing audit log entries
+type AuditLogFilter struct {
+	ActorID    string
+	TenantID   string
+	Operation  string
+	EntityType string
+	EntityID   string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
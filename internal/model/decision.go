@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Decision sources
+const (
+	DecisionSourceCheckPermission = "check_permission"
+	DecisionSourceMiddleware      = "middleware"
+)
+
+// DecisionLog records the outcome of a single permission check
+type DecisionLog struct {
+	ID          string           `json:"id"`
+	OccurredAt  time.Time        `json:"occurred_at"`
+	Source      string           `json:"source"`
+	PrincipalID string           `json:"principal_id"`
+	TenantID    string           `json:"tenant_id,omitempty"`
+	Permissions []PermissionCode `json:"permissions"`
+	Condition   string           `json:"condition,omitempty"`
+	Allowed     bool             `json:"allowed"`
+	Error       string           `json:"error,omitempty"`
+	LatencyMs   float64          `json:"latency_ms"`
+	RequestID   string           `json:"request_id,omitempty"`
+}
@@ -1,14 +1,20 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Event statuses
 const (
-	StatusPending    = "pending"
-	StatusPublished  = "published"
-	StatusProcessing = "processing"
-	StatusCompleted  = "completed"
-	StatusFailed     = "failed"
+	StatusPending      = "pending"
+	StatusPublished    = "published"
+	StatusUnroutable   = "unroutable"
+	StatusProcessing   = "processing"
+	StatusCompleted    = "completed"
+	StatusFailed       = "failed"
+	StatusDeadLettered = "dead_lettered"
+	StatusReplayed     = "replayed"
 )
 
 // Event types
@@ -25,6 +31,57 @@ const (
 	EventUserGroupRemoveRequest = "rbac.user_group.remove.request"
 	EventUserGroupRemoveSuccess = "rbac.user_group.remove.success"
 	EventUserGroupRemoveFailed  = "rbac.user_group.remove.failed"
+	EventPermissionDecision     = "rbac.permission.decision.logged"
+
+	EventRoleCreateRequest = "rbac.role.create.request"
+	EventRoleCreateSuccess = "rbac.role.create.success"
+	EventRoleCreateFailed  = "rbac.role.create.failed"
+
+	EventRolePermissionAssignRequest = "rbac.role_permission.assign.request"
+	EventRolePermissionAssignSuccess = "rbac.role_permission.assign.success"
+	EventRolePermissionAssignFailed  = "rbac.role_permission.assign.failed"
+	EventRolePermissionRemoveRequest = "rbac.role_permission.remove.request"
+	EventRolePermissionRemoveSuccess = "rbac.role_permission.remove.success"
+	EventRolePermissionRemoveFailed  = "rbac.role_permission.remove.failed"
+	EventRolePermissionSyncRequest   = "rbac.role_permission.sync.request"
+	EventRolePermissionSyncSuccess   = "rbac.role_permission.sync.success"
+	EventRolePermissionSyncFailed    = "rbac.role_permission.sync.failed"
+
+	EventGroupCreateRequest = "rbac.group.create.request"
+	EventGroupCreateSuccess = "rbac.group.create.success"
+	EventGroupCreateFailed  = "rbac.group.create.failed"
+
+	EventGroupPermissionAssignRequest = "rbac.group_permission.assign.request"
+	EventGroupPermissionAssignSuccess = "rbac.group_permission.assign.success"
+	EventGroupPermissionAssignFailed  = "rbac.group_permission.assign.failed"
+	EventGroupPermissionRemoveRequest = "rbac.group_permission.remove.request"
+	EventGroupPermissionRemoveSuccess = "rbac.group_permission.remove.success"
+	EventGroupPermissionRemoveFailed  = "rbac.group_permission.remove.failed"
+	EventGroupPermissionSyncRequest   = "rbac.group_permission.sync.request"
+	EventGroupPermissionSyncSuccess   = "rbac.group_permission.sync.success"
+	EventGroupPermissionSyncFailed    = "rbac.group_permission.sync.failed"
+
+	EventTenantPermissionAssignRequest = "rbac.tenant_permission.assign.request"
+	EventTenantPermissionAssignSuccess = "rbac.tenant_permission.assign.success"
+	EventTenantPermissionAssignFailed  = "rbac.tenant_permission.assign.failed"
+	EventTenantPermissionRemoveRequest = "rbac.tenant_permission.remove.request"
+	EventTenantPermissionRemoveSuccess = "rbac.tenant_permission.remove.success"
+	EventTenantPermissionRemoveFailed  = "rbac.tenant_permission.remove.failed"
+	EventTenantPermissionSyncRequest   = "rbac.tenant_permission.sync.request"
+	EventTenantPermissionSyncSuccess   = "rbac.tenant_permission.sync.success"
+	EventTenantPermissionSyncFailed    = "rbac.tenant_permission.sync.failed"
+
+	// Domain change events, published when authorization data actually changes
+	EventRoleCreated               = "rbac.role.created"
+	EventRolePermissionsChanged    = "rbac.role.permissions.changed"
+	EventRoleUsersChanged          = "rbac.role.users.changed"
+	EventGroupCreated              = "rbac.group.created"
+	EventGroupPermissionsChanged   = "rbac.group.permissions.changed"
+	EventGroupUsersChanged         = "rbac.group.users.changed"
+	EventTenantEntitlementsChanged = "rbac.tenant.entitlements.changed"
+	EventAPIKeyCreated             = "rbac.api_key.created"
+	EventAPIKeyRolesChanged        = "rbac.api_key.roles.changed"
+	EventAPIKeyRevoked             = "rbac.api_key.revoked"
 )
 
 // Event represents a message in the event system
@@ -33,6 +90,17 @@ type Event struct {
 	Type      string      `json:"type"`
 	Payload   interface{} `json:"payload"`
 	Timestamp time.Time   `json:"timestamp"`
+
+	// CorrelationID is shared by every event started by the same original
+	// request; CausationID is the ID of the request or event that caused this one
+	CorrelationID string `json:"correlation_id,omitempty"`
+	CausationID   string `json:"causation_id,omitempty"`
+
+	// CloudEvents attributes, set on events received as CloudEvents. Published
+	// CloudEvents fill them in when empty.
+	Source     string `json:"-"`
+	Subject    string `json:"-"`
+	DataSchema string `json:"-"`
 }
 
 // UserRolePayload represents the payload for user-role events
@@ -47,23 +115,156 @@ type UserGroupPayload struct {
 	GroupID string   `json:"group_id"`
 }
 
-// ErrorPayload represents the payload for failed events
+// CreateRolePayload represents the payload for role create requests
+type CreateRolePayload struct {
+	Name     string `json:"name"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// CreateGroupPayload represents the payload for group create requests
+type CreateGroupPayload struct {
+	Name     string `json:"name"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// RolePermissionPayload represents the payload for role permission events
+type RolePermissionPayload struct {
+	RoleID      string       `json:"role_id"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// GroupPermissionPayload represents the payload for group permission events
+type GroupPermissionPayload struct {
+	GroupID     string       `json:"group_id"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// TenantPermissionPayload represents the payload for tenant entitlement events
+type TenantPermissionPayload struct {
+	TenantID    string       `json:"tenant_id"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// ErrorPayload represents the payload for failed events. It echoes the
+// identifiers of the request.
 type ErrorPayload struct {
-	UserIDs []string `json:"user_ids,omitempty"`
-	RoleID  string   `json:"role_id,omitempty"`
-	GroupID string   `json:"group_id,omitempty"`
-	Error   string   `json:"error"`
+	UserIDs     []string     `json:"user_ids,omitempty"`
+	RoleID      string       `json:"role_id,omitempty"`
+	GroupID     string       `json:"group_id,omitempty"`
+	TenantID    string       `json:"tenant_id,omitempty"`
+	Name        string       `json:"name,omitempty"`
+	Permissions []Permission `json:"permissions,omitempty"`
+	Error       string       `json:"error"`
+}
+
+// PermissionChange is the effect of a permission mutation: the permissions
+// actually granted and revoked, which may differ from those requested
+type PermissionChange struct {
+	TenantID string       `json:"tenant_id,omitempty"`
+	Added    []Permission `json:"added"`
+	Removed  []Permission `json:"removed"`
+}
+
+// Empty reports whether the mutation changed nothing
+func (c PermissionChange) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
+}
+
+// MembershipChange is the effect of a membership mutation: the IDs actually
+// added and removed
+type MembershipChange struct {
+	TenantID string   `json:"tenant_id,omitempty"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+}
+
+// Empty reports whether the mutation changed nothing
+func (c MembershipChange) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
+}
+
+// PermissionChangeEvents builds the events of a permission mutation from its
+// effect. Repositories call it inside the mutation's transaction and write the
+// events to the outbox, so they are only published if the change commits.
+type PermissionChangeEvents func(change PermissionChange) []Event
+
+// MembershipChangeEvents builds the events of a membership mutation from its
+// effect, like PermissionChangeEvents
+type MembershipChangeEvents func(change MembershipChange) []Event
+
+// RoleCreatedPayload represents the payload for role created events
+type RoleCreatedPayload struct {
+	RoleID   string `json:"role_id"`
+	Name     string `json:"name"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// GroupCreatedPayload represents the payload for group created events
+type GroupCreatedPayload struct {
+	GroupID  string `json:"group_id"`
+	Name     string `json:"name"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// RolePermissionsChangedPayload represents the payload for role permission change events
+type RolePermissionsChangedPayload struct {
+	RoleID string `json:"role_id"`
+	PermissionChange
+}
+
+// GroupPermissionsChangedPayload represents the payload for group permission change events
+type GroupPermissionsChangedPayload struct {
+	GroupID string `json:"group_id"`
+	PermissionChange
+}
+
+// RoleUsersChangedPayload represents the payload for role membership change
+// events. Added and Removed are user IDs.
+type RoleUsersChangedPayload struct {
+	RoleID string `json:"role_id"`
+	MembershipChange
+}
+
+// GroupUsersChangedPayload represents the payload for group membership change
+// events. Added and Removed are user IDs.
+type GroupUsersChangedPayload struct {
+	GroupID string `json:"group_id"`
+	MembershipChange
+}
+
+// APIKeyCreatedPayload represents the payload for API key created events
+type APIKeyCreatedPayload struct {
+	APIKeyID string   `json:"api_key_id"`
+	Name     string   `json:"name"`
+	TenantID string   `json:"tenant_id,omitempty"`
+	RoleIDs  []string `json:"role_ids"`
+}
+
+// APIKeyRolesChangedPayload represents the payload for API key role change
+// events. Added and Removed are role IDs.
+type APIKeyRolesChangedPayload struct {
+	APIKeyID string `json:"api_key_id"`
+	MembershipChange
+}
+
+// APIKeyRevokedPayload represents the payload for API key revoked events
+type APIKeyRevokedPayload struct {
+	APIKeyID string `json:"api_key_id"`
 }
 
 // PublishedEvent represents an event in the published_events table
 type PublishedEvent struct {
-	ID           string
-	EventType    string
-	Payload      []byte
-	Status       string
-	ErrorMessage *string
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID            string
+	EventType     string
+	Payload       []byte
+	Status        string
+	ErrorMessage  *string
+	Attempts      int
+	CorrelationID *string
+	CausationID   *string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
 }
 
 // ConsumedEvent represents an event in the consumed_events table
@@ -77,3 +278,118 @@ type ConsumedEvent struct {
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
+
+// DeadLetterEvent is the API representation of a dead-lettered consumed event
+type DeadLetterEvent struct {
+	ID         string          `json:"id"`
+	EventType  string          `json:"event_type"`
+	Payload    json.RawMessage `json:"payload"`
+	Status     string          `json:"status"`
+	Error      string          `json:"error,omitempty"`
+	RetryCount int             `json:"retry_count"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// PurgeDeadLettersResponse reports how many dead-lettered events were purged
+type PurgeDeadLettersResponse struct {
+	QueueMessages int   `json:"queue_messages"`
+	Events        int64 `json:"events"`
+}
+
+// EventFilter holds the query filters for listing published or consumed events
+type EventFilter struct {
+	Status    string
+	EventType string
+	From      *time.Time
+	To        *time.Time
+	Limit     int
+	Offset    int
+}
+
+// PublishedEventRecord is the API representation of an outbox entry. The
+// payload is only included when a single event is requested.
+type PublishedEventRecord struct {
+	ID            string          `json:"id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	Status        string          `json:"status"`
+	Error         string          `json:"error,omitempty"`
+	Attempts      int             `json:"attempts"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	CausationID   string          `json:"causation_id,omitempty"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// ConsumedEventRecord is the API representation of a consumed event. The
+// payload is only included when a single event is requested.
+type ConsumedEventRecord struct {
+	ID         string          `json:"id"`
+	EventType  string          `json:"event_type"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	Status     string          `json:"status"`
+	Error      string          `json:"error,omitempty"`
+	RetryCount int             `json:"retry_count"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// Record converts an outbox entry to its API representation
+func (e *PublishedEvent) Record() PublishedEventRecord {
+	record := PublishedEventRecord{
+		ID:            e.ID,
+		EventType:     e.EventType,
+		Payload:       e.Payload,
+		Status:        e.Status,
+		Attempts:      e.Attempts,
+		NextAttemptAt: e.NextAttemptAt,
+		CreatedAt:     e.CreatedAt,
+		UpdatedAt:     e.UpdatedAt,
+	}
+	if e.ErrorMessage != nil {
+		record.Error = *e.ErrorMessage
+	}
+	if e.CorrelationID != nil {
+		record.CorrelationID = *e.CorrelationID
+	}
+	if e.CausationID != nil {
+		record.CausationID = *e.CausationID
+	}
+	return record
+}
+
+// Record converts a consumed event to its API representation
+func (e *ConsumedEvent) Record() ConsumedEventRecord {
+	record := ConsumedEventRecord{
+		ID:         e.ID,
+		EventType:  e.EventType,
+		Payload:    e.Payload,
+		Status:     e.Status,
+		RetryCount: e.RetryCount,
+		CreatedAt:  e.CreatedAt,
+		UpdatedAt:  e.UpdatedAt,
+	}
+	if e.ErrorMessage != nil {
+		record.Error = *e.ErrorMessage
+	}
+	return record
+}
+
+// EventRetentionCounts holds the number of rows pruned from an event table
+type EventRetentionCounts struct {
+	Completed int64 `json:"completed"`
+	Failed    int64 `json:"failed"`
+}
+
+// EventRetentionStats reports the retention job's work since the service started
+type EventRetentionStats struct {
+	Enabled   bool                 `json:"enabled"`
+	Runs      int64                `json:"runs"`
+	LastRunAt *time.Time           `json:"last_run_at,omitempty"`
+	LastError string               `json:"last_error,omitempty"`
+	Published EventRetentionCounts `json:"published"`
+	Consumed  EventRetentionCounts `json:"consumed"`
+	Archived  int64                `json:"archived"`
+}
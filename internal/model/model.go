@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Resource struct {
 	ID          string `json:"id"`
 	Code        string `json:"code"`
@@ -104,3 +106,33 @@ type PermissionCode struct {
 	ResourceCode string `json:"resource_code"`
 	ActionCode   string `json:"action_code"`
 }
+
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	TenantID   string     `json:"tenant_id,omitempty"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	TenantID  string     `json:"tenant_id"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RoleIDs   []string   `json:"role_ids"`
+}
+
+// CreateAPIKeyResponse carries the plaintext key, which is only returned once
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type BulkAPIKeyRoleRequest struct {
+	RoleIDs []string `json:"role_ids"`
+}
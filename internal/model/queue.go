@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// QueueBinding routes messages published to an exchange with a matching routing key to a queue
+type QueueBinding struct {
+	Queue      string `json:"queue"`
+	Exchange   string `json:"exchange"`
+	RoutingKey string `json:"routing_key"`
+}
+
+// QueueMessage is a message stored by the Postgres queue provider
+type QueueMessage struct {
+	ID            int64             `json:"id"`
+	Queue         string            `json:"queue"`
+	RoutingKey    string            `json:"routing_key"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Body          []byte            `json:"body"`
+	DeliveryCount int               `json:"delivery_count"`
+	AvailableAt   time.Time         `json:"available_at"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
@@ -0,0 +1,377 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/model"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrAPIKeyNotFound is returned when no API key matches the lookup
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type APIKeyRepository struct{}
+
+func NewAPIKeyRepository() *APIKeyRepository {
+	return &APIKeyRepository{}
+}
+
+const (
+	apiKeyColumns           = "id, name, prefix, key_hash, tenant_id, created_by, created_at, expires_at, last_used_at, revoked_at"
+	apiKeyRoleSnapshotQuery = "SELECT role_id::text FROM pmsn.user_role WHERE user_id = $1 AND role_id::text = ANY($2)"
+)
+
+// CreateAPIKey stores a new API key and assigns its initial roles in one
+// transaction. The given events are written to the outbox in the same transaction.
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *model.APIKey, roleIDs []string, events ...model.Event) error {
+	pool := GetPool()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		"INSERT INTO pmsn.api_key (id, name, prefix, key_hash, tenant_id, created_by, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		key.ID, key.Name, key.Prefix, key.KeyHash, nullableString(key.TenantID), nullableString(key.CreatedBy), key.CreatedAt, key.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	if len(roleIDs) > 0 {
+		batch := &pgx.Batch{}
+		for _, roleID := range roleIDs {
+			batch.Queue("INSERT INTO pmsn.user_role (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", key.ID, roleID)
+		}
+
+		br := tx.SendBatch(ctx, batch)
+		defer br.Close()
+
+		for i := 0; i < len(roleIDs); i++ {
+			if _, err := br.Exec(); err != nil {
+				logger.Error(ctx, "Failed to assign role to api key", err, "api_key_id", key.ID)
+				return fmt.Errorf("failed to execute batch: %w", err)
+			}
+		}
+
+		if err := br.Close(); err != nil {
+			return fmt.Errorf("failed to close batch results: %w", err)
+		}
+	}
+
+	err = recordAudit(ctx, tx, auditRecord{
+		tenantID:   key.TenantID,
+		operation:  model.AuditAPIKeyCreate,
+		entityType: model.AuditEntityAPIKey,
+		entityID:   key.ID,
+		after: map[string]interface{}{
+			"api_key":  key,
+			"role_ids": roleIDs,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := enqueueEvents(ctx, tx, events); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKeyByHash looks up an API key by the hash of its secret
+func (r *APIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	row := GetPool().QueryRow(ctx, "SELECT "+apiKeyColumns+" FROM pmsn.api_key WHERE key_hash = $1", keyHash)
+	return scanAPIKey(row)
+}
+
+// GetAPIKey looks up an API key by ID
+func (r *APIKeyRepository) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	row := GetPool().QueryRow(ctx, "SELECT "+apiKeyColumns+" FROM pmsn.api_key WHERE id = $1", id)
+	return scanAPIKey(row)
+}
+
+// ListAPIKeys lists API keys, optionally restricted to a tenant
+func (r *APIKeyRepository) ListAPIKeys(ctx context.Context, tenantID string) ([]model.APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM pmsn.api_key"
+	var args []interface{}
+	if tenantID != "" {
+		query += " WHERE tenant_id = $1"
+		args = append(args, tenantID)
+	}
+	query += " ORDER BY created_at DESC"
+
+	rows, err := GetPool().Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []model.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey marks an API key as revoked and removes its role assignments,
+// so nothing restores them if the principal ID is reused. The events built from
+// the removed roles and the given events are written to the outbox in the same
+// transaction.
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, id string, changeEvents model.MembershipChangeEvents, events ...model.Event) error {
+	pool := GetPool()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	key, err := scanAPIKey(tx.QueryRow(ctx, "SELECT "+apiKeyColumns+" FROM pmsn.api_key WHERE id = $1 AND revoked_at IS NULL FOR UPDATE", id))
+	if err != nil {
+		return err
+	}
+
+	revokedAt := time.Now()
+	if _, err := tx.Exec(ctx, "UPDATE pmsn.api_key SET revoked_at = $1 WHERE id = $2", revokedAt, id); err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	before := *key
+	key.RevokedAt = &revokedAt
+	err = recordAudit(ctx, tx, auditRecord{
+		tenantID:   key.TenantID,
+		operation:  model.AuditAPIKeyRevoke,
+		entityType: model.AuditEntityAPIKey,
+		entityID:   key.ID,
+		before:     before,
+		after:      key,
+	})
+	if err != nil {
+		return err
+	}
+
+	removed, err := idSnapshot(ctx, tx, "DELETE FROM pmsn.user_role WHERE user_id = $1 RETURNING role_id::text", id)
+	if err != nil {
+		return fmt.Errorf("failed to remove api key roles: %w", err)
+	}
+
+	if len(removed) > 0 {
+		change := diffIDs(removed, nil)
+		err = recordAudit(ctx, tx, auditRecord{
+			tenantID:   key.TenantID,
+			operation:  model.AuditAPIKeyRolesRemove,
+			entityType: model.AuditEntityAPIKey,
+			entityID:   key.ID,
+			before:     removed,
+			after:      []string{},
+			diff:       change,
+		})
+		if err != nil {
+			return err
+		}
+
+		change.TenantID = key.TenantID
+		if err := enqueueMembershipChange(ctx, tx, change, changeEvents); err != nil {
+			return err
+		}
+	}
+
+	if err := enqueueEvents(ctx, tx, events); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// TouchAPIKey records that an API key was used, unless that was already
+// recorded within the interval
+func (r *APIKeyRepository) TouchAPIKey(ctx context.Context, id string, interval time.Duration) error {
+	now := time.Now()
+	_, err := GetPool().Exec(ctx, `
+		UPDATE pmsn.api_key SET last_used_at = $1
+		WHERE id = $2 AND (last_used_at IS NULL OR last_used_at <= $3)
+	`, now, id, now.Add(-interval))
+	if err != nil {
+		return fmt.Errorf("failed to update api key last used: %w", err)
+	}
+	return nil
+}
+
+// RolesOutsideTenant returns the given role IDs that are not roles of the
+// tenant, including global roles and unknown IDs
+func (r *APIKeyRepository) RolesOutsideTenant(ctx context.Context, roleIDs []string, tenantID string) ([]string, error) {
+	if len(roleIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := GetPool().Query(ctx, `
+		SELECT requested.id
+		FROM unnest($1::text[]) AS requested(id)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM pmsn.role r
+			WHERE r.id::text = requested.id AND r.tenant_id::text = $2
+		)
+	`, roleIDs, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check role tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var outside []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan role id: %w", err)
+		}
+		outside = append(outside, id)
+	}
+
+	return outside, rows.Err()
+}
+
+// BulkAssignRoles assigns roles to an API key principal. The events built from
+// the change are written to the outbox in the same transaction.
+func (r *APIKeyRepository) BulkAssignRoles(ctx context.Context, keyID string, roleIDs []string, changeEvents model.MembershipChangeEvents) error {
+	return r.execRoleBatch(ctx, keyID, roleIDs, model.AuditAPIKeyRolesAssign, "INSERT INTO pmsn.user_role (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", changeEvents)
+}
+
+// BulkRemoveRoles removes roles from an API key principal. The events built
+// from the change are written to the outbox in the same transaction.
+func (r *APIKeyRepository) BulkRemoveRoles(ctx context.Context, keyID string, roleIDs []string, changeEvents model.MembershipChangeEvents) error {
+	return r.execRoleBatch(ctx, keyID, roleIDs, model.AuditAPIKeyRolesRemove, "DELETE FROM pmsn.user_role WHERE user_id = $1 AND role_id = $2", changeEvents)
+}
+
+func (r *APIKeyRepository) execRoleBatch(ctx context.Context, keyID string, roleIDs []string, operation, query string, changeEvents model.MembershipChangeEvents) error {
+	if len(roleIDs) == 0 {
+		return nil
+	}
+
+	pool := GetPool()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := idSnapshot(ctx, tx, apiKeyRoleSnapshotQuery, keyID, roleIDs)
+	if err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	for _, roleID := range roleIDs {
+		batch.Queue(query, keyID, roleID)
+	}
+
+	br := tx.SendBatch(ctx, batch)
+	defer br.Close()
+
+	for i := 0; i < len(roleIDs); i++ {
+		if _, err := br.Exec(); err != nil {
+			logger.Error(ctx, "Failed to update api key roles", err, "api_key_id", keyID)
+			return fmt.Errorf("failed to execute batch: %w", err)
+		}
+	}
+
+	if err := br.Close(); err != nil {
+		return fmt.Errorf("failed to close batch results: %w", err)
+	}
+
+	after, err := idSnapshot(ctx, tx, apiKeyRoleSnapshotQuery, keyID, roleIDs)
+	if err != nil {
+		return err
+	}
+
+	var tenantID *string
+	if err := tx.QueryRow(ctx, "SELECT tenant_id::text FROM pmsn.api_key WHERE id = $1", keyID).Scan(&tenantID); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to get api key tenant: %w", err)
+	}
+
+	change := diffIDs(before, after)
+	err = recordAudit(ctx, tx, auditRecord{
+		tenantID:   derefString(tenantID),
+		operation:  operation,
+		entityType: model.AuditEntityAPIKey,
+		entityID:   keyID,
+		before:     before,
+		after:      after,
+		diff:       change,
+	})
+	if err != nil {
+		return err
+	}
+
+	change.TenantID = derefString(tenantID)
+	if err := enqueueMembershipChange(ctx, tx, change, changeEvents); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (*model.APIKey, error) {
+	var key model.APIKey
+	var tenantID, createdBy *string
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&tenantID,
+		&createdBy,
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to scan api key: %w", err)
+	}
+
+	if tenantID != nil {
+		key.TenantID = *tenantID
+	}
+	if createdBy != nil {
+		key.CreatedBy = *createdBy
+	}
+
+	return &key, nil
+}
+
+// nullableString maps an empty string to SQL NULL
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
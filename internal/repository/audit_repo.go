@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"rbac-service/internal/auth"
+	"rbac-service/internal/model"
+	"rbac-service/internal/reqctx"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// auditRecord describes a single authorization data change
+type auditRecord struct {
+	tenantID   string
+	operation  string
+	entityType string
+	entityID   string
+	before     interface{}
+	after      interface{}
+	diff       interface{}
+}
+
+// recordAudit writes an audit log entry within the caller's transaction, so
+// the entry is committed or rolled back together with the change it describes.
+// The actor and request ID are taken from the context.
+func recordAudit(ctx context.Context, tx pgx.Tx, rec auditRecord) error {
+	var actorID, actorType *string
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		actorID = &principal.ID
+		actorType = &principal.Type
+	}
+
+	before, err := marshalAuditValue(rec.before)
+	if err != nil {
+		return err
+	}
+	after, err := marshalAuditValue(rec.after)
+	if err != nil {
+		return err
+	}
+	diff, err := marshalAuditValue(rec.diff)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO pmsn.authz_audit_log (occurred_at, actor_id, actor_type, tenant_id, operation, entity_type, entity_id, before, after, diff, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`,
+		time.Now(),
+		actorID,
+		actorType,
+		nullableString(rec.tenantID),
+		rec.operation,
+		rec.entityType,
+		rec.entityID,
+		before,
+		after,
+		diff,
+		nullableString(reqctx.RequestID(ctx)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	return nil
+}
+
+func marshalAuditValue(v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit value: %w", err)
+	}
+	return data, nil
+}
+
+// entityTenant returns the tenant of a role or group, or "" for global entities
+func entityTenant(ctx context.Context, tx pgx.Tx, table, id string) (string, error) {
+	var tenantID *string
+	err := tx.QueryRow(ctx, "SELECT tenant_id::text FROM "+table+" WHERE id = $1", id).Scan(&tenantID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("failed to get entity tenant: %w", err)
+	}
+	if tenantID == nil {
+		return "", nil
+	}
+	return *tenantID, nil
+}
+
+// permissionSnapshot reads a set of resource/action pairs within the transaction
+func permissionSnapshot(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) ([]model.Permission, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot permissions: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := []model.Permission{}
+	for rows.Next() {
+		var p model.Permission
+		if err := rows.Scan(&p.ResourceID, &p.ActionID); err != nil {
+			return nil, fmt.Errorf("failed to scan permission snapshot: %w", err)
+		}
+		permissions = append(permissions, p)
+	}
+
+	return permissions, rows.Err()
+}
+
+// idSnapshot reads a set of IDs within the transaction
+func idSnapshot(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot ids: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan id snapshot: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func diffPermissions(before, after []model.Permission) model.PermissionChange {
+	key := func(p model.Permission) string { return p.ResourceID + ":" + p.ActionID }
+
+	beforeSet := make(map[string]bool, len(before))
+	for _, p := range before {
+		beforeSet[key(p)] = true
+	}
+	afterSet := make(map[string]bool, len(after))
+	for _, p := range after {
+		afterSet[key(p)] = true
+	}
+
+	diff := model.PermissionChange{Added: []model.Permission{}, Removed: []model.Permission{}}
+	for _, p := range after {
+		if !beforeSet[key(p)] {
+			diff.Added = append(diff.Added, p)
+		}
+	}
+	for _, p := range before {
+		if !afterSet[key(p)] {
+			diff.Removed = append(diff.Removed, p)
+		}
+	}
+
+	return diff
+}
+
+func diffIDs(before, after []string) model.MembershipChange {
+	beforeSet := make(map[string]bool, len(before))
+	for _, id := range before {
+		beforeSet[id] = true
+	}
+	afterSet := make(map[string]bool, len(after))
+	for _, id := range after {
+		afterSet[id] = true
+	}
+
+	diff := model.MembershipChange{Added: []string{}, Removed: []string{}}
+	for _, id := range after {
+		if !beforeSet[id] {
+			diff.Added = append(diff.Added, id)
+		}
+	}
+	for _, id := range before {
+		if !afterSet[id] {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+
+	return diff
+}
+
+// AuditLogRepository handles read access to the authz_audit_log table
+type AuditLogRepository struct{}
+
+// NewAuditLogRepository creates a new audit log repository
+func NewAuditLogRepository() *AuditLogRepository {
+	return &AuditLogRepository{}
+}
+
+// ListAuditLog returns audit log entries matching the filter, newest first
+func (r *AuditLogRepository) ListAuditLog(ctx context.Context, filter model.AuditLogFilter) ([]model.AuditLogEntry, error) {
+	var conditions []string
+	var args []interface{}
+
+	addCondition := func(clause string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(clause, len(args)))
+	}
+
+	if filter.ActorID != "" {
+		addCondition("actor_id = $%d", filter.ActorID)
+	}
+	if filter.TenantID != "" {
+		addCondition("tenant_id = $%d", filter.TenantID)
+	}
+	if filter.Operation != "" {
+		addCondition("operation = $%d", filter.Operation)
+	}
+	if filter.EntityType != "" {
+		addCondition("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		addCondition("entity_id = $%d", filter.EntityID)
+	}
+	if filter.RequestID != "" {
+		addCondition("request_id = $%d", filter.RequestID)
+	}
+	if filter.From != nil {
+		addCondition("occurred_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("occurred_at < $%d", *filter.To)
+	}
+
+	query := `
+		SELECT id, occurred_at, actor_id, actor_type, tenant_id, operation, entity_type, entity_id, before, after, diff, request_id
+		FROM pmsn.authz_audit_log
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY occurred_at DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := GetPool().Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []model.AuditLogEntry{}
+	for rows.Next() {
+		var e model.AuditLogEntry
+		var actorID, actorType, tenantID, requestID *string
+		err := rows.Scan(
+			&e.ID,
+			&e.OccurredAt,
+			&actorID,
+			&actorType,
+			&tenantID,
+			&e.Operation,
+			&e.EntityType,
+			&e.EntityID,
+			&e.Before,
+			&e.After,
+			&e.Diff,
+			&requestID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit log entry: %w", err)
+		}
+
+		e.ActorID = derefString(actorID)
+		e.ActorType = derefString(actorType)
+		e.TenantID = derefString(tenantID)
+		e.RequestID = derefString(requestID)
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
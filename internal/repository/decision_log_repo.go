@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"rbac-service/internal/model"
+)
+
+// DecisionLogRepository handles database operations for the decision_log table
+type DecisionLogRepository struct{}
+
+// NewDecisionLogRepository creates a new decision log repository
+func NewDecisionLogRepository() *DecisionLogRepository {
+	return &DecisionLogRepository{}
+}
+
+// CreateDecisionLog inserts a permission decision
+func (r *DecisionLogRepository) CreateDecisionLog(ctx context.Context, decision *model.DecisionLog) error {
+	permissions, err := json.Marshal(decision.Permissions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal decision permissions: %w", err)
+	}
+
+	query := `
+		INSERT INTO pmsn.decision_log (id, occurred_at, source, principal_id, tenant_id, permissions, condition, allowed, error_message, latency_ms, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err = GetPool().Exec(ctx, query,
+		decision.ID,
+		decision.OccurredAt,
+		decision.Source,
+		decision.PrincipalID,
+		nullableString(decision.TenantID),
+		permissions,
+		nullableString(decision.Condition),
+		decision.Allowed,
+		nullableString(decision.Error),
+		decision.LatencyMs,
+		nullableString(decision.RequestID),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create decision log: %w", err)
+	}
+
+	return nil
+}
@@ -2,9 +2,23 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"rbac-service/internal/model"
+	"strings"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrPublishedEventNotFound is returned when no published event matches the lookup
+	ErrPublishedEventNotFound = errors.New("published event not found")
+	// ErrConsumedEventNotFound is returned when no consumed event matches the lookup
+	ErrConsumedEventNotFound = errors.New("consumed event not found")
+	// ErrConsumedEventInProgress is returned when another delivery of an event
+	// is still being processed
+	ErrConsumedEventInProgress = errors.New("consumed event is being processed")
 )
 
 // EventAuditRepository handles database operations for event audit tables
@@ -55,6 +69,76 @@ func (r *EventAuditRepository) UpdatePublishedEvent(ctx context.Context, id, sta
 	return nil
 }
 
+// EnqueuePublishedEvent writes an event to the outbox for the relay to publish
+func (r *EventAuditRepository) EnqueuePublishedEvent(ctx context.Context, event model.Event) error {
+	return enqueueEvents(ctx, GetPool(), []model.Event{event})
+}
+
+// ClaimPendingEvents returns up to limit pending events that are due for
+// publishing, oldest first. Claimed events are leased by pushing their next
+// attempt into the future, so other relays skip them while they are in flight.
+func (r *EventAuditRepository) ClaimPendingEvents(ctx context.Context, limit int, lease time.Duration) ([]model.PublishedEvent, error) {
+	query := `
+		UPDATE pmsn.published_events
+		SET next_attempt_at = $1
+		WHERE id IN (
+			SELECT id FROM pmsn.published_events
+			WHERE status = $2 AND next_attempt_at <= $3
+			ORDER BY created_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_type, payload, status, error_message, attempts, correlation_id, causation_id, next_attempt_at, created_at, updated_at
+	`
+
+	now := time.Now()
+	rows, err := GetPool().Query(ctx, query, now.Add(lease), model.StatusPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pending events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []model.PublishedEvent
+	for rows.Next() {
+		var event model.PublishedEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.EventType,
+			&event.Payload,
+			&event.Status,
+			&event.ErrorMessage,
+			&event.Attempts,
+			&event.CorrelationID,
+			&event.CausationID,
+			&event.NextAttemptAt,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pending event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// ReschedulePublishedEvent records a failed publish attempt and schedules the next one
+func (r *EventAuditRepository) ReschedulePublishedEvent(ctx context.Context, id, errorMessage string, attempts int, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE pmsn.published_events
+		SET error_message = $1, attempts = $2, next_attempt_at = $3, updated_at = $4
+		WHERE id = $5
+	`
+
+	_, err := GetPool().Exec(ctx, query, errorMessage, attempts, nextAttemptAt, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule published event: %w", err)
+	}
+
+	return nil
+}
+
 // CreateConsumedEvent creates a new consumed event record
 func (r *EventAuditRepository) CreateConsumedEvent(ctx context.Context, event *model.ConsumedEvent) error {
 	query := `
@@ -80,6 +164,86 @@ func (r *EventAuditRepository) CreateConsumedEvent(ctx context.Context, event *m
 	return nil
 }
 
+// ClaimConsumedEvent marks an event as processing before it is handled. It
+// returns false if the event was already completed, so redeliveries can be
+// acknowledged without running the handler again. An event that is processing
+// holds its claim for the lease: a redelivery within the lease gets
+// ErrConsumedEventInProgress, and one after it (e.g. after a crash) resumes.
+// For a resumed or failed event the previous record is returned.
+func (r *EventAuditRepository) ClaimConsumedEvent(ctx context.Context, event *model.ConsumedEvent, lease time.Duration) (*model.ConsumedEvent, bool, error) {
+	tx, err := GetPool().Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO pmsn.consumed_events (id, event_type, payload, status, error_message, retry_count, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO NOTHING
+	`,
+		event.ID,
+		event.EventType,
+		event.Payload,
+		event.Status,
+		event.ErrorMessage,
+		event.RetryCount,
+		event.CreatedAt,
+		event.UpdatedAt,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create consumed event: %w", err)
+	}
+
+	if tag.RowsAffected() == 1 {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil, true, nil
+	}
+
+	// Redelivery: lock the existing record and decide whether to resume
+	var previous model.ConsumedEvent
+	err = tx.QueryRow(ctx, `
+		SELECT id, event_type, payload, status, error_message, retry_count, created_at, updated_at
+		FROM pmsn.consumed_events
+		WHERE id = $1
+		FOR UPDATE
+	`, event.ID).Scan(
+		&previous.ID,
+		&previous.EventType,
+		&previous.Payload,
+		&previous.Status,
+		&previous.ErrorMessage,
+		&previous.RetryCount,
+		&previous.CreatedAt,
+		&previous.UpdatedAt,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get consumed event: %w", err)
+	}
+
+	if previous.Status == model.StatusCompleted {
+		return &previous, false, nil
+	}
+
+	now := time.Now()
+	if previous.Status == model.StatusProcessing && previous.UpdatedAt.After(now.Add(-lease)) {
+		return &previous, false, ErrConsumedEventInProgress
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE pmsn.consumed_events SET status = $1, updated_at = $2 WHERE id = $3", model.StatusProcessing, now, event.ID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to update consumed event: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &previous, true, nil
+}
+
 // UpdateConsumedEvent updates a consumed event record
 func (r *EventAuditRepository) UpdateConsumedEvent(ctx context.Context, id, status string, errorMessage *string, retryCount int) error {
 	query := `
@@ -117,8 +281,371 @@ func (r *EventAuditRepository) GetConsumedEvent(ctx context.Context, id string)
 	)
 
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrConsumedEventNotFound
+		}
 		return nil, fmt.Errorf("failed to get consumed event: %w", err)
 	}
 
 	return &event, nil
 }
+
+// ListConsumedEventsByStatus returns consumed events with the given status, newest first
+func (r *EventAuditRepository) ListConsumedEventsByStatus(ctx context.Context, status string, limit, offset int) ([]model.ConsumedEvent, error) {
+	query := `
+		SELECT id, event_type, payload, status, error_message, retry_count, created_at, updated_at
+		FROM pmsn.consumed_events
+		WHERE status = $1
+		ORDER BY updated_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := GetPool().Query(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consumed events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []model.ConsumedEvent{}
+	for rows.Next() {
+		var event model.ConsumedEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.EventType,
+			&event.Payload,
+			&event.Status,
+			&event.ErrorMessage,
+			&event.RetryCount,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan consumed event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// DeleteConsumedEventsByStatus deletes all consumed events with the given status
+func (r *EventAuditRepository) DeleteConsumedEventsByStatus(ctx context.Context, status string) (int64, error) {
+	tag, err := GetPool().Exec(ctx, "DELETE FROM pmsn.consumed_events WHERE status = $1", status)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete consumed events: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ListPublishedEvents returns outbox entries matching the filter, newest
+// first. Payloads are not loaded.
+func (r *EventAuditRepository) ListPublishedEvents(ctx context.Context, filter model.EventFilter) ([]model.PublishedEvent, error) {
+	where, args := eventFilterConditions(filter)
+	args = append(args, filter.Limit, filter.Offset)
+
+	query := `
+		SELECT id, event_type, status, error_message, attempts, correlation_id, causation_id, next_attempt_at, created_at, updated_at
+		FROM pmsn.published_events
+	` + where + fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := GetPool().Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list published events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []model.PublishedEvent{}
+	for rows.Next() {
+		var event model.PublishedEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.EventType,
+			&event.Status,
+			&event.ErrorMessage,
+			&event.Attempts,
+			&event.CorrelationID,
+			&event.CausationID,
+			&event.NextAttemptAt,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan published event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// GetPublishedEvent retrieves an outbox entry by ID
+func (r *EventAuditRepository) GetPublishedEvent(ctx context.Context, id string) (*model.PublishedEvent, error) {
+	query := `
+		SELECT id, event_type, payload, status, error_message, attempts, correlation_id, causation_id, next_attempt_at, created_at, updated_at
+		FROM pmsn.published_events
+		WHERE id = $1
+	`
+
+	var event model.PublishedEvent
+	err := GetPool().QueryRow(ctx, query, id).Scan(
+		&event.ID,
+		&event.EventType,
+		&event.Payload,
+		&event.Status,
+		&event.ErrorMessage,
+		&event.Attempts,
+		&event.CorrelationID,
+		&event.CausationID,
+		&event.NextAttemptAt,
+		&event.CreatedAt,
+		&event.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPublishedEventNotFound
+		}
+		return nil, fmt.Errorf("failed to get published event: %w", err)
+	}
+
+	return &event, nil
+}
+
+// RequeuePublishedEvent makes an outbox entry in one of the given statuses
+// pending and due now, so the relay publishes it again. It returns false if
+// the entry is not in one of those statuses.
+func (r *EventAuditRepository) RequeuePublishedEvent(ctx context.Context, id string, statuses []string) (bool, error) {
+	now := time.Now()
+	tag, err := GetPool().Exec(ctx, `
+		UPDATE pmsn.published_events
+		SET status = $1, next_attempt_at = $2, updated_at = $2
+		WHERE id = $3 AND status = ANY($4)
+	`, model.StatusPending, now, id, statuses)
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue published event: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// ListConsumedEvents returns consumed events matching the filter, newest
+// first. Payloads are not loaded.
+func (r *EventAuditRepository) ListConsumedEvents(ctx context.Context, filter model.EventFilter) ([]model.ConsumedEvent, error) {
+	where, args := eventFilterConditions(filter)
+	args = append(args, filter.Limit, filter.Offset)
+
+	query := `
+		SELECT id, event_type, status, error_message, retry_count, created_at, updated_at
+		FROM pmsn.consumed_events
+	` + where + fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := GetPool().Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consumed events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []model.ConsumedEvent{}
+	for rows.Next() {
+		var event model.ConsumedEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.EventType,
+			&event.Status,
+			&event.ErrorMessage,
+			&event.RetryCount,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan consumed event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// MarkConsumedEventReplayed marks a consumed event in one of the given
+// statuses as replayed. It returns false if the event is not in one of those
+// statuses.
+func (r *EventAuditRepository) MarkConsumedEventReplayed(ctx context.Context, id string, statuses []string) (bool, error) {
+	tag, err := GetPool().Exec(ctx, `
+		UPDATE pmsn.consumed_events
+		SET status = $1, updated_at = $2
+		WHERE id = $3 AND status = ANY($4)
+	`, model.StatusReplayed, time.Now(), id, statuses)
+	if err != nil {
+		return false, fmt.Errorf("failed to update consumed event: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// eventFilterConditions builds the WHERE clause of an event filter, shared by
+// both event tables
+func eventFilterConditions(filter model.EventFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	addCondition := func(clause string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(clause, len(args)))
+	}
+
+	if filter.Status != "" {
+		addCondition("status = $%d", filter.Status)
+	}
+	if filter.EventType != "" {
+		addCondition("event_type = $%d", filter.EventType)
+	}
+	if filter.From != nil {
+		addCondition("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("created_at < $%d", *filter.To)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// PrunePublishedEvents deletes up to limit outbox entries in one of the given
+// statuses that were last updated before the cutoff, oldest first. The rows
+// are passed to archive, if set, before the deletion commits, so they are kept
+// if archiving fails.
+func (r *EventAuditRepository) PrunePublishedEvents(ctx context.Context, statuses []string, before time.Time, limit int, archive func([]model.PublishedEvent) error) (int, error) {
+	tx, err := GetPool().Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Rows locked by the relay or another replica are left for the next run
+	rows, err := tx.Query(ctx, `
+		DELETE FROM pmsn.published_events
+		WHERE id IN (
+			SELECT id FROM pmsn.published_events
+			WHERE status = ANY($1) AND updated_at < $2
+			ORDER BY updated_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_type, payload, status, error_message, attempts, correlation_id, causation_id, next_attempt_at, created_at, updated_at
+	`, statuses, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune published events: %w", err)
+	}
+
+	var events []model.PublishedEvent
+	for rows.Next() {
+		var event model.PublishedEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.EventType,
+			&event.Payload,
+			&event.Status,
+			&event.ErrorMessage,
+			&event.Attempts,
+			&event.CorrelationID,
+			&event.CausationID,
+			&event.NextAttemptAt,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan published event: %w", err)
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to prune published events: %w", err)
+	}
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	if archive != nil {
+		if err := archive(events); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(events), nil
+}
+
+// PruneConsumedEvents deletes up to limit consumed events in one of the given
+// statuses that were last updated before the cutoff, oldest first. The rows
+// are passed to archive, if set, before the deletion commits, so they are kept
+// if archiving fails.
+func (r *EventAuditRepository) PruneConsumedEvents(ctx context.Context, statuses []string, before time.Time, limit int, archive func([]model.ConsumedEvent) error) (int, error) {
+	tx, err := GetPool().Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Rows locked by a consumer or another replica are left for the next run
+	rows, err := tx.Query(ctx, `
+		DELETE FROM pmsn.consumed_events
+		WHERE id IN (
+			SELECT id FROM pmsn.consumed_events
+			WHERE status = ANY($1) AND updated_at < $2
+			ORDER BY updated_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_type, payload, status, error_message, retry_count, created_at, updated_at
+	`, statuses, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune consumed events: %w", err)
+	}
+
+	var events []model.ConsumedEvent
+	for rows.Next() {
+		var event model.ConsumedEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.EventType,
+			&event.Payload,
+			&event.Status,
+			&event.ErrorMessage,
+			&event.RetryCount,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan consumed event: %w", err)
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to prune consumed events: %w", err)
+	}
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	if archive != nil {
+		if err := archive(events); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(events), nil
+}
@@ -9,22 +9,58 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+const (
+	groupPermissionSnapshotQuery = "SELECT resource_id::text, action_id::text FROM pmsn.group_permission WHERE group_id = $1 ORDER BY resource_id, action_id"
+	groupUserSnapshotQuery       = "SELECT user_id::text FROM pmsn.user_group WHERE group_id = $1 AND user_id::text = ANY($2)"
+)
+
 type GroupRepository struct{}
 
 func NewGroupRepository() *GroupRepository {
 	return &GroupRepository{}
 }
 
-func (r *GroupRepository) CreateGroup(ctx context.Context, group *model.Group) error {
+// CreateGroup stores a new group. The given events are written to the outbox in the
+// same transaction.
+func (r *GroupRepository) CreateGroup(ctx context.Context, group *model.Group, events ...model.Event) error {
 	pool := GetPool()
-	_, err := pool.Exec(ctx, "INSERT INTO pmsn.group (id, name, tenant_id) VALUES ($1, $2, $3)", group.ID, group.Name, group.TenantID)
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "INSERT INTO pmsn.group (id, name, tenant_id) VALUES ($1, $2, $3)", group.ID, group.Name, group.TenantID)
 	if err != nil {
 		return fmt.Errorf("failed to create group: %w", err)
 	}
+
+	err = recordAudit(ctx, tx, auditRecord{
+		tenantID:   group.TenantID,
+		operation:  model.AuditGroupCreate,
+		entityType: model.AuditEntityGroup,
+		entityID:   group.ID,
+		after:      group,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := enqueueEvents(ctx, tx, events); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
-func (r *GroupRepository) BulkAssignPermissions(ctx context.Context, groupID string, permissions []model.Permission) error {
+// BulkAssignPermissions grants permissions to the group.
+// The events built from the change are written to the outbox in the same
+// transaction.
+func (r *GroupRepository) BulkAssignPermissions(ctx context.Context, groupID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
 	if len(permissions) == 0 {
 		return nil
 	}
@@ -36,6 +72,11 @@ func (r *GroupRepository) BulkAssignPermissions(ctx context.Context, groupID str
 	}
 	defer tx.Rollback(ctx)
 
+	before, err := permissionSnapshot(ctx, tx, groupPermissionSnapshotQuery, groupID)
+	if err != nil {
+		return err
+	}
+
 	batch := &pgx.Batch{}
 	for _, p := range permissions {
 		batch.Queue("INSERT INTO pmsn.group_permission (group_id, resource_id, action_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", groupID, p.ResourceID, p.ActionID)
@@ -55,6 +96,15 @@ func (r *GroupRepository) BulkAssignPermissions(ctx context.Context, groupID str
 		return fmt.Errorf("failed to close batch results: %w", err)
 	}
 
+	change, err := r.auditPermissions(ctx, tx, groupID, model.AuditGroupPermissionsAssign, before)
+	if err != nil {
+		return err
+	}
+
+	if err := enqueuePermissionChange(ctx, tx, change, changeEvents); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -62,7 +112,10 @@ func (r *GroupRepository) BulkAssignPermissions(ctx context.Context, groupID str
 	return nil
 }
 
-func (r *GroupRepository) BulkRemovePermissions(ctx context.Context, groupID string, permissions []model.Permission) error {
+// BulkRemovePermissions revokes permissions from the group.
+// The events built from the change are written to the outbox in the same
+// transaction.
+func (r *GroupRepository) BulkRemovePermissions(ctx context.Context, groupID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
 	if len(permissions) == 0 {
 		return nil
 	}
@@ -74,6 +127,11 @@ func (r *GroupRepository) BulkRemovePermissions(ctx context.Context, groupID str
 	}
 	defer tx.Rollback(ctx)
 
+	before, err := permissionSnapshot(ctx, tx, groupPermissionSnapshotQuery, groupID)
+	if err != nil {
+		return err
+	}
+
 	batch := &pgx.Batch{}
 	for _, p := range permissions {
 		batch.Queue("DELETE FROM pmsn.group_permission WHERE group_id = $1 AND resource_id = $2 AND action_id = $3", groupID, p.ResourceID, p.ActionID)
@@ -93,6 +151,15 @@ func (r *GroupRepository) BulkRemovePermissions(ctx context.Context, groupID str
 		return fmt.Errorf("failed to close batch results: %w", err)
 	}
 
+	change, err := r.auditPermissions(ctx, tx, groupID, model.AuditGroupPermissionsRemove, before)
+	if err != nil {
+		return err
+	}
+
+	if err := enqueuePermissionChange(ctx, tx, change, changeEvents); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -100,7 +167,10 @@ func (r *GroupRepository) BulkRemovePermissions(ctx context.Context, groupID str
 	return nil
 }
 
-func (r *GroupRepository) BulkSyncPermissions(ctx context.Context, groupID string, permissions []model.Permission) error {
+// BulkSyncPermissions replaces the group's permissions.
+// The events built from the change are written to the outbox in the same
+// transaction.
+func (r *GroupRepository) BulkSyncPermissions(ctx context.Context, groupID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
 	pool := GetPool()
 	tx, err := pool.Begin(ctx)
 	if err != nil {
@@ -108,6 +178,11 @@ func (r *GroupRepository) BulkSyncPermissions(ctx context.Context, groupID strin
 	}
 	defer tx.Rollback(ctx)
 
+	before, err := permissionSnapshot(ctx, tx, groupPermissionSnapshotQuery, groupID)
+	if err != nil {
+		return err
+	}
+
 	// 1. Delete all existing permissions for the group
 	if _, err := tx.Exec(ctx, "DELETE FROM pmsn.group_permission WHERE group_id = $1", groupID); err != nil {
 		return fmt.Errorf("failed to delete existing permissions: %w", err)
@@ -134,6 +209,15 @@ func (r *GroupRepository) BulkSyncPermissions(ctx context.Context, groupID strin
 		}
 	}
 
+	change, err := r.auditPermissions(ctx, tx, groupID, model.AuditGroupPermissionsSync, before)
+	if err != nil {
+		return err
+	}
+
+	if err := enqueuePermissionChange(ctx, tx, change, changeEvents); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -141,7 +225,9 @@ func (r *GroupRepository) BulkSyncPermissions(ctx context.Context, groupID strin
 	return nil
 }
 
-func (r *GroupRepository) BulkAssignUsers(ctx context.Context, groupID string, userIDs []string) error {
+// BulkAssignUsers assigns users to the group. The events built from the change
+// are written to the outbox in the same transaction.
+func (r *GroupRepository) BulkAssignUsers(ctx context.Context, groupID string, userIDs []string, changeEvents model.MembershipChangeEvents) error {
 	if len(userIDs) == 0 {
 		return nil
 	}
@@ -153,6 +239,11 @@ func (r *GroupRepository) BulkAssignUsers(ctx context.Context, groupID string, u
 	}
 	defer tx.Rollback(ctx)
 
+	before, err := idSnapshot(ctx, tx, groupUserSnapshotQuery, groupID, userIDs)
+	if err != nil {
+		return err
+	}
+
 	batch := &pgx.Batch{}
 	for _, uid := range userIDs {
 		batch.Queue("INSERT INTO pmsn.user_group (user_id, group_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", uid, groupID)
@@ -172,6 +263,15 @@ func (r *GroupRepository) BulkAssignUsers(ctx context.Context, groupID string, u
 		return fmt.Errorf("failed to close batch results: %w", err)
 	}
 
+	change, err := r.auditUsers(ctx, tx, groupID, userIDs, model.AuditGroupUsersAssign, before)
+	if err != nil {
+		return err
+	}
+
+	if err := enqueueMembershipChange(ctx, tx, change, changeEvents); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -179,7 +279,9 @@ func (r *GroupRepository) BulkAssignUsers(ctx context.Context, groupID string, u
 	return nil
 }
 
-func (r *GroupRepository) BulkRemoveUsers(ctx context.Context, groupID string, userIDs []string) error {
+// BulkRemoveUsers removes users from the group. The events built from the change
+// are written to the outbox in the same transaction.
+func (r *GroupRepository) BulkRemoveUsers(ctx context.Context, groupID string, userIDs []string, changeEvents model.MembershipChangeEvents) error {
 	if len(userIDs) == 0 {
 		return nil
 	}
@@ -191,6 +293,11 @@ func (r *GroupRepository) BulkRemoveUsers(ctx context.Context, groupID string, u
 	}
 	defer tx.Rollback(ctx)
 
+	before, err := idSnapshot(ctx, tx, groupUserSnapshotQuery, groupID, userIDs)
+	if err != nil {
+		return err
+	}
+
 	batch := &pgx.Batch{}
 	for _, uid := range userIDs {
 		batch.Queue("DELETE FROM pmsn.user_group WHERE user_id = $1 AND group_id = $2", uid, groupID)
@@ -210,9 +317,80 @@ func (r *GroupRepository) BulkRemoveUsers(ctx context.Context, groupID string, u
 		return fmt.Errorf("failed to close batch results: %w", err)
 	}
 
+	change, err := r.auditUsers(ctx, tx, groupID, userIDs, model.AuditGroupUsersRemove, before)
+	if err != nil {
+		return err
+	}
+
+	if err := enqueueMembershipChange(ctx, tx, change, changeEvents); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
+
+// auditPermissions records the group's permission set before and after a change
+// and returns the change
+func (r *GroupRepository) auditPermissions(ctx context.Context, tx pgx.Tx, groupID, operation string, before []model.Permission) (model.PermissionChange, error) {
+	after, err := permissionSnapshot(ctx, tx, groupPermissionSnapshotQuery, groupID)
+	if err != nil {
+		return model.PermissionChange{}, err
+	}
+
+	tenantID, err := entityTenant(ctx, tx, "pmsn.group", groupID)
+	if err != nil {
+		return model.PermissionChange{}, err
+	}
+
+	change := diffPermissions(before, after)
+	err = recordAudit(ctx, tx, auditRecord{
+		tenantID:   tenantID,
+		operation:  operation,
+		entityType: model.AuditEntityGroup,
+		entityID:   groupID,
+		before:     before,
+		after:      after,
+		diff:       change,
+	})
+	if err != nil {
+		return model.PermissionChange{}, err
+	}
+
+	change.TenantID = tenantID
+	return change, nil
+}
+
+// auditUsers records which of the affected users held the group before and after a change
+// and returns the change
+func (r *GroupRepository) auditUsers(ctx context.Context, tx pgx.Tx, groupID string, userIDs []string, operation string, before []string) (model.MembershipChange, error) {
+	after, err := idSnapshot(ctx, tx, groupUserSnapshotQuery, groupID, userIDs)
+	if err != nil {
+		return model.MembershipChange{}, err
+	}
+
+	tenantID, err := entityTenant(ctx, tx, "pmsn.group", groupID)
+	if err != nil {
+		return model.MembershipChange{}, err
+	}
+
+	change := diffIDs(before, after)
+	err = recordAudit(ctx, tx, auditRecord{
+		tenantID:   tenantID,
+		operation:  operation,
+		entityType: model.AuditEntityGroup,
+		entityID:   groupID,
+		before:     before,
+		after:      after,
+		diff:       change,
+	})
+	if err != nil {
+		return model.MembershipChange{}, err
+	}
+
+	change.TenantID = tenantID
+	return change, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"rbac-service/internal/model"
+	"rbac-service/internal/reqctx"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// execer is satisfied by both the pool and a transaction
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// enqueueEvents writes events to the outbox as pending. When called with a
+// transaction the events are only published if the transaction commits.
+// Events without correlation or causation IDs take them from ctx.
+func enqueueEvents(ctx context.Context, db execer, events []model.Event) error {
+	for _, event := range events {
+		if event.CorrelationID == "" {
+			event.CorrelationID = reqctx.CorrelationID(ctx)
+		}
+		if event.CausationID == "" {
+			event.CausationID = reqctx.CausationID(ctx)
+		}
+
+		payload, err := json.Marshal(event.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event payload: %w", err)
+		}
+
+		createdAt := event.Timestamp
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+
+		_, err = db.Exec(ctx, `
+			INSERT INTO pmsn.published_events (id, event_type, payload, status, attempts, correlation_id, causation_id, next_attempt_at, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, 0, $5, $6, $7, $7, $7)
+		`, event.ID, event.Type, payload, model.StatusPending, nullableString(event.CorrelationID), nullableString(event.CausationID), createdAt)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue event: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// enqueuePermissionChange writes the events built for a permission change to
+// the outbox. A nil builder writes nothing.
+func enqueuePermissionChange(ctx context.Context, db execer, change model.PermissionChange, build model.PermissionChangeEvents) error {
+	if build == nil {
+		return nil
+	}
+	return enqueueEvents(ctx, db, build(change))
+}
+
+// enqueueMembershipChange writes the events built for a membership change to
+// the outbox. A nil builder writes nothing.
+func enqueueMembershipChange(ctx context.Context, db execer, change model.MembershipChange, build model.MembershipChangeEvents) error {
+	if build == nil {
+		return nil
+	}
+	return enqueueEvents(ctx, db, build(change))
+}
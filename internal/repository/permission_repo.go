@@ -91,7 +91,9 @@ func (r *PermissionRepository) GetUserPermissions(ctx context.Context, userID, t
 
 // CheckMiddlewarePermissionsWithMV performs an optimized check using the materialized view.
 // This is significantly faster than CheckMiddlewarePermissions as it uses pre-computed permissions.
-func (r *PermissionRepository) CheckMiddlewarePermissionsWithMV(ctx context.Context, userID string, tenantID *string, permRes, permAct, assocRes, assocAct string) (bool, error) {
+// global reports whether the user holds the permission globally rather than
+// only within the tenant.
+func (r *PermissionRepository) CheckMiddlewarePermissionsWithMV(ctx context.Context, userID string, tenantID *string, permRes, permAct, assocRes, assocAct string) (allowed bool, global bool, err error) {
 	pool := GetPool()
 
 	query := `
@@ -102,9 +104,7 @@ func (r *PermissionRepository) CheckMiddlewarePermissionsWithMV(ctx context.Cont
 			AND mvp.resource_code = $2 
 			AND mvp.action_code = $3
 			AND mvp.tenant_id IS NULL
-			
-			UNION
-			
+		), EXISTS (
 			-- 2. Tenant Permission Check (if tenant provided)
 			SELECT 1 FROM pmsn.mv_user_permissions mvp
 			JOIN pmsn.resource_action_tenant rat 
@@ -143,10 +143,10 @@ func (r *PermissionRepository) CheckMiddlewarePermissionsWithMV(ctx context.Cont
 		)
 	`
 
-	var allowed bool
-	err := pool.QueryRow(ctx, query, userID, permRes, permAct, tenantID, assocRes, assocAct).Scan(&allowed)
+	var inTenant bool
+	err = pool.QueryRow(ctx, query, userID, permRes, permAct, tenantID, assocRes, assocAct).Scan(&global, &inTenant)
 	if err != nil {
-		return false, fmt.Errorf("failed to check permissions with MV: %w", err)
+		return false, false, fmt.Errorf("failed to check permissions with MV: %w", err)
 	}
-	return allowed, nil
+	return global || inTenant, global, nil
 }
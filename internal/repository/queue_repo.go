@@ -0,0 +1,381 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"rbac-service/internal/model"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// QueueNotifyChannel is the LISTEN/NOTIFY channel carrying the names of queues that received messages
+const QueueNotifyChannel = "pmsn_queue"
+
+var (
+	// ErrQueueExchangeNotFound is returned when publishing or binding to an undeclared exchange
+	ErrQueueExchangeNotFound = errors.New("exchange not found")
+	// ErrQueueLeaseLost is returned when settling a message whose visibility
+	// timeout expired and that was claimed again, or that is already gone
+	ErrQueueLeaseLost = errors.New("message lease lost")
+)
+
+// QueueRepository handles database operations for the Postgres queue provider tables
+type QueueRepository struct{}
+
+// NewQueueRepository creates a new queue repository
+func NewQueueRepository() *QueueRepository {
+	return &QueueRepository{}
+}
+
+// Ping checks the database connection
+func (r *QueueRepository) Ping(ctx context.Context) error {
+	if GetPool() == nil {
+		return fmt.Errorf("database is not initialized")
+	}
+	return GetPool().Ping(ctx)
+}
+
+// DeclareExchange creates an exchange, failing if it exists with another type
+func (r *QueueRepository) DeclareExchange(ctx context.Context, name, exchangeType string) error {
+	query := `
+		WITH inserted AS (
+			INSERT INTO pmsn.queue_exchange (name, type, created_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (name) DO NOTHING
+			RETURNING type
+		)
+		SELECT type FROM inserted
+		UNION ALL
+		SELECT type FROM pmsn.queue_exchange WHERE name = $1
+		LIMIT 1
+	`
+
+	var existing string
+	if err := GetPool().QueryRow(ctx, query, name, exchangeType, time.Now()).Scan(&existing); err != nil {
+		return fmt.Errorf("failed to declare exchange: %w", err)
+	}
+
+	if existing != exchangeType {
+		return fmt.Errorf("exchange %s already declared as %s", name, existing)
+	}
+	return nil
+}
+
+// DeclareQueue creates a queue if it does not exist
+func (r *QueueRepository) DeclareQueue(ctx context.Context, name string) error {
+	query := `
+		INSERT INTO pmsn.queue (name, created_at)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO NOTHING
+	`
+
+	if _, err := GetPool().Exec(ctx, query, name, time.Now()); err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+	return nil
+}
+
+// BindQueue binds a queue to an exchange with a routing key pattern
+func (r *QueueRepository) BindQueue(ctx context.Context, binding model.QueueBinding) error {
+	query := `
+		INSERT INTO pmsn.queue_binding (queue, exchange, routing_key)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := GetPool().Exec(ctx, query, binding.Queue, binding.Exchange, binding.RoutingKey); err != nil {
+		return fmt.Errorf("failed to bind queue: %w", err)
+	}
+	return nil
+}
+
+// GetExchangeBindings returns an exchange's type and the bindings of its queues
+func (r *QueueRepository) GetExchangeBindings(ctx context.Context, exchange string) (string, []model.QueueBinding, error) {
+	var exchangeType string
+	err := GetPool().QueryRow(ctx, `SELECT type FROM pmsn.queue_exchange WHERE name = $1`, exchange).Scan(&exchangeType)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil, ErrQueueExchangeNotFound
+		}
+		return "", nil, fmt.Errorf("failed to get exchange: %w", err)
+	}
+
+	rows, err := GetPool().Query(ctx, `
+		SELECT queue, exchange, routing_key
+		FROM pmsn.queue_binding
+		WHERE exchange = $1
+	`, exchange)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list bindings: %w", err)
+	}
+	defer rows.Close()
+
+	var bindings []model.QueueBinding
+	for rows.Next() {
+		var b model.QueueBinding
+		if err := rows.Scan(&b.Queue, &b.Exchange, &b.RoutingKey); err != nil {
+			return "", nil, fmt.Errorf("failed to scan binding: %w", err)
+		}
+		bindings = append(bindings, b)
+	}
+
+	return exchangeType, bindings, rows.Err()
+}
+
+// EnqueueMessage stores a copy of a message in each of the queues that exist
+// and notifies their consumers. The message becomes visible at availableAt.
+// It returns the number of queues the message was stored in.
+func (r *QueueRepository) EnqueueMessage(ctx context.Context, queues []string, routingKey string, headers map[string]string, body []byte, availableAt time.Time) (int, error) {
+	if len(queues) == 0 {
+		return 0, nil
+	}
+
+	headersJSON, err := marshalHeaders(headers)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		WITH inserted AS (
+			INSERT INTO pmsn.queue_message (queue, routing_key, headers, body, available_at, created_at)
+			SELECT q.name, $2, $3, $4, $5, $6
+			FROM pmsn.queue q
+			WHERE q.name = ANY($1)
+			RETURNING queue
+		),
+		notified AS (
+			SELECT pg_notify($7, queue) FROM (SELECT DISTINCT queue FROM inserted) AS queues
+		)
+		SELECT (SELECT count(*) FROM inserted), (SELECT count(*) FROM notified)
+	`
+
+	var stored, notified int
+	err = GetPool().QueryRow(ctx, query, queues, routingKey, headersJSON, body, availableAt, time.Now(), QueueNotifyChannel).Scan(&stored, &notified)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue message: %w", err)
+	}
+	return stored, nil
+}
+
+// DeadLetterMessage moves a claimed message to the queues that exist of the
+// given ones, replacing its routing key and headers, and notifies their
+// consumers. The message is kept where it is if none of the queues exist. It
+// returns the number of queues the message was moved to, or ErrQueueLeaseLost
+// if the message was claimed again since the claim with deliveryCount.
+func (r *QueueRepository) DeadLetterMessage(ctx context.Context, id int64, deliveryCount int, queues []string, routingKey string, headers map[string]string) (int, error) {
+	headersJSON, err := marshalHeaders(headers)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := GetPool().Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked int64
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM pmsn.queue_message WHERE id = $1 AND delivery_count = $2 FOR UPDATE
+	`, id, deliveryCount).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrQueueLeaseLost
+		}
+		return 0, fmt.Errorf("failed to lock message: %w", err)
+	}
+
+	var stored, notified int
+	err = tx.QueryRow(ctx, `
+		WITH inserted AS (
+			INSERT INTO pmsn.queue_message (queue, routing_key, headers, body, available_at, created_at)
+			SELECT q.name, $3, $4, m.body, $5, $5
+			FROM pmsn.queue q, pmsn.queue_message m
+			WHERE q.name = ANY($2) AND m.id = $1
+			RETURNING queue
+		),
+		notified AS (
+			SELECT pg_notify($6, queue) FROM (SELECT DISTINCT queue FROM inserted) AS queues
+		)
+		SELECT (SELECT count(*) FROM inserted), (SELECT count(*) FROM notified)
+	`, id, queues, routingKey, headersJSON, time.Now(), QueueNotifyChannel).Scan(&stored, &notified)
+	if err != nil {
+		return 0, fmt.Errorf("failed to dead-letter message: %w", err)
+	}
+
+	if stored == 0 {
+		return 0, nil
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM pmsn.queue_message WHERE id = $1`, id); err != nil {
+		return 0, fmt.Errorf("failed to delete dead-lettered message: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return stored, nil
+}
+
+// ClaimMessages returns up to limit visible messages of a queue, oldest first.
+// Claimed messages are hidden for the visibility timeout and their delivery
+// count is incremented, so they reappear if they are not acknowledged in time.
+func (r *QueueRepository) ClaimMessages(ctx context.Context, queue string, limit int, visibilityTimeout time.Duration) ([]model.QueueMessage, error) {
+	query := `
+		UPDATE pmsn.queue_message
+		SET available_at = $1, delivery_count = delivery_count + 1
+		WHERE id IN (
+			SELECT id FROM pmsn.queue_message
+			WHERE queue = $2 AND available_at <= $3
+			ORDER BY id
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, queue, routing_key, headers, body, delivery_count, available_at, created_at
+	`
+
+	now := time.Now()
+	rows, err := GetPool().Query(ctx, query, now.Add(visibilityTimeout), queue, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []model.QueueMessage
+	for rows.Next() {
+		var msg model.QueueMessage
+		var headers []byte
+		err := rows.Scan(
+			&msg.ID,
+			&msg.Queue,
+			&msg.RoutingKey,
+			&headers,
+			&msg.Body,
+			&msg.DeliveryCount,
+			&msg.AvailableAt,
+			&msg.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		if err := json.Unmarshal(headers, &msg.Headers); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal message headers: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not preserve the subquery order
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+
+	return messages, nil
+}
+
+// DeleteMessage acknowledges a message claimed with deliveryCount. It returns
+// ErrQueueLeaseLost if the message was claimed again since, so a delivery that
+// outlived its visibility timeout can't delete a copy in flight elsewhere.
+func (r *QueueRepository) DeleteMessage(ctx context.Context, id int64, deliveryCount int) error {
+	tag, err := GetPool().Exec(ctx, `DELETE FROM pmsn.queue_message WHERE id = $1 AND delivery_count = $2`, id, deliveryCount)
+	if err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrQueueLeaseLost
+	}
+	return nil
+}
+
+// ReleaseMessage makes a message claimed with deliveryCount visible again at
+// availableAt. It returns ErrQueueLeaseLost if the message was claimed again since.
+func (r *QueueRepository) ReleaseMessage(ctx context.Context, id int64, deliveryCount int, availableAt time.Time) error {
+	tag, err := GetPool().Exec(ctx, `
+		UPDATE pmsn.queue_message SET available_at = $1
+		WHERE id = $2 AND delivery_count = $3
+	`, availableAt, id, deliveryCount)
+	if err != nil {
+		return fmt.Errorf("failed to release message: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrQueueLeaseLost
+	}
+	return nil
+}
+
+// PurgeQueue deletes the visible messages of a queue. Claimed and delayed
+// messages are kept.
+func (r *QueueRepository) PurgeQueue(ctx context.Context, queue string) (int, error) {
+	tag, err := GetPool().Exec(ctx, `
+		DELETE FROM pmsn.queue_message
+		WHERE queue = $1 AND available_at <= $2
+	`, queue, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge queue: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// marshalHeaders encodes message headers for the headers column
+func marshalHeaders(headers map[string]string) ([]byte, error) {
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	data, err := json.Marshal(headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message headers: %w", err)
+	}
+	return data, nil
+}
+
+// QueueListener receives the names of queues that got new messages
+type QueueListener struct {
+	conn *pgxpool.Conn
+}
+
+// Listen holds a pool connection listening on QueueNotifyChannel until the listener is closed
+func (r *QueueRepository) Listen(ctx context.Context) (*QueueListener, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	if _, err := conn.Exec(ctx, "LISTEN "+QueueNotifyChannel); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	return &QueueListener{conn: conn}, nil
+}
+
+// Wait blocks until a notification arrives and returns the queue name
+func (l *QueueListener) Wait(ctx context.Context) (string, error) {
+	notification, err := l.conn.Conn().WaitForNotification(ctx)
+	if err != nil {
+		return "", err
+	}
+	return notification.Payload, nil
+}
+
+// Close stops listening and returns the connection to the pool. A connection
+// interrupted mid-wait is closed instead, since its state is unknown.
+func (l *QueueListener) Close() {
+	if l.conn.Conn().IsClosed() {
+		l.conn.Release()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := l.conn.Exec(ctx, "UNLISTEN "+QueueNotifyChannel); err != nil {
+		l.conn.Conn().Close(ctx)
+	}
+	l.conn.Release()
+}
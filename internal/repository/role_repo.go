@@ -9,22 +9,58 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+const (
+	rolePermissionSnapshotQuery = "SELECT resource_id::text, action_id::text FROM pmsn.role_permission WHERE role_id = $1 ORDER BY resource_id, action_id"
+	roleUserSnapshotQuery       = "SELECT user_id::text FROM pmsn.user_role WHERE role_id = $1 AND user_id::text = ANY($2)"
+)
+
 type RoleRepository struct{}
 
 func NewRoleRepository() *RoleRepository {
 	return &RoleRepository{}
 }
 
-func (r *RoleRepository) CreateRole(ctx context.Context, role *model.Role) error {
+// CreateRole stores a new role. The given events are written to the outbox in the
+// same transaction.
+func (r *RoleRepository) CreateRole(ctx context.Context, role *model.Role, events ...model.Event) error {
 	pool := GetPool()
-	_, err := pool.Exec(ctx, "INSERT INTO pmsn.role (id, name, tenant_id) VALUES ($1, $2, $3)", role.ID, role.Name, role.TenantID)
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "INSERT INTO pmsn.role (id, name, tenant_id) VALUES ($1, $2, $3)", role.ID, role.Name, role.TenantID)
 	if err != nil {
 		return fmt.Errorf("failed to create role: %w", err)
 	}
+
+	err = recordAudit(ctx, tx, auditRecord{
+		tenantID:   role.TenantID,
+		operation:  model.AuditRoleCreate,
+		entityType: model.AuditEntityRole,
+		entityID:   role.ID,
+		after:      role,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := enqueueEvents(ctx, tx, events); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
-func (r *RoleRepository) BulkAssignPermissions(ctx context.Context, roleID string, permissions []model.Permission) error {
+// BulkAssignPermissions grants permissions to the role.
+// The events built from the change are written to the outbox in the same
+// transaction.
+func (r *RoleRepository) BulkAssignPermissions(ctx context.Context, roleID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
 	if len(permissions) == 0 {
 		return nil
 	}
@@ -36,6 +72,11 @@ func (r *RoleRepository) BulkAssignPermissions(ctx context.Context, roleID strin
 	}
 	defer tx.Rollback(ctx)
 
+	before, err := permissionSnapshot(ctx, tx, rolePermissionSnapshotQuery, roleID)
+	if err != nil {
+		return err
+	}
+
 	batch := &pgx.Batch{}
 	for _, p := range permissions {
 		batch.Queue("INSERT INTO pmsn.role_permission (role_id, resource_id, action_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", roleID, p.ResourceID, p.ActionID)
@@ -55,6 +96,15 @@ func (r *RoleRepository) BulkAssignPermissions(ctx context.Context, roleID strin
 		return fmt.Errorf("failed to close batch results: %w", err)
 	}
 
+	change, err := r.auditPermissions(ctx, tx, roleID, model.AuditRolePermissionsAssign, before)
+	if err != nil {
+		return err
+	}
+
+	if err := enqueuePermissionChange(ctx, tx, change, changeEvents); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -62,7 +112,10 @@ func (r *RoleRepository) BulkAssignPermissions(ctx context.Context, roleID strin
 	return nil
 }
 
-func (r *RoleRepository) BulkRemovePermissions(ctx context.Context, roleID string, permissions []model.Permission) error {
+// BulkRemovePermissions revokes permissions from the role.
+// The events built from the change are written to the outbox in the same
+// transaction.
+func (r *RoleRepository) BulkRemovePermissions(ctx context.Context, roleID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
 	if len(permissions) == 0 {
 		return nil
 	}
@@ -74,6 +127,11 @@ func (r *RoleRepository) BulkRemovePermissions(ctx context.Context, roleID strin
 	}
 	defer tx.Rollback(ctx)
 
+	before, err := permissionSnapshot(ctx, tx, rolePermissionSnapshotQuery, roleID)
+	if err != nil {
+		return err
+	}
+
 	batch := &pgx.Batch{}
 	for _, p := range permissions {
 		batch.Queue("DELETE FROM pmsn.role_permission WHERE role_id = $1 AND resource_id = $2 AND action_id = $3", roleID, p.ResourceID, p.ActionID)
@@ -93,6 +151,15 @@ func (r *RoleRepository) BulkRemovePermissions(ctx context.Context, roleID strin
 		return fmt.Errorf("failed to close batch results: %w", err)
 	}
 
+	change, err := r.auditPermissions(ctx, tx, roleID, model.AuditRolePermissionsRemove, before)
+	if err != nil {
+		return err
+	}
+
+	if err := enqueuePermissionChange(ctx, tx, change, changeEvents); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -100,7 +167,10 @@ func (r *RoleRepository) BulkRemovePermissions(ctx context.Context, roleID strin
 	return nil
 }
 
-func (r *RoleRepository) BulkSyncPermissions(ctx context.Context, roleID string, permissions []model.Permission) error {
+// BulkSyncPermissions replaces the role's permissions.
+// The events built from the change are written to the outbox in the same
+// transaction.
+func (r *RoleRepository) BulkSyncPermissions(ctx context.Context, roleID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
 	pool := GetPool()
 	tx, err := pool.Begin(ctx)
 	if err != nil {
@@ -108,6 +178,11 @@ func (r *RoleRepository) BulkSyncPermissions(ctx context.Context, roleID string,
 	}
 	defer tx.Rollback(ctx)
 
+	before, err := permissionSnapshot(ctx, tx, rolePermissionSnapshotQuery, roleID)
+	if err != nil {
+		return err
+	}
+
 	// 1. Delete all existing permissions for the role
 	if _, err := tx.Exec(ctx, "DELETE FROM pmsn.role_permission WHERE role_id = $1", roleID); err != nil {
 		return fmt.Errorf("failed to delete existing permissions: %w", err)
@@ -134,6 +209,15 @@ func (r *RoleRepository) BulkSyncPermissions(ctx context.Context, roleID string,
 		}
 	}
 
+	change, err := r.auditPermissions(ctx, tx, roleID, model.AuditRolePermissionsSync, before)
+	if err != nil {
+		return err
+	}
+
+	if err := enqueuePermissionChange(ctx, tx, change, changeEvents); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -141,7 +225,9 @@ func (r *RoleRepository) BulkSyncPermissions(ctx context.Context, roleID string,
 	return nil
 }
 
-func (r *RoleRepository) BulkAssignUsers(ctx context.Context, roleID string, userIDs []string) error {
+// BulkAssignUsers assigns users to the role. The events built from the change
+// are written to the outbox in the same transaction.
+func (r *RoleRepository) BulkAssignUsers(ctx context.Context, roleID string, userIDs []string, changeEvents model.MembershipChangeEvents) error {
 	if len(userIDs) == 0 {
 		return nil
 	}
@@ -153,6 +239,11 @@ func (r *RoleRepository) BulkAssignUsers(ctx context.Context, roleID string, use
 	}
 	defer tx.Rollback(ctx)
 
+	before, err := idSnapshot(ctx, tx, roleUserSnapshotQuery, roleID, userIDs)
+	if err != nil {
+		return err
+	}
+
 	batch := &pgx.Batch{}
 	for _, uid := range userIDs {
 		batch.Queue("INSERT INTO pmsn.user_role (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", uid, roleID)
@@ -172,6 +263,15 @@ func (r *RoleRepository) BulkAssignUsers(ctx context.Context, roleID string, use
 		return fmt.Errorf("failed to close batch results: %w", err)
 	}
 
+	change, err := r.auditUsers(ctx, tx, roleID, userIDs, model.AuditRoleUsersAssign, before)
+	if err != nil {
+		return err
+	}
+
+	if err := enqueueMembershipChange(ctx, tx, change, changeEvents); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -179,7 +279,9 @@ func (r *RoleRepository) BulkAssignUsers(ctx context.Context, roleID string, use
 	return nil
 }
 
-func (r *RoleRepository) BulkRemoveUsers(ctx context.Context, roleID string, userIDs []string) error {
+// BulkRemoveUsers removes users from the role. The events built from the change
+// are written to the outbox in the same transaction.
+func (r *RoleRepository) BulkRemoveUsers(ctx context.Context, roleID string, userIDs []string, changeEvents model.MembershipChangeEvents) error {
 	if len(userIDs) == 0 {
 		return nil
 	}
@@ -191,6 +293,11 @@ func (r *RoleRepository) BulkRemoveUsers(ctx context.Context, roleID string, use
 	}
 	defer tx.Rollback(ctx)
 
+	before, err := idSnapshot(ctx, tx, roleUserSnapshotQuery, roleID, userIDs)
+	if err != nil {
+		return err
+	}
+
 	batch := &pgx.Batch{}
 	for _, uid := range userIDs {
 		batch.Queue("DELETE FROM pmsn.user_role WHERE user_id = $1 AND role_id = $2", uid, roleID)
@@ -210,9 +317,80 @@ func (r *RoleRepository) BulkRemoveUsers(ctx context.Context, roleID string, use
 		return fmt.Errorf("failed to close batch results: %w", err)
 	}
 
+	change, err := r.auditUsers(ctx, tx, roleID, userIDs, model.AuditRoleUsersRemove, before)
+	if err != nil {
+		return err
+	}
+
+	if err := enqueueMembershipChange(ctx, tx, change, changeEvents); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
+
+// auditPermissions records the role's permission set before and after a change
+// and returns the change
+func (r *RoleRepository) auditPermissions(ctx context.Context, tx pgx.Tx, roleID, operation string, before []model.Permission) (model.PermissionChange, error) {
+	after, err := permissionSnapshot(ctx, tx, rolePermissionSnapshotQuery, roleID)
+	if err != nil {
+		return model.PermissionChange{}, err
+	}
+
+	tenantID, err := entityTenant(ctx, tx, "pmsn.role", roleID)
+	if err != nil {
+		return model.PermissionChange{}, err
+	}
+
+	change := diffPermissions(before, after)
+	err = recordAudit(ctx, tx, auditRecord{
+		tenantID:   tenantID,
+		operation:  operation,
+		entityType: model.AuditEntityRole,
+		entityID:   roleID,
+		before:     before,
+		after:      after,
+		diff:       change,
+	})
+	if err != nil {
+		return model.PermissionChange{}, err
+	}
+
+	change.TenantID = tenantID
+	return change, nil
+}
+
+// auditUsers records which of the affected users held the role before and after a change
+// and returns the change
+func (r *RoleRepository) auditUsers(ctx context.Context, tx pgx.Tx, roleID string, userIDs []string, operation string, before []string) (model.MembershipChange, error) {
+	after, err := idSnapshot(ctx, tx, roleUserSnapshotQuery, roleID, userIDs)
+	if err != nil {
+		return model.MembershipChange{}, err
+	}
+
+	tenantID, err := entityTenant(ctx, tx, "pmsn.role", roleID)
+	if err != nil {
+		return model.MembershipChange{}, err
+	}
+
+	change := diffIDs(before, after)
+	err = recordAudit(ctx, tx, auditRecord{
+		tenantID:   tenantID,
+		operation:  operation,
+		entityType: model.AuditEntityRole,
+		entityID:   roleID,
+		before:     before,
+		after:      after,
+		diff:       change,
+	})
+	if err != nil {
+		return model.MembershipChange{}, err
+	}
+
+	change.TenantID = tenantID
+	return change, nil
+}
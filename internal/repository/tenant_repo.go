@@ -9,13 +9,18 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+const tenantPermissionSnapshotQuery = "SELECT resource_id::text, action_id::text FROM pmsn.resource_action_tenant WHERE tenant_id = $1 ORDER BY resource_id, action_id"
+
 type TenantRepository struct{}
 
 func NewTenantRepository() *TenantRepository {
 	return &TenantRepository{}
 }
 
-func (r *TenantRepository) BulkAssignPermissions(ctx context.Context, tenantID string, permissions []model.Permission) error {
+// BulkAssignPermissions grants permissions to the tenant.
+// The events built from the change are written to the outbox in the same
+// transaction.
+func (r *TenantRepository) BulkAssignPermissions(ctx context.Context, tenantID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
 	if len(permissions) == 0 {
 		return nil
 	}
@@ -27,6 +32,11 @@ func (r *TenantRepository) BulkAssignPermissions(ctx context.Context, tenantID s
 	}
 	defer tx.Rollback(ctx)
 
+	before, err := permissionSnapshot(ctx, tx, tenantPermissionSnapshotQuery, tenantID)
+	if err != nil {
+		return err
+	}
+
 	// Prepare batch insert
 	batch := &pgx.Batch{}
 	for _, p := range permissions {
@@ -48,6 +58,15 @@ func (r *TenantRepository) BulkAssignPermissions(ctx context.Context, tenantID s
 		return fmt.Errorf("failed to close batch results: %w", err)
 	}
 
+	change, err := r.auditPermissions(ctx, tx, tenantID, model.AuditTenantPermissionsAssign, before)
+	if err != nil {
+		return err
+	}
+
+	if err := enqueuePermissionChange(ctx, tx, change, changeEvents); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -55,7 +74,10 @@ func (r *TenantRepository) BulkAssignPermissions(ctx context.Context, tenantID s
 	return nil
 }
 
-func (r *TenantRepository) BulkRemovePermissions(ctx context.Context, tenantID string, permissions []model.Permission) error {
+// BulkRemovePermissions revokes permissions from the tenant.
+// The events built from the change are written to the outbox in the same
+// transaction.
+func (r *TenantRepository) BulkRemovePermissions(ctx context.Context, tenantID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
 	if len(permissions) == 0 {
 		return nil
 	}
@@ -67,6 +89,11 @@ func (r *TenantRepository) BulkRemovePermissions(ctx context.Context, tenantID s
 	}
 	defer tx.Rollback(ctx)
 
+	before, err := permissionSnapshot(ctx, tx, tenantPermissionSnapshotQuery, tenantID)
+	if err != nil {
+		return err
+	}
+
 	batch := &pgx.Batch{}
 	for _, p := range permissions {
 		batch.Queue("DELETE FROM pmsn.resource_action_tenant WHERE resource_id = $1 AND action_id = $2 AND tenant_id = $3", p.ResourceID, p.ActionID, tenantID)
@@ -86,6 +113,15 @@ func (r *TenantRepository) BulkRemovePermissions(ctx context.Context, tenantID s
 		return fmt.Errorf("failed to close batch results: %w", err)
 	}
 
+	change, err := r.auditPermissions(ctx, tx, tenantID, model.AuditTenantPermissionsRemove, before)
+	if err != nil {
+		return err
+	}
+
+	if err := enqueuePermissionChange(ctx, tx, change, changeEvents); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -93,7 +129,10 @@ func (r *TenantRepository) BulkRemovePermissions(ctx context.Context, tenantID s
 	return nil
 }
 
-func (r *TenantRepository) BulkSyncPermissions(ctx context.Context, tenantID string, permissions []model.Permission) error {
+// BulkSyncPermissions replaces the tenant's permissions.
+// The events built from the change are written to the outbox in the same
+// transaction.
+func (r *TenantRepository) BulkSyncPermissions(ctx context.Context, tenantID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
 	pool := GetPool()
 	tx, err := pool.Begin(ctx)
 	if err != nil {
@@ -101,6 +140,11 @@ func (r *TenantRepository) BulkSyncPermissions(ctx context.Context, tenantID str
 	}
 	defer tx.Rollback(ctx)
 
+	before, err := permissionSnapshot(ctx, tx, tenantPermissionSnapshotQuery, tenantID)
+	if err != nil {
+		return err
+	}
+
 	// 1. Delete all existing permissions for the tenant
 	if _, err := tx.Exec(ctx, "DELETE FROM pmsn.resource_action_tenant WHERE tenant_id = $1", tenantID); err != nil {
 		return fmt.Errorf("failed to delete existing permissions: %w", err)
@@ -127,6 +171,15 @@ func (r *TenantRepository) BulkSyncPermissions(ctx context.Context, tenantID str
 		}
 	}
 
+	change, err := r.auditPermissions(ctx, tx, tenantID, model.AuditTenantPermissionsSync, before)
+	if err != nil {
+		return err
+	}
+
+	if err := enqueuePermissionChange(ctx, tx, change, changeEvents); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -153,3 +206,29 @@ func (r *TenantRepository) GetTenantPermissions(ctx context.Context, tenantID st
 
 	return permissions, nil
 }
+
+// auditPermissions records the tenant's entitlements before and after a change
+// and returns the change
+func (r *TenantRepository) auditPermissions(ctx context.Context, tx pgx.Tx, tenantID, operation string, before []model.Permission) (model.PermissionChange, error) {
+	after, err := permissionSnapshot(ctx, tx, tenantPermissionSnapshotQuery, tenantID)
+	if err != nil {
+		return model.PermissionChange{}, err
+	}
+
+	change := diffPermissions(before, after)
+	err = recordAudit(ctx, tx, auditRecord{
+		tenantID:   tenantID,
+		operation:  operation,
+		entityType: model.AuditEntityTenant,
+		entityID:   tenantID,
+		before:     before,
+		after:      after,
+		diff:       change,
+	})
+	if err != nil {
+		return model.PermissionChange{}, err
+	}
+
+	change.TenantID = tenantID
+	return change, nil
+}
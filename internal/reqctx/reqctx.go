@@ -0,0 +1,49 @@
+package reqctx
+
+import "context"
+
+type contextKey string
+
+const (
+	requestIDKey     contextKey = "request_id"
+	correlationIDKey contextKey = "correlation_id"
+	causationIDKey   contextKey = "causation_id"
+)
+
+// HeaderRequestID is the HTTP header carrying the request ID
+const HeaderRequestID = "X-Request-ID"
+
+// WithRequestID returns a context carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, if any
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// WithCorrelationID returns a context carrying the ID shared by all requests
+// and events started by the same original request
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey, correlationID)
+}
+
+// CorrelationID returns the correlation ID carried by ctx, if any
+func CorrelationID(ctx context.Context) string {
+	correlationID, _ := ctx.Value(correlationIDKey).(string)
+	return correlationID
+}
+
+// WithCausationID returns a context carrying the ID of the request or event
+// being handled, which causes any events published while handling it
+func WithCausationID(ctx context.Context, causationID string) context.Context {
+	return context.WithValue(ctx, causationIDKey, causationID)
+}
+
+// CausationID returns the causation ID carried by ctx, if any
+func CausationID(ctx context.Context) string {
+	causationID, _ := ctx.Value(causationIDKey).(string)
+	return causationID
+}
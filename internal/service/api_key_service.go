@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"rbac-service/internal/logger"
+	"rbac-service/internal/model"
+	"rbac-service/internal/repository"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	apiKeyPrefix = "rbk"
+	// apiKeyTouchInterval limits how often last_used_at is written for a key
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	// ErrInvalidAPIKey is returned when a key is unknown, revoked or expired
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrTenantNotAuthorized is returned when an API key would belong to a
+	// tenant other than the one the caller is authorized for
+	ErrTenantNotAuthorized = errors.New("caller is not authorized for the tenant")
+	// ErrRoleOutsideTenant is returned when a tenant-scoped caller binds an API
+	// key to a global role or a role of another tenant
+	ErrRoleOutsideTenant = errors.New("role does not belong to the tenant")
+)
+
+type APIKeyService struct {
+	apiKeyRepo *repository.APIKeyRepository
+}
+
+func NewAPIKeyService(apiKeyRepo *repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+// CreateAPIKey generates a new key and returns it with its plaintext secret.
+// Only the SHA-256 hash of the secret is stored. The events built for the new
+// key are written to the outbox with it; events may be nil.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, name, tenantID, createdBy string, expiresAt *time.Time, roleIDs []string, events func(key *model.APIKey) []model.Event) (*model.APIKey, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	id := uuid.New().String()
+	prefix := apiKeyPrefix + "_" + strings.ReplaceAll(id, "-", "")[:8]
+	rawKey := prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &model.APIKey{
+		ID:        id,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hashAPIKey(rawKey),
+		TenantID:  tenantID,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+
+	var created []model.Event
+	if events != nil {
+		created = events(key)
+	}
+	if err := s.apiKeyRepo.CreateAPIKey(ctx, key, roleIDs, created...); err != nil {
+		return nil, "", err
+	}
+
+	return key, rawKey, nil
+}
+
+// VerifyAPIKey resolves a plaintext key to an active API key
+func (s *APIKeyService) VerifyAPIKey(ctx context.Context, rawKey string) (*model.APIKey, error) {
+	key, err := s.apiKeyRepo.GetAPIKeyByHash(ctx, hashAPIKey(rawKey))
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if key.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: revoked", ErrInvalidAPIKey)
+	}
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidAPIKey)
+	}
+
+	// Skip the write on most requests; last_used_at is only accurate to the interval
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.apiKeyRepo.TouchAPIKey(ctx, key.ID, apiKeyTouchInterval); err != nil {
+			logger.Warn(ctx, "Failed to record api key usage", nil, "api_key_id", key.ID, "error", err.Error())
+		}
+	}
+
+	return key, nil
+}
+
+// CheckRolesInTenant rejects roles that are not roles of the tenant
+func (s *APIKeyService) CheckRolesInTenant(ctx context.Context, roleIDs []string, tenantID string) error {
+	outside, err := s.apiKeyRepo.RolesOutsideTenant(ctx, roleIDs, tenantID)
+	if err != nil {
+		return err
+	}
+	if len(outside) > 0 {
+		return fmt.Errorf("%w: %s", ErrRoleOutsideTenant, strings.Join(outside, ", "))
+	}
+	return nil
+}
+
+func (s *APIKeyService) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	return s.apiKeyRepo.GetAPIKey(ctx, id)
+}
+
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, tenantID string) ([]model.APIKey, error) {
+	return s.apiKeyRepo.ListAPIKeys(ctx, tenantID)
+}
+
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id string, changeEvents model.MembershipChangeEvents, events ...model.Event) error {
+	return s.apiKeyRepo.RevokeAPIKey(ctx, id, changeEvents, events...)
+}
+
+func (s *APIKeyService) AssignRoles(ctx context.Context, keyID string, roleIDs []string, changeEvents model.MembershipChangeEvents) error {
+	return s.apiKeyRepo.BulkAssignRoles(ctx, keyID, roleIDs, changeEvents)
+}
+
+func (s *APIKeyService) RemoveRoles(ctx context.Context, keyID string, roleIDs []string, changeEvents model.MembershipChangeEvents) error {
+	return s.apiKeyRepo.BulkRemoveRoles(ctx, keyID, roleIDs, changeEvents)
+}
+
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"rbac-service/internal/model"
+	"rbac-service/internal/repository"
+)
+
+const (
+	defaultDeadLetterLimit = 100
+	maxDeadLetterLimit     = 1000
+)
+
+// ErrDeadLetterNotFound is returned when an event does not exist or is not dead-lettered
+var ErrDeadLetterNotFound = errors.New("dead-lettered event not found")
+
+type DeadLetterService struct {
+	auditRepo *repository.EventAuditRepository
+}
+
+func NewDeadLetterService(auditRepo *repository.EventAuditRepository) *DeadLetterService {
+	return &DeadLetterService{
+		auditRepo: auditRepo,
+	}
+}
+
+func (s *DeadLetterService) ListDeadLetters(ctx context.Context, limit, offset int) ([]model.DeadLetterEvent, error) {
+	if limit <= 0 {
+		limit = defaultDeadLetterLimit
+	}
+	if limit > maxDeadLetterLimit {
+		limit = maxDeadLetterLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	events, err := s.auditRepo.ListConsumedEventsByStatus(ctx, model.StatusDeadLettered, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetters := make([]model.DeadLetterEvent, 0, len(events))
+	for i := range events {
+		deadLetters = append(deadLetters, ToDeadLetterEvent(&events[i]))
+	}
+	return deadLetters, nil
+}
+
+func (s *DeadLetterService) GetDeadLetter(ctx context.Context, id string) (*model.ConsumedEvent, error) {
+	event, err := s.auditRepo.GetConsumedEvent(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrConsumedEventNotFound) {
+			return nil, ErrDeadLetterNotFound
+		}
+		return nil, err
+	}
+
+	if event.Status != model.StatusDeadLettered {
+		return nil, ErrDeadLetterNotFound
+	}
+	return event, nil
+}
+
+func (s *DeadLetterService) PurgeDeadLetters(ctx context.Context) (int64, error) {
+	return s.auditRepo.DeleteConsumedEventsByStatus(ctx, model.StatusDeadLettered)
+}
+
+// ToDeadLetterEvent converts a consumed event to its API representation
+func ToDeadLetterEvent(event *model.ConsumedEvent) model.DeadLetterEvent {
+	deadLetter := model.DeadLetterEvent{
+		ID:         event.ID,
+		EventType:  event.EventType,
+		Payload:    event.Payload,
+		Status:     event.Status,
+		RetryCount: event.RetryCount,
+		CreatedAt:  event.CreatedAt,
+		UpdatedAt:  event.UpdatedAt,
+	}
+	if event.ErrorMessage != nil {
+		deadLetter.Error = *event.ErrorMessage
+	}
+	return deadLetter
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rbac-service/internal/model"
+	"rbac-service/internal/repository"
+	"slices"
+)
+
+const (
+	defaultEventAuditLimit = 100
+	maxEventAuditLimit     = 1000
+)
+
+var (
+	// ErrPublishedEventNotFound is returned when a published event does not exist
+	ErrPublishedEventNotFound = errors.New("published event not found")
+	// ErrConsumedEventNotFound is returned when a consumed event does not exist
+	ErrConsumedEventNotFound = errors.New("consumed event not found")
+	// ErrEventNotRecoverable is returned when an event's status does not allow
+	// republishing or reprocessing it
+	ErrEventNotRecoverable = errors.New("event cannot be recovered in its current status")
+)
+
+// republishableStatuses are the outbox statuses an event can be republished from
+var republishableStatuses = []string{model.StatusPending, model.StatusUnroutable, model.StatusFailed}
+
+// reprocessableStatuses are the consumed statuses an event can be reprocessed from
+var reprocessableStatuses = []string{model.StatusFailed, model.StatusDeadLettered, model.StatusReplayed}
+
+type EventAuditService struct {
+	auditRepo *repository.EventAuditRepository
+}
+
+func NewEventAuditService(auditRepo *repository.EventAuditRepository) *EventAuditService {
+	return &EventAuditService{
+		auditRepo: auditRepo,
+	}
+}
+
+func (s *EventAuditService) ListPublishedEvents(ctx context.Context, filter model.EventFilter) ([]model.PublishedEventRecord, error) {
+	events, err := s.auditRepo.ListPublishedEvents(ctx, normalizeEventFilter(filter))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]model.PublishedEventRecord, 0, len(events))
+	for i := range events {
+		records = append(records, events[i].Record())
+	}
+	return records, nil
+}
+
+func (s *EventAuditService) GetPublishedEvent(ctx context.Context, id string) (*model.PublishedEventRecord, error) {
+	event, err := s.auditRepo.GetPublishedEvent(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrPublishedEventNotFound) {
+			return nil, ErrPublishedEventNotFound
+		}
+		return nil, err
+	}
+
+	record := event.Record()
+	return &record, nil
+}
+
+// RequeuePublishedEvent makes a pending, unroutable or failed event due now,
+// so the outbox relay publishes it again
+func (s *EventAuditService) RequeuePublishedEvent(ctx context.Context, id string) error {
+	event, err := s.auditRepo.GetPublishedEvent(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrPublishedEventNotFound) {
+			return ErrPublishedEventNotFound
+		}
+		return err
+	}
+
+	if !slices.Contains(republishableStatuses, event.Status) {
+		return fmt.Errorf("%w: %s", ErrEventNotRecoverable, event.Status)
+	}
+
+	requeued, err := s.auditRepo.RequeuePublishedEvent(ctx, id, republishableStatuses)
+	if err != nil {
+		return err
+	}
+	if !requeued {
+		// Published by the relay in the meantime
+		return fmt.Errorf("%w: status changed", ErrEventNotRecoverable)
+	}
+	return nil
+}
+
+func (s *EventAuditService) ListConsumedEvents(ctx context.Context, filter model.EventFilter) ([]model.ConsumedEventRecord, error) {
+	events, err := s.auditRepo.ListConsumedEvents(ctx, normalizeEventFilter(filter))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]model.ConsumedEventRecord, 0, len(events))
+	for i := range events {
+		records = append(records, events[i].Record())
+	}
+	return records, nil
+}
+
+func (s *EventAuditService) GetConsumedEvent(ctx context.Context, id string) (*model.ConsumedEvent, error) {
+	event, err := s.auditRepo.GetConsumedEvent(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrConsumedEventNotFound) {
+			return nil, ErrConsumedEventNotFound
+		}
+		return nil, err
+	}
+	return event, nil
+}
+
+// MarkReprocessing marks a failed, dead-lettered or replayed event as replayed
+// before it is republished, so the consumer processes it again. It fails if
+// the event's status changed since it was read.
+func (s *EventAuditService) MarkReprocessing(ctx context.Context, event *model.ConsumedEvent) error {
+	if !slices.Contains(reprocessableStatuses, event.Status) {
+		return fmt.Errorf("%w: %s", ErrEventNotRecoverable, event.Status)
+	}
+
+	marked, err := s.auditRepo.MarkConsumedEventReplayed(ctx, event.ID, []string{event.Status})
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return fmt.Errorf("%w: status changed", ErrEventNotRecoverable)
+	}
+	return nil
+}
+
+// RestoreStatus puts back the status an event had before a failed reprocess
+func (s *EventAuditService) RestoreStatus(ctx context.Context, event *model.ConsumedEvent) error {
+	return s.auditRepo.UpdateConsumedEvent(ctx, event.ID, event.Status, event.ErrorMessage, event.RetryCount)
+}
+
+func normalizeEventFilter(filter model.EventFilter) model.EventFilter {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultEventAuditLimit
+	}
+	if filter.Limit > maxEventAuditLimit {
+		filter.Limit = maxEventAuditLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return filter
+}
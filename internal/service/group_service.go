@@ -18,34 +18,41 @@ func NewGroupService(groupRepo *repository.GroupRepository) *GroupService {
 	}
 }
 
-func (s *GroupService) CreateGroup(ctx context.Context, name, tenantID string) (*model.Group, error) {
+// CreateGroup creates a group. The events built for the new group are written to
+// the outbox with it; events may be nil.
+func (s *GroupService) CreateGroup(ctx context.Context, name, tenantID string, events func(group *model.Group) []model.Event) (*model.Group, error) {
 	group := &model.Group{
 		ID:       uuid.New().String(),
 		Name:     name,
 		TenantID: tenantID,
 	}
-	if err := s.groupRepo.CreateGroup(ctx, group); err != nil {
+
+	var created []model.Event
+	if events != nil {
+		created = events(group)
+	}
+	if err := s.groupRepo.CreateGroup(ctx, group, created...); err != nil {
 		return nil, err
 	}
 	return group, nil
 }
 
-func (s *GroupService) AssignPermissions(ctx context.Context, groupID string, permissions []model.Permission) error {
-	return s.groupRepo.BulkAssignPermissions(ctx, groupID, permissions)
+func (s *GroupService) AssignPermissions(ctx context.Context, groupID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
+	return s.groupRepo.BulkAssignPermissions(ctx, groupID, permissions, changeEvents)
 }
 
-func (s *GroupService) RemovePermissions(ctx context.Context, groupID string, permissions []model.Permission) error {
-	return s.groupRepo.BulkRemovePermissions(ctx, groupID, permissions)
+func (s *GroupService) RemovePermissions(ctx context.Context, groupID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
+	return s.groupRepo.BulkRemovePermissions(ctx, groupID, permissions, changeEvents)
 }
 
-func (s *GroupService) SyncPermissions(ctx context.Context, groupID string, permissions []model.Permission) error {
-	return s.groupRepo.BulkSyncPermissions(ctx, groupID, permissions)
+func (s *GroupService) SyncPermissions(ctx context.Context, groupID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
+	return s.groupRepo.BulkSyncPermissions(ctx, groupID, permissions, changeEvents)
 }
 
-func (s *GroupService) AssignUsers(ctx context.Context, groupID string, userIDs []string) error {
-	return s.groupRepo.BulkAssignUsers(ctx, groupID, userIDs)
+func (s *GroupService) AssignUsers(ctx context.Context, groupID string, userIDs []string, changeEvents model.MembershipChangeEvents) error {
+	return s.groupRepo.BulkAssignUsers(ctx, groupID, userIDs, changeEvents)
 }
 
-func (s *GroupService) RemoveUsers(ctx context.Context, groupID string, userIDs []string) error {
-	return s.groupRepo.BulkRemoveUsers(ctx, groupID, userIDs)
+func (s *GroupService) RemoveUsers(ctx context.Context, groupID string, userIDs []string, changeEvents model.MembershipChangeEvents) error {
+	return s.groupRepo.BulkRemoveUsers(ctx, groupID, userIDs, changeEvents)
 }
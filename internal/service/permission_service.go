@@ -66,6 +66,6 @@ func (s *PermissionService) CheckPermission(ctx context.Context, req model.Check
 	}
 }
 
-func (s *PermissionService) CheckMiddlewarePermissionsWithMV(ctx context.Context, userID string, tenantID *string, permRes, permAct, assocRes, assocAct string) (bool, error) {
+func (s *PermissionService) CheckMiddlewarePermissionsWithMV(ctx context.Context, userID string, tenantID *string, permRes, permAct, assocRes, assocAct string) (bool, bool, error) {
 	return s.permRepo.CheckMiddlewarePermissionsWithMV(ctx, userID, tenantID, permRes, permAct, assocRes, assocAct)
 }
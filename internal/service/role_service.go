@@ -18,39 +18,46 @@ func NewRoleService(roleRepo *repository.RoleRepository) *RoleService {
 	}
 }
 
-func (s *RoleService) CreateRole(ctx context.Context, name, tenantID string) (*model.Role, error) {
+// CreateRole creates a role. The events built for the new role are written to
+// the outbox with it; events may be nil.
+func (s *RoleService) CreateRole(ctx context.Context, name, tenantID string, events func(role *model.Role) []model.Event) (*model.Role, error) {
 	role := &model.Role{
 		ID:       uuid.New().String(),
 		Name:     name,
 		TenantID: tenantID,
 	}
-	if err := s.roleRepo.CreateRole(ctx, role); err != nil {
+
+	var created []model.Event
+	if events != nil {
+		created = events(role)
+	}
+	if err := s.roleRepo.CreateRole(ctx, role, created...); err != nil {
 		return nil, err
 	}
 	return role, nil
 }
 
-func (s *RoleService) AssignPermissions(ctx context.Context, roleID string, permissions []model.Permission) error {
+func (s *RoleService) AssignPermissions(ctx context.Context, roleID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
 	// TODO: Verify role belongs to tenant if tenant context is available.
 	// Current architecture relies on middleware for user permission check,
 	// but resource ownership check is missing here.
 	// For now, we assume the caller has verified this or we trust the ID.
 	// Ideally, we should fetch the role and check its TenantID against the context's TenantID.
-	return s.roleRepo.BulkAssignPermissions(ctx, roleID, permissions)
+	return s.roleRepo.BulkAssignPermissions(ctx, roleID, permissions, changeEvents)
 }
 
-func (s *RoleService) RemovePermissions(ctx context.Context, roleID string, permissions []model.Permission) error {
-	return s.roleRepo.BulkRemovePermissions(ctx, roleID, permissions)
+func (s *RoleService) RemovePermissions(ctx context.Context, roleID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
+	return s.roleRepo.BulkRemovePermissions(ctx, roleID, permissions, changeEvents)
 }
 
-func (s *RoleService) SyncPermissions(ctx context.Context, roleID string, permissions []model.Permission) error {
-	return s.roleRepo.BulkSyncPermissions(ctx, roleID, permissions)
+func (s *RoleService) SyncPermissions(ctx context.Context, roleID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
+	return s.roleRepo.BulkSyncPermissions(ctx, roleID, permissions, changeEvents)
 }
 
-func (s *RoleService) AssignUsers(ctx context.Context, roleID string, userIDs []string) error {
-	return s.roleRepo.BulkAssignUsers(ctx, roleID, userIDs)
+func (s *RoleService) AssignUsers(ctx context.Context, roleID string, userIDs []string, changeEvents model.MembershipChangeEvents) error {
+	return s.roleRepo.BulkAssignUsers(ctx, roleID, userIDs, changeEvents)
 }
 
-func (s *RoleService) RemoveUsers(ctx context.Context, roleID string, userIDs []string) error {
-	return s.roleRepo.BulkRemoveUsers(ctx, roleID, userIDs)
+func (s *RoleService) RemoveUsers(ctx context.Context, roleID string, userIDs []string, changeEvents model.MembershipChangeEvents) error {
+	return s.roleRepo.BulkRemoveUsers(ctx, roleID, userIDs, changeEvents)
 }
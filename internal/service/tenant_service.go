@@ -16,14 +16,14 @@ func NewTenantService(tenantRepo *repository.TenantRepository) *TenantService {
 	}
 }
 
-func (s *TenantService) AssignPermissions(ctx context.Context, tenantID string, permissions []model.Permission) error {
-	return s.tenantRepo.BulkAssignPermissions(ctx, tenantID, permissions)
+func (s *TenantService) AssignPermissions(ctx context.Context, tenantID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
+	return s.tenantRepo.BulkAssignPermissions(ctx, tenantID, permissions, changeEvents)
 }
 
-func (s *TenantService) RemovePermissions(ctx context.Context, tenantID string, permissions []model.Permission) error {
-	return s.tenantRepo.BulkRemovePermissions(ctx, tenantID, permissions)
+func (s *TenantService) RemovePermissions(ctx context.Context, tenantID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
+	return s.tenantRepo.BulkRemovePermissions(ctx, tenantID, permissions, changeEvents)
 }
 
-func (s *TenantService) SyncPermissions(ctx context.Context, tenantID string, permissions []model.Permission) error {
-	return s.tenantRepo.BulkSyncPermissions(ctx, tenantID, permissions)
+func (s *TenantService) SyncPermissions(ctx context.Context, tenantID string, permissions []model.Permission, changeEvents model.PermissionChangeEvents) error {
+	return s.tenantRepo.BulkSyncPermissions(ctx, tenantID, permissions, changeEvents)
 }
@@ -0,0 +1,49 @@
+BEGIN;
+
+-- Migration 005: API Keys
+-- Service-to-service principals authenticated by a hashed secret.
+-- A key's id is used as its principal id, so it is assigned roles through
+-- pmsn.user_role exactly like a user.
+
+CREATE TABLE IF NOT EXISTS pmsn.api_key (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(32) NOT NULL,
+    key_hash VARCHAR(128) NOT NULL UNIQUE,
+    tenant_id UUID,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_key_tenant_id ON pmsn.api_key(tenant_id);
+
+-- API key management permissions
+INSERT INTO pmsn.resource (code, name, description) VALUES
+('api_key', 'API Key', 'Service API key management')
+ON CONFLICT (code) DO NOTHING;
+
+WITH res AS (SELECT id FROM pmsn.resource WHERE code = 'api_key')
+INSERT INTO pmsn.action (resource_id, code, name, description) VALUES
+((SELECT id FROM res), 'manage', 'Manage API Keys', 'Create, list and revoke API keys'),
+((SELECT id FROM res), 'manage_tenant_associated', 'Manage Associated API Keys', 'Create, list and revoke API keys within associated tenant only')
+ON CONFLICT (resource_id, code) DO NOTHING;
+
+-- Grant to superadmin
+WITH sa_role AS (
+    SELECT id FROM pmsn.role WHERE name = 'superadmin' AND tenant_id IS NULL LIMIT 1
+),
+key_actions AS (
+    SELECT r.id as resource_id, a.id as action_id
+    FROM pmsn.resource r
+    JOIN pmsn.action a ON r.id = a.resource_id
+    WHERE r.code = 'api_key'
+)
+INSERT INTO pmsn.role_permission (role_id, resource_id, action_id)
+SELECT sa_role.id, key_actions.resource_id, key_actions.action_id
+FROM sa_role, key_actions
+ON CONFLICT DO NOTHING;
+
+COMMIT;
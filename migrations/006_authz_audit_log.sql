@@ -0,0 +1,54 @@
+BEGIN;
+
+-- Migration 006: Authorization Audit Log
+-- Records every change to authorization data, written in the same
+-- transaction as the change itself
+
+CREATE TABLE IF NOT EXISTS pmsn.authz_audit_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    occurred_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    actor_id VARCHAR,
+    actor_type VARCHAR,
+    tenant_id VARCHAR,
+    operation VARCHAR NOT NULL, -- e.g. 'role.permissions.sync'
+    entity_type VARCHAR NOT NULL, -- 'role', 'group', 'tenant', 'api_key'
+    entity_id VARCHAR NOT NULL,
+    before JSONB,
+    after JSONB,
+    diff JSONB,
+    request_id VARCHAR
+);
+
+CREATE INDEX IF NOT EXISTS idx_authz_audit_log_occurred_at ON pmsn.authz_audit_log(occurred_at);
+CREATE INDEX IF NOT EXISTS idx_authz_audit_log_actor ON pmsn.authz_audit_log(actor_id);
+CREATE INDEX IF NOT EXISTS idx_authz_audit_log_tenant ON pmsn.authz_audit_log(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_authz_audit_log_entity ON pmsn.authz_audit_log(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_authz_audit_log_request_id ON pmsn.authz_audit_log(request_id);
+
+-- Audit log read permissions
+INSERT INTO pmsn.resource (code, name, description) VALUES
+('audit_log', 'Audit Log', 'Authorization audit log')
+ON CONFLICT (code) DO NOTHING;
+
+WITH res AS (SELECT id FROM pmsn.resource WHERE code = 'audit_log')
+INSERT INTO pmsn.action (resource_id, code, name, description) VALUES
+((SELECT id FROM res), 'read', 'Read Audit Log', 'Read the authorization audit log'),
+((SELECT id FROM res), 'read_tenant_associated', 'Read Associated Audit Log', 'Read the authorization audit log for associated tenant only')
+ON CONFLICT (resource_id, code) DO NOTHING;
+
+-- Grant to superadmin
+WITH sa_role AS (
+    SELECT id FROM pmsn.role WHERE name = 'superadmin' AND tenant_id IS NULL LIMIT 1
+),
+audit_actions AS (
+    SELECT r.id as resource_id, a.id as action_id
+    FROM pmsn.resource r
+    JOIN pmsn.action a ON r.id = a.resource_id
+    WHERE r.code = 'audit_log'
+)
+INSERT INTO pmsn.role_permission (role_id, resource_id, action_id)
+SELECT sa_role.id, audit_actions.resource_id, audit_actions.action_id
+FROM sa_role, audit_actions
+ON CONFLICT DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,25 @@
+BEGIN;
+
+-- Migration 007: Permission Decision Log
+-- Sampled record of permission check outcomes, written when
+-- DECISION_LOG_SINK=POSTGRES
+
+CREATE TABLE IF NOT EXISTS pmsn.decision_log (
+    id UUID PRIMARY KEY,
+    occurred_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    source VARCHAR NOT NULL, -- 'check_permission', 'middleware'
+    principal_id VARCHAR NOT NULL,
+    tenant_id VARCHAR,
+    permissions JSONB NOT NULL,
+    condition VARCHAR,
+    allowed BOOLEAN NOT NULL,
+    error_message TEXT,
+    latency_ms DOUBLE PRECISION NOT NULL,
+    request_id VARCHAR
+);
+
+CREATE INDEX IF NOT EXISTS idx_decision_log_occurred_at ON pmsn.decision_log(occurred_at);
+CREATE INDEX IF NOT EXISTS idx_decision_log_principal ON pmsn.decision_log(principal_id);
+CREATE INDEX IF NOT EXISTS idx_decision_log_tenant ON pmsn.decision_log(tenant_id);
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+-- Migration 008: Transactional Outbox
+-- published_events doubles as the outbox: rows are written in the same
+-- transaction as the data change and published by the outbox relay
+
+ALTER TABLE pmsn.published_events ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE pmsn.published_events ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_published_events_outbox ON pmsn.published_events(next_attempt_at) WHERE status = 'pending';
+
+COMMIT;
@@ -0,0 +1,30 @@
+BEGIN;
+
+-- Migration 009: Dead-Letter Administration
+-- Permission to list, inspect, replay and purge dead-lettered events
+
+INSERT INTO pmsn.resource (code, name, description) VALUES
+('event', 'Event', 'Event system administration')
+ON CONFLICT (code) DO NOTHING;
+
+WITH res AS (SELECT id FROM pmsn.resource WHERE code = 'event')
+INSERT INTO pmsn.action (resource_id, code, name, description) VALUES
+((SELECT id FROM res), 'manage', 'Manage Events', 'Inspect, replay and purge dead-lettered events')
+ON CONFLICT (resource_id, code) DO NOTHING;
+
+-- Grant to superadmin
+WITH sa_role AS (
+    SELECT id FROM pmsn.role WHERE name = 'superadmin' AND tenant_id IS NULL LIMIT 1
+),
+event_actions AS (
+    SELECT r.id as resource_id, a.id as action_id
+    FROM pmsn.resource r
+    JOIN pmsn.action a ON r.id = a.resource_id
+    WHERE r.code = 'event'
+)
+INSERT INTO pmsn.role_permission (role_id, resource_id, action_id)
+SELECT sa_role.id, event_actions.resource_id, event_actions.action_id
+FROM sa_role, event_actions
+ON CONFLICT DO NOTHING;
+
+COMMIT;
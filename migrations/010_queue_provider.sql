@@ -0,0 +1,39 @@
+BEGIN;
+
+-- Migration 010: Postgres Queue Provider
+-- Exchanges, queues, bindings and messages for QUEUE_PROVIDER=POSTGRES.
+-- Messages are claimed with FOR UPDATE SKIP LOCKED and hidden for a
+-- visibility timeout until they are acknowledged (deleted) or released.
+
+CREATE TABLE IF NOT EXISTS pmsn.queue_exchange (
+    name VARCHAR(255) PRIMARY KEY,
+    type VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS pmsn.queue (
+    name VARCHAR(255) PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS pmsn.queue_binding (
+    queue VARCHAR(255) NOT NULL REFERENCES pmsn.queue(name) ON DELETE CASCADE,
+    exchange VARCHAR(255) NOT NULL REFERENCES pmsn.queue_exchange(name) ON DELETE CASCADE,
+    routing_key VARCHAR(255) NOT NULL,
+    PRIMARY KEY (queue, exchange, routing_key)
+);
+
+CREATE TABLE IF NOT EXISTS pmsn.queue_message (
+    id BIGSERIAL PRIMARY KEY,
+    queue VARCHAR(255) NOT NULL REFERENCES pmsn.queue(name) ON DELETE CASCADE,
+    routing_key VARCHAR(255) NOT NULL,
+    headers JSONB NOT NULL DEFAULT '{}',
+    body BYTEA NOT NULL,
+    delivery_count INT NOT NULL DEFAULT 0,
+    available_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_queue_message_available ON pmsn.queue_message(queue, available_at);
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Migration 011: Event Correlation
+-- Links published events to the request or event that caused them
+
+ALTER TABLE pmsn.published_events ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(255);
+ALTER TABLE pmsn.published_events ADD COLUMN IF NOT EXISTS causation_id VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_published_events_correlation_id ON pmsn.published_events(correlation_id);
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Migration 012: Event Retention
+-- The retention job deletes finished events by status and last update time
+
+CREATE INDEX IF NOT EXISTS idx_published_events_retention ON pmsn.published_events(status, updated_at);
+CREATE INDEX IF NOT EXISTS idx_consumed_events_retention ON pmsn.consumed_events(status, updated_at);
+
+COMMIT;
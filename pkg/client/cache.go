@@ -0,0 +1,132 @@
+package client
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DecisionCache is an in-process TTL cache of permission check decisions
+type DecisionCache struct {
+	ttl        time.Duration
+	maxEntries int
+	mu         sync.Mutex
+	entries    map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	userID    string
+	allowed   bool
+	expiresAt time.Time
+}
+
+// NewDecisionCache creates a new decision cache. A maxEntries of 0 means unbounded.
+func NewDecisionCache(ttl time.Duration, maxEntries int) *DecisionCache {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+
+	return &DecisionCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]cacheEntry),
+	}
+}
+
+// Get returns the cached decision for req, if present and not expired
+func (d *DecisionCache) Get(req CheckPermissionRequest) (bool, bool) {
+	key := cacheKey(req)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.entries[key]
+	if !ok {
+		return false, false
+	}
+
+	if time.Now().After(entry.expiresAt) {
+		delete(d.entries, key)
+		return false, false
+	}
+
+	return entry.allowed, true
+}
+
+// Set stores the decision for req
+func (d *DecisionCache) Set(req CheckPermissionRequest, allowed bool) {
+	key := cacheKey(req)
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.maxEntries > 0 && len(d.entries) >= d.maxEntries {
+		d.evictUnsafe(now)
+	}
+
+	d.entries[key] = cacheEntry{
+		userID:    req.UserID,
+		allowed:   allowed,
+		expiresAt: now.Add(d.ttl),
+	}
+}
+
+// InvalidateUser drops all cached decisions for a user
+func (d *DecisionCache) InvalidateUser(userID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, entry := range d.entries {
+		if entry.userID == userID {
+			delete(d.entries, key)
+		}
+	}
+}
+
+// Purge drops all cached decisions
+func (d *DecisionCache) Purge() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entries = make(map[string]cacheEntry)
+}
+
+// evictUnsafe removes expired entries, falling back to the entry closest to
+// expiry when nothing has expired (must be called with lock held)
+func (d *DecisionCache) evictUnsafe(now time.Time) {
+	oldestKey := ""
+	var oldest time.Time
+
+	for key, entry := range d.entries {
+		if now.After(entry.expiresAt) {
+			delete(d.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.expiresAt.Before(oldest) {
+			oldestKey = key
+			oldest = entry.expiresAt
+		}
+	}
+
+	if len(d.entries) >= d.maxEntries && oldestKey != "" {
+		delete(d.entries, oldestKey)
+	}
+}
+
+// cacheKey builds an order-independent key for a check request
+func cacheKey(req CheckPermissionRequest) string {
+	codes := make([]string, 0, len(req.Permissions))
+	for _, p := range req.Permissions {
+		codes = append(codes, p.ResourceCode+"."+p.ActionCode)
+	}
+	sort.Strings(codes)
+
+	condition := req.Condition
+	if condition != ConditionOr {
+		condition = ConditionAnd
+	}
+
+	return req.UserID + "|" + req.TenantID + "|" + condition + "|" + strings.Join(codes, ",")
+}
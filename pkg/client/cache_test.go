@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func checkRequest(userID string, codes ...string) CheckPermissionRequest {
+	req := CheckPermissionRequest{UserID: userID, TenantID: "tenant-1"}
+	for _, code := range codes {
+		req.Permissions = append(req.Permissions, PermissionCode{ResourceCode: code, ActionCode: "read"})
+	}
+	return req
+}
+
+func TestDecisionCacheKeyIgnoresPermissionOrder(t *testing.T) {
+	cache := NewDecisionCache(time.Minute, 0)
+	cache.Set(checkRequest("user-1", "role", "group"), true)
+
+	if allowed, ok := cache.Get(checkRequest("user-1", "group", "role")); !ok || !allowed {
+		t.Fatalf("Get with permissions reordered = %v, %v, want true, true", allowed, ok)
+	}
+
+	// An empty condition means AND
+	and := checkRequest("user-1", "role", "group")
+	and.Condition = ConditionAnd
+	if _, ok := cache.Get(and); !ok {
+		t.Fatal("AND condition missed the entry cached without a condition")
+	}
+
+	or := checkRequest("user-1", "role", "group")
+	or.Condition = ConditionOr
+	if _, ok := cache.Get(or); ok {
+		t.Fatal("OR condition hit the entry cached for AND")
+	}
+
+	other := checkRequest("user-1", "role", "group")
+	other.TenantID = "tenant-2"
+	if _, ok := cache.Get(other); ok {
+		t.Fatal("another tenant hit the entry")
+	}
+}
+
+func TestDecisionCacheExpires(t *testing.T) {
+	cache := NewDecisionCache(20*time.Millisecond, 0)
+	req := checkRequest("user-1", "role")
+	cache.Set(req, false)
+
+	if allowed, ok := cache.Get(req); !ok || allowed {
+		t.Fatalf("Get = %v, %v, want false, true", allowed, ok)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := cache.Get(req); ok {
+		t.Fatal("expired decision returned")
+	}
+}
+
+func TestDecisionCacheInvalidation(t *testing.T) {
+	cache := NewDecisionCache(time.Minute, 0)
+	cache.Set(checkRequest("user-1", "role"), true)
+	cache.Set(checkRequest("user-1", "group"), true)
+	cache.Set(checkRequest("user-2", "role"), true)
+
+	cache.InvalidateUser("user-1")
+	if _, ok := cache.Get(checkRequest("user-1", "role")); ok {
+		t.Fatal("decision of invalidated user returned")
+	}
+	if _, ok := cache.Get(checkRequest("user-1", "group")); ok {
+		t.Fatal("decision of invalidated user returned")
+	}
+	if _, ok := cache.Get(checkRequest("user-2", "role")); !ok {
+		t.Fatal("decision of another user dropped")
+	}
+
+	cache.Purge()
+	if _, ok := cache.Get(checkRequest("user-2", "role")); ok {
+		t.Fatal("decision returned after purge")
+	}
+}
+
+func TestDecisionCacheEvictsClosestToExpiry(t *testing.T) {
+	cache := NewDecisionCache(time.Minute, 2)
+	cache.Set(checkRequest("user-1", "role"), true)
+	time.Sleep(time.Millisecond)
+	cache.Set(checkRequest("user-2", "role"), true)
+	time.Sleep(time.Millisecond)
+	cache.Set(checkRequest("user-3", "role"), true)
+
+	if _, ok := cache.Get(checkRequest("user-1", "role")); ok {
+		t.Fatal("oldest decision kept past maxEntries")
+	}
+	for _, userID := range []string{"user-2", "user-3"} {
+		if _, ok := cache.Get(checkRequest(userID, "role")); !ok {
+			t.Fatalf("decision of %s evicted", userID)
+		}
+	}
+}
+
+func TestCheckPermissionUsesCache(t *testing.T) {
+	server := newRBACServer(t)
+	c := newTestClient(t, server, WithDecisionCache(time.Minute, 100))
+	req := checkRequest("user-1", "role")
+
+	for i := 0; i < 2; i++ {
+		allowed, err := c.CheckPermission(context.Background(), req)
+		if err != nil || !allowed {
+			t.Fatalf("CheckPermission = %v, %v, want true, nil", allowed, err)
+		}
+	}
+	if got := server.count(); got != 1 {
+		t.Fatalf("server called %d times, want 1", got)
+	}
+
+	c.Cache().InvalidateUser("user-1")
+	if _, err := c.CheckPermission(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.count(); got != 2 {
+		t.Fatalf("server called %d times after invalidation, want 2", got)
+	}
+}
+
+func TestCheckPermissionDoesNotCacheFailures(t *testing.T) {
+	server := newRBACServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK)
+	c := newTestClient(t, server, WithDecisionCache(time.Minute, 100))
+	req := checkRequest("user-1", "role")
+
+	if _, err := c.CheckPermission(context.Background(), req); err == nil {
+		t.Fatal("CheckPermission succeeded while the service was unavailable")
+	}
+
+	allowed, err := c.CheckPermission(context.Background(), req)
+	if err != nil || !allowed {
+		t.Fatalf("CheckPermission = %v, %v, want true, nil", allowed, err)
+	}
+}
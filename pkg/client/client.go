@@ -0,0 +1,270 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	HeaderUserID    = "X-User-ID"
+	HeaderTenantID  = "X-Tenant-ID"
+	HeaderRequestID = "X-Request-ID"
+	HeaderAPIKey    = "X-API-Key"
+
+	apiPrefix = "/api/v1"
+)
+
+// APIError is returned when the service responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("rbac service returned %d: %s", e.StatusCode, e.Message)
+}
+
+// IsForbidden reports whether err is a 403 response from the service
+func IsForbidden(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden
+}
+
+// IsUnauthorized reports whether err is a 401 response from the service
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized
+}
+
+// Client is a typed HTTP client for the RBAC service
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	maxRetries  int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	cache       *DecisionCache
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the underlying HTTP client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetry sets the retry count and backoff bounds for transient failures
+func WithRetry(maxRetries int, baseBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.baseBackoff = baseBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithDecisionCache enables a local cache of permission check decisions
+func WithDecisionCache(ttl time.Duration, maxEntries int) Option {
+	return func(c *Client) {
+		c.cache = NewDecisionCache(ttl, maxEntries)
+	}
+}
+
+// New creates a new client for the service at baseURL (e.g. http://rbac:9980)
+func New(baseURL string, opts ...Option) (*Client, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("base URL is required")
+	}
+
+	c := &Client{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		maxRetries:  3,
+		baseBackoff: 100 * time.Millisecond,
+		maxBackoff:  2 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Cache returns the decision cache, or nil if caching is disabled
+func (c *Client) Cache() *DecisionCache {
+	return c.cache
+}
+
+type contextKey string
+
+const (
+	requestIDKey contextKey = "rbac_request_id"
+	tenantKey    contextKey = "rbac_tenant"
+	tokenKey     contextKey = "rbac_bearer_token"
+	apiKeyKey    contextKey = "rbac_api_key"
+)
+
+// WithRequestID returns a context carrying a request ID that is sent as X-Request-ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, if any
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// WithTenant returns a context carrying the tenant management calls act on.
+// It is sent as the X-Tenant-ID header. The caller is always identified by
+// the credential (WithBearerToken or WithAPIKey), never by a header.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenantID)
+}
+
+// WithBearerToken returns a context carrying a token sent as the Authorization
+// header on management calls
+func WithBearerToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey, token)
+}
+
+// WithAPIKey returns a context carrying an API key sent as the X-API-Key
+// header on management calls, for services calling as themselves
+func WithAPIKey(ctx context.Context, apiKey string) context.Context {
+	return context.WithValue(ctx, apiKeyKey, apiKey)
+}
+
+// do sends a request, retrying transient failures with exponential backoff.
+// Non-idempotent requests are not retried once the server has responded.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}, idempotent bool) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+	}
+
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			if err := c.sleep(ctx, attempt); err != nil {
+				return err
+			}
+		}
+
+		retryable, err := c.attempt(ctx, method, path, requestID, payload, out)
+		if err == nil {
+			return nil
+		}
+
+		lastErr = err
+		if !retryable {
+			return err
+		}
+
+		var apiErr *APIError
+		if !idempotent && errors.As(err, &apiErr) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("request failed after %d retries: %w", c.maxRetries, lastErr)
+}
+
+// attempt performs a single HTTP round trip and reports whether a failure is retryable
+func (c *Client) attempt(ctx context.Context, method, path, requestID string, payload []byte, out interface{}) (bool, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+apiPrefix+path, reader)
+	if err != nil {
+		return false, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set(HeaderRequestID, requestID)
+	if token, ok := ctx.Value(tokenKey).(string); ok && token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if apiKey, ok := ctx.Value(apiKeyKey).(string); ok && apiKey != "" {
+		req.Header.Set(HeaderAPIKey, apiKey)
+	}
+	if tenantID, ok := ctx.Value(tenantKey).(string); ok && tenantID != "" {
+		req.Header.Set(HeaderTenantID, tenantID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return true, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return true, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		var errBody struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(respBody, &errBody) == nil && errBody.Error != "" {
+			apiErr.Message = errBody.Error
+		}
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retryable, apiErr
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return false, fmt.Errorf("failed to unmarshal response body: %w", err)
+		}
+	}
+
+	return false, nil
+}
+
+// sleep waits for the backoff of the given attempt or until ctx is done
+func (c *Client) sleep(ctx context.Context, attempt int) error {
+	backoff := c.baseBackoff << uint(attempt-1)
+	if backoff > c.maxBackoff || backoff <= 0 {
+		backoff = c.maxBackoff
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,268 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rbacServer is a stand-in for the RBAC service that answers each request with
+// the next status in statuses, repeating the last one
+type rbacServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	checks   []CheckPermissionRequest
+	allow    func(req CheckPermissionRequest) bool
+}
+
+func newRBACServer(t *testing.T, statuses ...int) *rbacServer {
+	t.Helper()
+
+	s := &rbacServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *rbacServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	status := http.StatusOK
+	if n := len(s.requests); len(s.statuses) > 0 {
+		status = s.statuses[min(n, len(s.statuses))-1]
+	}
+
+	var check CheckPermissionRequest
+	if r.URL.Path == apiPrefix+"/check-permission" {
+		json.NewDecoder(r.Body).Decode(&check)
+		s.checks = append(s.checks, check)
+	}
+	allow := s.allow
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": http.StatusText(status)})
+		return
+	}
+
+	switch r.URL.Path {
+	case apiPrefix + "/check-permission":
+		json.NewEncoder(w).Encode(CheckPermissionResponse{Allowed: allow == nil || allow(check)})
+	case apiPrefix + "/roles":
+		json.NewEncoder(w).Encode(Role{ID: "role-1", Name: "editor"})
+	default:
+		json.NewEncoder(w).Encode(MessageResponse{Message: "ok"})
+	}
+}
+
+func (s *rbacServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+func (s *rbacServer) request(i int) *http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[i]
+}
+
+func newTestClient(t *testing.T, server *rbacServer, opts ...Option) *Client {
+	t.Helper()
+
+	opts = append([]Option{WithRetry(3, time.Millisecond, 5*time.Millisecond)}, opts...)
+	c, err := New(server.URL, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		call       func(ctx context.Context, c *Client) error
+		wantCalls  int
+		wantStatus int // 0 if the call succeeds
+	}{
+		{
+			name:      "transient failures are retried",
+			statuses:  []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			call:      syncRolePermissions,
+			wantCalls: 3,
+		},
+		{
+			name:      "rate limiting is retried",
+			statuses:  []int{http.StatusTooManyRequests, http.StatusOK},
+			call:      syncRolePermissions,
+			wantCalls: 2,
+		},
+		{
+			name:       "retries run out",
+			statuses:   []int{http.StatusInternalServerError},
+			call:       syncRolePermissions,
+			wantCalls:  4,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "client errors are not retried",
+			statuses:   []int{http.StatusForbidden},
+			call:       syncRolePermissions,
+			wantCalls:  1,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:     "non-idempotent calls are not retried once the server responded",
+			statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+			call: func(ctx context.Context, c *Client) error {
+				_, err := c.CreateRole(ctx, CreateRoleRequest{Name: "editor"})
+				return err
+			},
+			wantCalls:  1,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRBACServer(t, tt.statuses...)
+			err := tt.call(context.Background(), newTestClient(t, server))
+
+			if got := server.count(); got != tt.wantCalls {
+				t.Fatalf("server called %d times, want %d", got, tt.wantCalls)
+			}
+
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("call failed: %v", err)
+				}
+				return
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.wantStatus {
+				t.Fatalf("call returned %v, want an APIError with status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func syncRolePermissions(ctx context.Context, c *Client) error {
+	return c.SyncRolePermissions(ctx, "role-1", BulkRolePermissionRequest{})
+}
+
+func TestClientRetriesUnreachableService(t *testing.T) {
+	server := newRBACServer(t)
+	server.Close()
+
+	c := newTestClient(t, server)
+	err := syncRolePermissions(context.Background(), c)
+	if err == nil {
+		t.Fatal("call to a closed server succeeded")
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		t.Fatalf("call returned %v, want a transport error", err)
+	}
+}
+
+func TestClientBacksOffExponentially(t *testing.T) {
+	server := newRBACServer(t, http.StatusServiceUnavailable)
+	c := newTestClient(t, server, WithRetry(3, 20*time.Millisecond, 30*time.Millisecond))
+
+	start := time.Now()
+	syncRolePermissions(context.Background(), c)
+
+	// 20ms, then 40ms capped to 30ms, twice
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Fatalf("retries took %v, want at least 80ms of backoff", elapsed)
+	}
+}
+
+func TestClientStopsRetryingWhenContextDone(t *testing.T) {
+	server := newRBACServer(t, http.StatusServiceUnavailable)
+	c := newTestClient(t, server, WithRetry(5, time.Hour, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := syncRolePermissions(ctx, c)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("call returned %v, want the context error", err)
+	}
+	if got := server.count(); got != 1 {
+		t.Fatalf("server called %d times, want 1", got)
+	}
+}
+
+func TestClientSendsRequestHeaders(t *testing.T) {
+	server := newRBACServer(t, http.StatusServiceUnavailable, http.StatusOK)
+	c := newTestClient(t, server)
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = WithTenant(ctx, "tenant-1")
+	ctx = WithBearerToken(ctx, "token-1")
+	ctx = WithAPIKey(ctx, "key-1")
+	if err := syncRolePermissions(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every attempt carries the same request ID
+	for i := 0; i < 2; i++ {
+		r := server.request(i)
+		if got := r.Header.Get(HeaderRequestID); got != "req-1" {
+			t.Fatalf("attempt %d: request ID = %q, want req-1", i, got)
+		}
+		if got := r.Header.Get(HeaderTenantID); got != "tenant-1" {
+			t.Fatalf("attempt %d: tenant = %q, want tenant-1", i, got)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token-1" {
+			t.Fatalf("attempt %d: authorization = %q, want Bearer token-1", i, got)
+		}
+		if got := r.Header.Get(HeaderAPIKey); got != "key-1" {
+			t.Fatalf("attempt %d: API key = %q, want key-1", i, got)
+		}
+		if got := r.Header.Get(HeaderUserID); got != "" {
+			t.Fatalf("attempt %d: sent user header %q", i, got)
+		}
+	}
+}
+
+func TestClientGeneratesRequestID(t *testing.T) {
+	server := newRBACServer(t, http.StatusServiceUnavailable, http.StatusOK)
+	c := newTestClient(t, server)
+
+	if err := syncRolePermissions(context.Background(), c); err != nil {
+		t.Fatal(err)
+	}
+
+	first := server.request(0).Header.Get(HeaderRequestID)
+	if first == "" || server.request(1).Header.Get(HeaderRequestID) != first {
+		t.Fatalf("request IDs = %q, %q, want one generated ID on both attempts", first, server.request(1).Header.Get(HeaderRequestID))
+	}
+}
+
+func TestIsForbiddenAndUnauthorized(t *testing.T) {
+	for _, status := range []int{http.StatusForbidden, http.StatusUnauthorized} {
+		server := newRBACServer(t, status)
+		err := syncRolePermissions(context.Background(), newTestClient(t, server))
+
+		if IsForbidden(err) != (status == http.StatusForbidden) {
+			t.Fatalf("IsForbidden(%v) = %v", err, IsForbidden(err))
+		}
+		if IsUnauthorized(err) != (status == http.StatusUnauthorized) {
+			t.Fatalf("IsUnauthorized(%v) = %v", err, IsUnauthorized(err))
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CheckPermission checks whether a user holds the requested permissions.
+// Decisions are served from the local cache when caching is enabled.
+func (c *Client) CheckPermission(ctx context.Context, req CheckPermissionRequest) (bool, error) {
+	if c.cache != nil {
+		if allowed, ok := c.cache.Get(req); ok {
+			return allowed, nil
+		}
+	}
+
+	var resp CheckPermissionResponse
+	if err := c.do(ctx, http.MethodPost, "/check-permission", req, &resp, true); err != nil {
+		return false, err
+	}
+
+	if c.cache != nil {
+		c.cache.Set(req, resp.Allowed)
+	}
+
+	return resp.Allowed, nil
+}
+
+// HasPermission is a convenience wrapper to check a single resource/action pair
+func (c *Client) HasPermission(ctx context.Context, userID, tenantID, resourceCode, actionCode string) (bool, error) {
+	return c.CheckPermission(ctx, CheckPermissionRequest{
+		UserID:   userID,
+		TenantID: tenantID,
+		Permissions: []PermissionCode{
+			{ResourceCode: resourceCode, ActionCode: actionCode},
+		},
+		Condition: ConditionAnd,
+	})
+}
+
+// Tenant
+
+func (c *Client) AddTenantPermissions(ctx context.Context, req BulkTenantPermissionRequest) error {
+	return c.do(ctx, http.MethodPost, "/tenant/permissions/add", req, nil, true)
+}
+
+func (c *Client) RemoveTenantPermissions(ctx context.Context, req BulkTenantPermissionRequest) error {
+	return c.do(ctx, http.MethodPost, "/tenant/permissions/remove", req, nil, true)
+}
+
+func (c *Client) SyncTenantPermissions(ctx context.Context, req BulkTenantPermissionRequest) error {
+	return c.do(ctx, http.MethodPut, "/tenant/permissions", req, nil, true)
+}
+
+// Roles
+
+func (c *Client) CreateRole(ctx context.Context, req CreateRoleRequest) (*Role, error) {
+	var role Role
+	if err := c.do(ctx, http.MethodPost, "/roles", req, &role, false); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (c *Client) AddRolePermissions(ctx context.Context, roleID string, req BulkRolePermissionRequest) error {
+	return c.do(ctx, http.MethodPost, "/roles/"+url.PathEscape(roleID)+"/permissions/add", req, nil, true)
+}
+
+func (c *Client) RemoveRolePermissions(ctx context.Context, roleID string, req BulkRolePermissionRequest) error {
+	return c.do(ctx, http.MethodPost, "/roles/"+url.PathEscape(roleID)+"/permissions/remove", req, nil, true)
+}
+
+func (c *Client) SyncRolePermissions(ctx context.Context, roleID string, req BulkRolePermissionRequest) error {
+	return c.do(ctx, http.MethodPut, "/roles/"+url.PathEscape(roleID)+"/permissions", req, nil, true)
+}
+
+func (c *Client) AssignRoleUsers(ctx context.Context, roleID string, req BulkUserRoleRequest) error {
+	return c.do(ctx, http.MethodPost, "/roles/"+url.PathEscape(roleID)+"/users/bulk", req, nil, true)
+}
+
+func (c *Client) RemoveRoleUsers(ctx context.Context, roleID string, req BulkUserRoleRequest) error {
+	return c.do(ctx, http.MethodDelete, "/roles/"+url.PathEscape(roleID)+"/users/bulk", req, nil, true)
+}
+
+// Groups
+
+func (c *Client) CreateGroup(ctx context.Context, req CreateGroupRequest) (*Group, error) {
+	var group Group
+	if err := c.do(ctx, http.MethodPost, "/groups", req, &group, false); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (c *Client) AddGroupPermissions(ctx context.Context, groupID string, req BulkGroupPermissionRequest) error {
+	return c.do(ctx, http.MethodPost, "/groups/"+url.PathEscape(groupID)+"/permissions/add", req, nil, true)
+}
+
+func (c *Client) RemoveGroupPermissions(ctx context.Context, groupID string, req BulkGroupPermissionRequest) error {
+	return c.do(ctx, http.MethodPost, "/groups/"+url.PathEscape(groupID)+"/permissions/remove", req, nil, true)
+}
+
+func (c *Client) SyncGroupPermissions(ctx context.Context, groupID string, req BulkGroupPermissionRequest) error {
+	return c.do(ctx, http.MethodPut, "/groups/"+url.PathEscape(groupID)+"/permissions", req, nil, true)
+}
+
+func (c *Client) AssignGroupUsers(ctx context.Context, groupID string, req BulkUserGroupRequest) error {
+	return c.do(ctx, http.MethodPost, "/groups/"+url.PathEscape(groupID)+"/users/bulk", req, nil, true)
+}
+
+func (c *Client) RemoveGroupUsers(ctx context.Context, groupID string, req BulkUserGroupRequest) error {
+	return c.do(ctx, http.MethodDelete, "/groups/"+url.PathEscape(groupID)+"/users/bulk", req, nil, true)
+}
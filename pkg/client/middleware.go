@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// IdentityFunc extracts the user and tenant being authorized from an incoming
+// request. It should read the user from a credential the service has already
+// verified, such as its own session or JWT middleware.
+type IdentityFunc func(r *http.Request) (userID, tenantID string)
+
+// TrustedHeaderIdentity reads the identity from the X-User-ID and X-Tenant-ID
+// headers. Anyone who can reach the service can set these headers, so only
+// use it behind a gateway that authenticates callers and overwrites them.
+func TrustedHeaderIdentity(r *http.Request) (string, string) {
+	tenantID := r.Header.Get(HeaderTenantID)
+	if tenantID == "" {
+		tenantID = r.URL.Query().Get("tenant_id")
+	}
+	return r.Header.Get(HeaderUserID), tenantID
+}
+
+// MiddlewareConfig configures the permission enforcement middleware
+type MiddlewareConfig struct {
+	// Permissions required to pass
+	Permissions []PermissionCode
+	// Condition is AND (default) or OR
+	Condition string
+	// Identity extracts the caller. It is required; the middleware panics
+	// without it rather than trust a client-supplied header.
+	Identity IdentityFunc
+	// FailOpen lets requests through when the RBAC service cannot be reached
+	FailOpen bool
+}
+
+// Require builds a config that requires a single resource/action pair of the
+// caller identified by identity
+func Require(identity IdentityFunc, resourceCode, actionCode string) MiddlewareConfig {
+	return MiddlewareConfig{
+		Permissions: []PermissionCode{{ResourceCode: resourceCode, ActionCode: actionCode}},
+		Condition:   ConditionAnd,
+		Identity:    identity,
+	}
+}
+
+// mustHaveIdentity panics at setup if cfg has no identity function
+func mustHaveIdentity(cfg MiddlewareConfig) {
+	if cfg.Identity == nil {
+		panic("client: MiddlewareConfig.Identity is required")
+	}
+}
+
+// authorize evaluates the request and returns the HTTP status and error message
+// to reject with, or 0 if the request is allowed
+func (c *Client) authorize(ctx context.Context, r *http.Request, cfg MiddlewareConfig) (int, string) {
+	userID, tenantID := cfg.Identity(r)
+	if userID == "" {
+		return http.StatusUnauthorized, "caller identity is required"
+	}
+
+	allowed, err := c.CheckPermission(ctx, CheckPermissionRequest{
+		UserID:      userID,
+		TenantID:    tenantID,
+		Permissions: cfg.Permissions,
+		Condition:   cfg.Condition,
+	})
+	if err != nil {
+		if cfg.FailOpen {
+			return 0, ""
+		}
+		return http.StatusServiceUnavailable, "Failed to check permissions"
+	}
+
+	if !allowed {
+		return http.StatusForbidden, "Permission denied"
+	}
+
+	return 0, ""
+}
+
+// requestContext carries the incoming request ID (or a new one) into the check
+func requestContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if RequestIDFromContext(ctx) != "" {
+		return ctx
+	}
+
+	requestID := r.Header.Get(HeaderRequestID)
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	return WithRequestID(ctx, requestID)
+}
+
+// Middleware returns a net/http middleware that enforces cfg remotely
+func (c *Client) Middleware(cfg MiddlewareConfig) func(http.Handler) http.Handler {
+	mustHaveIdentity(cfg)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := requestContext(r)
+
+			status, msg := c.authorize(ctx, r, cfg)
+			if status != 0 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				json.NewEncoder(w).Encode(map[string]string{"error": msg})
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GinMiddleware returns a Gin middleware that enforces cfg remotely
+func (c *Client) GinMiddleware(cfg MiddlewareConfig) gin.HandlerFunc {
+	mustHaveIdentity(cfg)
+
+	return func(ctx *gin.Context) {
+		reqCtx := requestContext(ctx.Request)
+
+		status, msg := c.authorize(reqCtx, ctx.Request, cfg)
+		if status != 0 {
+			ctx.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveMiddleware runs a request through middleware in front of a handler
+// that records the request ID it saw
+func serveMiddleware(mw func(http.Handler) http.Handler, req *http.Request) (*httptest.ResponseRecorder, bool, string) {
+	called := false
+	requestID := ""
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		requestID = RequestIDFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w, called, requestID
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     string
+		allow      bool
+		down       bool
+		failOpen   bool
+		wantStatus int
+	}{
+		{name: "allowed", userID: "user-1", allow: true, wantStatus: http.StatusNoContent},
+		{name: "denied", userID: "user-1", wantStatus: http.StatusForbidden},
+		{name: "no identity", wantStatus: http.StatusUnauthorized},
+		{name: "service down", userID: "user-1", down: true, wantStatus: http.StatusServiceUnavailable},
+		{name: "service down, fail open", userID: "user-1", down: true, failOpen: true, wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRBACServer(t)
+			server.allow = func(CheckPermissionRequest) bool { return tt.allow }
+			if tt.down {
+				server.Close()
+			}
+			c := newTestClient(t, server)
+
+			cfg := Require(TrustedHeaderIdentity, "role", "manage")
+			cfg.FailOpen = tt.failOpen
+
+			req := httptest.NewRequest(http.MethodGet, "/roles?tenant_id=tenant-1", nil)
+			req.Header.Set(HeaderUserID, tt.userID)
+			req.Header.Set(HeaderRequestID, "req-1")
+
+			w, called, requestID := serveMiddleware(c.Middleware(cfg), req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if called != (tt.wantStatus == http.StatusNoContent) {
+				t.Fatalf("handler called = %v with status %d", called, w.Code)
+			}
+			if called && requestID != "req-1" {
+				t.Fatalf("handler saw request ID %q, want req-1", requestID)
+			}
+		})
+	}
+}
+
+func TestMiddlewareChecksIdentity(t *testing.T) {
+	server := newRBACServer(t)
+	c := newTestClient(t, server)
+
+	identity := func(r *http.Request) (string, string) { return "user-1", "tenant-1" }
+	req := httptest.NewRequest(http.MethodGet, "/roles", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	// A header the identity function doesn't read is ignored
+	req.Header.Set(HeaderUserID, "admin")
+
+	serveMiddleware(c.Middleware(Require(identity, "role", "manage")), req)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.checks) != 1 {
+		t.Fatalf("checked %d times, want 1", len(server.checks))
+	}
+	check := server.checks[0]
+	if check.UserID != "user-1" || check.TenantID != "tenant-1" || len(check.Permissions) != 1 || check.Permissions[0].ResourceCode != "role" {
+		t.Fatalf("checked %+v, want user-1 in tenant-1 for role.manage", check)
+	}
+	if got := server.requests[0].Header.Get(HeaderRequestID); got != "req-1" {
+		t.Fatalf("check sent request ID %q, want req-1", got)
+	}
+}
+
+func TestMiddlewareRequiresIdentity(t *testing.T) {
+	c, err := New("http://rbac.invalid")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, build := range map[string]func(){
+		"net/http": func() { c.Middleware(MiddlewareConfig{}) },
+		"gin":      func() { c.GinMiddleware(MiddlewareConfig{}) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("middleware built without an identity function")
+				}
+			}()
+			build()
+		})
+	}
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		userID     string
+		allow      bool
+		wantStatus int
+	}{
+		{name: "allowed", userID: "user-1", allow: true, wantStatus: http.StatusNoContent},
+		{name: "denied", userID: "user-1", wantStatus: http.StatusForbidden},
+		{name: "no identity", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRBACServer(t)
+			server.allow = func(CheckPermissionRequest) bool { return tt.allow }
+			c := newTestClient(t, server)
+
+			requestID := ""
+			r := gin.New()
+			r.GET("/roles", c.GinMiddleware(Require(TrustedHeaderIdentity, "role", "manage")), func(ctx *gin.Context) {
+				requestID = RequestIDFromContext(ctx.Request.Context())
+				ctx.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/roles", nil)
+			req.Header.Set(HeaderUserID, tt.userID)
+			req.Header.Set(HeaderTenantID, "tenant-1")
+			req.Header.Set(HeaderRequestID, "req-1")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusNoContent && requestID != "req-1" {
+				t.Fatalf("handler saw request ID %q, want req-1", requestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package client
+
+import "rbac-service/internal/model"
+
+// Request and response DTOs are aliased from the service's model package so
+// that consuming services can build requests without importing internal packages.
+type (
+	Permission                  = model.Permission
+	PermissionCode              = model.PermissionCode
+	Role                        = model.Role
+	Group                       = model.Group
+	CreateRoleRequest           = model.CreateRoleRequest
+	CreateGroupRequest          = model.CreateGroupRequest
+	BulkTenantPermissionRequest = model.BulkTenantPermissionRequest
+	BulkRolePermissionRequest   = model.BulkRolePermissionRequest
+	BulkGroupPermissionRequest  = model.BulkGroupPermissionRequest
+	BulkUserRoleRequest         = model.BulkUserRoleRequest
+	BulkUserGroupRequest        = model.BulkUserGroupRequest
+	CheckPermissionRequest      = model.CheckPermissionRequest
+)
+
+// Permission check conditions
+const (
+	ConditionAnd = "AND"
+	ConditionOr  = "OR"
+)
+
+// CheckPermissionResponse is the response body of the check-permission endpoint
+type CheckPermissionResponse struct {
+	Allowed bool `json:"allowed"`
+}
+
+// MessageResponse is the response body returned by the bulk endpoints
+type MessageResponse struct {
+	Message string `json:"message"`
+}